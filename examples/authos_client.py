@@ -1,50 +1,183 @@
+import base64
+import hashlib
+import hmac
+import json
+import secrets
+import time
+from urllib.parse import urlencode
+
 import requests
 
 class AuthosClient:
     """
     负责与权限系统 (Authos) 进行通信的客户端
     """
-    def __init__(self, host, app_code, app_secret):
+    def __init__(self, host, app_code, app_secret, sign_requests=False):
+        """
+        sign_requests=True 时公共接口使用 HMAC 请求签名，appSecret 不再出现在请求体中
+        """
         self.host = host.rstrip('/')
         self.app_code = app_code
         self.app_secret = app_secret
+        self.sign_requests = sign_requests
+
+    def _signed_headers(self, method, path, body):
+        """
+        计算请求签名头：签名密钥为 hex(hmac_sha256(appSecret, "authos-request-signing"))，
+        待签名字符串为 方法、路径（含查询参数）、时间戳、随机串、请求体 SHA-256，以换行分隔
+        """
+        timestamp = str(int(time.time()))
+        nonce = secrets.token_hex(16)
+        canonical = "\n".join([
+            method.upper(),
+            path,
+            timestamp,
+            nonce,
+            hashlib.sha256(body).hexdigest(),
+        ])
+        signing_key = hmac.new(self.app_secret.encode(), b"authos-request-signing", hashlib.sha256).hexdigest()
+        signature = hmac.new(signing_key.encode(), canonical.encode(), hashlib.sha256).hexdigest()
+        return {
+            "X-Authos-App": self.app_code,
+            "X-Authos-Timestamp": timestamp,
+            "X-Authos-Nonce": nonce,
+            "X-Authos-Signature": signature,
+        }
+
+    def _post_public(self, path, payload):
+        """
+        调用 /api/public 接口：签名模式下以请求头签名，否则在请求体中携带 appCode + appSecret
+        """
+        url = f"{self.host}{path}"
+        if not self.sign_requests:
+            payload = {"appCode": self.app_code, "appSecret": self.app_secret, **payload}
+            return requests.post(url, json=payload, timeout=5)
+
+        # 签名覆盖原始请求体字节，因此自行序列化后发送
+        body = json.dumps(payload).encode()
+        headers = {"Content-Type": "application/json", **self._signed_headers("POST", path, body)}
+        return requests.post(url, data=body, headers=headers, timeout=5)
 
     def proxy_login(self, username, password):
         """
         后端透传登录：将用户提交的账号密码，加上App身份凭证，发给权限系统
         """
-        url = f"{self.host}/api/public/proxy-login"
         payload = {
-            "appCode": self.app_code,
-            "appSecret": self.app_secret,
             "username": username,
             "password": password
         }
         
         try:
             # 发起请求
-            response = requests.post(url, json=payload, timeout=5)
+            response = self._post_public("/api/public/proxy-login", payload)
             # 返回 JSON 数据和 HTTP 状态码
             return response.json(), response.status_code
         except requests.RequestException as e:
             # 处理网络错误等异常
             return {"message": f"Authos service error: {str(e)}"}, 500
 
-    def check_access(self, token, path, method):
+    def oauth_token(self, grant_type, **params):
+        """
+        OAuth 2.0 令牌端点：以 HTTP Basic 携带 App 身份凭证 (client_id=appCode, client_secret=appSecret)
+        grant_type 支持 client_credentials / password / refresh_token
+        """
+        url = f"{self.host}/oauth/token"
+        data = {"grant_type": grant_type, **params}
+
+        try:
+            response = requests.post(url, data=data, auth=(self.app_code, self.app_secret), timeout=5)
+            return response.json(), response.status_code
+        except requests.RequestException as e:
+            return {"error": "server_error", "error_description": f"Authos service error: {str(e)}"}, 500
+
+    def password_login(self, username, password):
+        """
+        OAuth 2.0 密码模式登录，替代 proxy_login
+        """
+        return self.oauth_token("password", username=username, password=password)
+
+    def verify_mfa(self, mfa_token, otp):
+        """
+        两步验证：password_login 返回 error=mfa_required 时，提交 mfa_token 与验证码（或恢复码）换取令牌
+        """
+        return self.oauth_token("urn:authos:params:oauth:grant-type:mfa-otp", mfa_token=mfa_token, otp=otp)
+
+    def refresh(self, refresh_token):
+        """
+        使用 refresh_token 换取新的访问令牌（旧的 refresh_token 立即失效）
+        """
+        return self.oauth_token("refresh_token", refresh_token=refresh_token)
+
+    def introspect(self, token, token_type_hint=None):
+        """
+        令牌内省 (RFC 7662)：只判断令牌是否有效，返回 (active, data)
+        """
+        url = f"{self.host}/oauth/introspect"
+        data = {"token": token}
+        if token_type_hint:
+            data["token_type_hint"] = token_type_hint
+
+        try:
+            response = requests.post(url, data=data, auth=(self.app_code, self.app_secret), timeout=5)
+            body = response.json()
+            return body.get("active", False), body
+        except requests.RequestException:
+            return False, {"message": "Authos service unavailable"}
+
+    def userinfo(self, access_token):
+        """
+        OIDC 用户信息端点：返回 sub / preferred_username / roles
+        """
+        url = f"{self.host}/userinfo"
+        try:
+            response = requests.get(url, headers={"Authorization": f"Bearer {access_token}"}, timeout=5)
+            return response.json(), response.status_code
+        except requests.RequestException as e:
+            return {"error": "server_error", "error_description": f"Authos service error: {str(e)}"}, 500
+
+    def authorize_url(self, redirect_uri, scope=""):
+        """
+        授权码模式：生成托管登录页地址
+        返回 (url, state, code_verifier)，state 与 code_verifier 需保存在用户会话中，回调时使用
+        """
+        state = secrets.token_urlsafe(16)
+        code_verifier = secrets.token_urlsafe(48)
+        digest = hashlib.sha256(code_verifier.encode()).digest()
+        code_challenge = base64.urlsafe_b64encode(digest).rstrip(b"=").decode()
+
+        params = {
+            "response_type": "code",
+            "client_id": self.app_code,
+            "redirect_uri": redirect_uri,
+            "state": state,
+            "code_challenge": code_challenge,
+            "code_challenge_method": "S256",
+        }
+        if scope:
+            params["scope"] = scope
+        return f"{self.host}/oauth/authorize?{urlencode(params)}", state, code_verifier
+
+    def exchange_code(self, code, redirect_uri, code_verifier):
+        """
+        授权码模式：回调后用授权码与 code_verifier 换取令牌
+        """
+        return self.oauth_token("authorization_code", code=code, redirect_uri=redirect_uri, code_verifier=code_verifier)
+
+    def check_access(self, token, path, method, context=None):
         """
         统一鉴权：将Token和请求信息，加上App身份凭证，发给权限系统校验
+        context 为权限条件的求值上下文，如 {"ip": "10.0.0.8", "attributes": {"tenant_region": "cn"}}
         """
-        url = f"{self.host}/api/public/check-access"
         payload = {
-            "appCode": self.app_code,
-            "appSecret": self.app_secret,
             "token": token,
             "obj": path,
             "act": method
         }
+        if context:
+            payload["context"] = context
 
         try:
-            response = requests.post(url, json=payload, timeout=5)
+            response = self._post_public("/api/public/check-access", payload)
             
             if response.status_code == 200:
                 data = response.json()
@@ -56,3 +189,45 @@ class AuthosClient:
             
         except requests.RequestException:
             return False, {"message": "Authos service unavailable"}
+
+    def check_access_batch(self, token, checks, context=None):
+        """
+        批量统一鉴权：一次请求检查多项权限，返回与 checks 顺序一致的结果列表
+        checks 每项为 {"obj": path, "act": method} 或 {"permission": key}
+        """
+        payload = {
+            "token": token,
+            "checks": checks
+        }
+        if context:
+            payload["context"] = context
+
+        try:
+            response = self._post_public("/api/public/check-access/batch", payload)
+            data = response.json()
+            if response.status_code == 200:
+                return [item.get("allowed", False) for item in data.get("results", [])], data
+
+            # Token 无效等情况下全部视为无权限
+            return [False] * len(checks), data
+
+        except requests.RequestException:
+            return [False] * len(checks), {"message": "Authos service unavailable"}
+
+    def verify_token_offline(self, token):
+        """
+        离线校验：使用权限系统发布的 JWKS 公钥在本地验证 Token 签名与过期时间
+        依赖 PyJWT (pip install "pyjwt[crypto]")，公钥会被 PyJWKClient 缓存
+        注意：离线校验无法感知登出/强制下线等吊销操作
+        """
+        import jwt
+
+        if not hasattr(self, "_jwks_client"):
+            self._jwks_client = jwt.PyJWKClient(f"{self.host}/.well-known/jwks.json")
+
+        try:
+            signing_key = self._jwks_client.get_signing_key_from_jwt(token)
+            claims = jwt.decode(token, signing_key.key, algorithms=["RS256", "ES256"])
+            return True, claims
+        except jwt.PyJWTError as e:
+            return False, {"message": f"Invalid token: {str(e)}"}
@@ -21,7 +21,8 @@ except ImportError:
 authos_client = AuthosClient(
     host="http://localhost:8080", 
     app_code="example_app",     
-    app_secret="example_secret"  
+    app_secret="example_secret",
+    sign_requests=True  # 使用 HMAC 请求签名，appSecret 不出现在请求体中
 )
 
 # ==========================================
@@ -99,7 +100,9 @@ class AuthosMiddleware:
         
         # 注意：这里可能会有性能损耗，生产环境可以考虑加一层本地缓存 (Redis/Memory)
         # 缓存 key 可以是 f"{token}:{path}:{method}"
-        allowed, details = authos_client.check_access(token, path, method)
+        # 附带终端用户 IP，供配置了网段条件的权限规则使用
+        context = {"ip": request.META.get('REMOTE_ADDR', '')}
+        allowed, details = authos_client.check_access(token, path, method, context)
 
         if not allowed:
             # 鉴权失败 (无权限 或 Token无效)
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// apiKeyDefaultLifetimeDays 未指定有效期时 API 密钥的默认有效天数
+const apiKeyDefaultLifetimeDays = 90
+
+// apiKeyFromContext 获取本次请求使用的 API 密钥（使用 JWT 访问时返回 nil）
+func apiKeyFromContext(c echo.Context) *model.APIKey {
+	key, _ := c.Get("apiKey").(*model.APIKey)
+	return key
+}
+
+// rejectAPIKeyAccess 密码、MFA 与密钥管理等敏感操作不接受 API 密钥，必须使用登录令牌
+func rejectAPIKeyAccess(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]string{"message": "This operation requires a login token, api keys are not accepted"})
+}
+
+// APIKeyHandler 个人 API 密钥处理器（用户自助管理，管理员代为管理）
+type APIKeyHandler struct {
+	APIKeyService *service.APIKeyService
+	UserService   *service.UserService
+}
+
+// NewAPIKeyHandler 创建 API 密钥处理器实例
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService, userService *service.UserService) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyService: apiKeyService,
+		UserService:   userService,
+	}
+}
+
+// CreateAPIKeyRequest 创建 API 密钥请求
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes"`        // 允许的接口权限标识，为空表示与用户权限一致
+	ExpiresInDays int      `json:"expiresInDays"` // 有效天数（1-365，默认 90）
+}
+
+// currentUser 获取当前登录用户（仅接受用户登录令牌）
+func (h *APIKeyHandler) currentUser(c echo.Context) (*model.User, error) {
+	userID, _ := c.Get("userID").(uint)
+	appID, _ := c.Get("appID").(uint)
+	if userID == 0 || appID == 0 {
+		return nil, errors.New("user token required")
+	}
+	return h.UserService.GetUserByID(userID, appID)
+}
+
+// targetUser 获取管理员操作的目标用户（按应用隔离）
+func (h *APIKeyHandler) targetUser(c echo.Context) (*model.User, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return nil, err
+	}
+	return h.UserService.GetUserByID(uint(id), appID)
+}
+
+// createKey 为用户创建密钥，明文只在响应中返回一次
+func (h *APIKeyHandler) createKey(c echo.Context, user *model.User) error {
+	var req CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = apiKeyDefaultLifetimeDays
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	raw, key, err := h.APIKeyService.CreateKey(user, req.Name, req.Scopes, time.Duration(req.ExpiresInDays)*24*time.Hour, operatorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      user.AppID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "CREATE",
+		Resource:   "API_KEY",
+		ResourceID: fmt.Sprintf("%d", key.ID),
+		Content:    fmt.Sprintf("为用户 %s 创建 API 密钥: %s (%s), 权限范围 %v", user.Username, key.Name, key.Prefix, key.Scopes),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"apiKey":  raw,
+		"key":     key,
+		"message": "API key created successfully, it will not be shown again",
+	})
+}
+
+// revokeKey 吊销用户的密钥
+func (h *APIKeyHandler) revokeKey(c echo.Context, user *model.User) error {
+	keyID, err := strconv.ParseUint(c.Param("keyId"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid api key ID"})
+	}
+
+	if err := h.APIKeyService.RevokeKey(uint(keyID), user.ID, user.AppID); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "API key not found"})
+		}
+		service.Log.Errorf("RevokeAPIKey: %v, keyID=%d", err, keyID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to revoke api key"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      user.AppID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "REVOKE",
+		Resource:   "API_KEY",
+		ResourceID: fmt.Sprintf("%d", keyID),
+		Content:    fmt.Sprintf("吊销用户 %s 的 API 密钥", user.Username),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "API key revoked successfully"})
+}
+
+// listKeys 列出用户的密钥
+func (h *APIKeyHandler) listKeys(c echo.Context, user *model.User) error {
+	keys, err := h.APIKeyService.ListKeys(user.ID, user.AppID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get api keys"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"keys":  keys,
+		"total": len(keys),
+	})
+}
+
+// ListMyAPIKeys 列出当前用户的 API 密钥
+func (h *APIKeyHandler) ListMyAPIKeys(c echo.Context) error {
+	if apiKeyFromContext(c) != nil {
+		return rejectAPIKeyAccess(c)
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+	return h.listKeys(c, user)
+}
+
+// CreateMyAPIKey 当前用户创建 API 密钥
+func (h *APIKeyHandler) CreateMyAPIKey(c echo.Context) error {
+	if apiKeyFromContext(c) != nil {
+		return rejectAPIKeyAccess(c)
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+	return h.createKey(c, user)
+}
+
+// RevokeMyAPIKey 当前用户吊销自己的 API 密钥
+func (h *APIKeyHandler) RevokeMyAPIKey(c echo.Context) error {
+	if apiKeyFromContext(c) != nil {
+		return rejectAPIKeyAccess(c)
+	}
+	user, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+	return h.revokeKey(c, user)
+}
+
+// ListUserAPIKeys 管理员查看用户的 API 密钥
+func (h *APIKeyHandler) ListUserAPIKeys(c echo.Context) error {
+	if apiKeyFromContext(c) != nil {
+		return rejectAPIKeyAccess(c)
+	}
+	user, err := h.targetUser(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+	return h.listKeys(c, user)
+}
+
+// CreateUserAPIKey 管理员代用户创建 API 密钥
+func (h *APIKeyHandler) CreateUserAPIKey(c echo.Context) error {
+	if apiKeyFromContext(c) != nil {
+		return rejectAPIKeyAccess(c)
+	}
+	user, err := h.targetUser(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+	return h.createKey(c, user)
+}
+
+// RevokeUserAPIKey 管理员吊销用户的 API 密钥
+func (h *APIKeyHandler) RevokeUserAPIKey(c echo.Context) error {
+	if apiKeyFromContext(c) != nil {
+		return rejectAPIKeyAccess(c)
+	}
+	user, err := h.targetUser(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+	return h.revokeKey(c, user)
+}
@@ -254,6 +254,53 @@ func (h *ApiPermissionHandler) GetApiPermissionsForRole(c echo.Context) error {
 	return c.JSON(http.StatusOK, permissions)
 }
 
+// GetApiPermissionRulesForRole 获取角色的接口权限规则（含方法、效果与条件），可按 effect 查询参数过滤
+func (h *ApiPermissionHandler) GetApiPermissionRulesForRole(c echo.Context) error {
+	return h.respondApiPermissionRules(c, c.QueryParam("effect"))
+}
+
+// GetApiPermissionDenyRulesForRole 获取角色的接口权限拒绝规则
+func (h *ApiPermissionHandler) GetApiPermissionDenyRulesForRole(c echo.Context) error {
+	return h.respondApiPermissionRules(c, model.EFFECT_DENY)
+}
+
+// respondApiPermissionRules 返回角色的接口权限规则
+func (h *ApiPermissionHandler) respondApiPermissionRules(c echo.Context, effect string) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	roleUUID := c.Param("roleUUID")
+	if roleUUID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "角色UUID不能为空"})
+	}
+
+	rules, err := h.ApiPermissionService.GetApiPermissionRulesForRole(appID, roleUUID, effect)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "获取角色权限规则失败"})
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+// RolePermissionRuleReq 角色接口权限规则请求
+type RolePermissionRuleReq struct {
+	PermissionUUID string `json:"permissionUUID"`
+	Act            string `json:"act"`       // 方法，默认 * 表示全部方法
+	Effect         string `json:"effect"`    // allow（默认）或 deny，拒绝优先于任何角色的允许
+	Condition      string `json:"condition"` // 附加条件表达式（仅添加时使用），为空表示无条件
+}
+
+// ruleOrDefault 规则字段为空时返回默认值，用于审计日志
+func ruleOrDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
 // AddApiPermissionToRole 为角色添加接口权限
 func (h *ApiPermissionHandler) AddApiPermissionToRole(c echo.Context) error {
 	// 从 JWT token 中获取 appID
@@ -267,15 +314,13 @@ func (h *ApiPermissionHandler) AddApiPermissionToRole(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "角色UUID不能为空"})
 	}
 
-	var req struct {
-		PermissionUUID string `json:"permissionUUID"`
-	}
+	var req RolePermissionRuleReq
 
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "请求参数错误"})
 	}
 
-	if err := h.ApiPermissionService.AddApiPermissionToRole(appID, roleUUID, req.PermissionUUID); err != nil {
+	if err := h.ApiPermissionService.AddApiPermissionRuleToRole(appID, roleUUID, req.PermissionUUID, req.Act, req.Effect, req.Condition); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
@@ -287,7 +332,7 @@ func (h *ApiPermissionHandler) AddApiPermissionToRole(c echo.Context) error {
 		Action:     "ASSIGN",
 		Resource:   "ROLE_PERMISSION",
 		ResourceID: roleUUID,
-		Content:    fmt.Sprintf("为角色分配权限: %s", req.PermissionUUID),
+		Content:    fmt.Sprintf("为角色分配权限: %s, 方法: %s, 效果: %s, 条件: %s", req.PermissionUUID, ruleOrDefault(req.Act, model.HTTP_ALL), ruleOrDefault(req.Effect, model.EFFECT_ALLOW), ruleOrDefault(req.Condition, model.CONDITION_ALWAYS)),
 		IP:         c.RealIP(),
 		Status:     1,
 	})
@@ -308,15 +353,13 @@ func (h *ApiPermissionHandler) RemoveApiPermissionFromRole(c echo.Context) error
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "角色UUID不能为空"})
 	}
 
-	var req struct {
-		PermissionUUID string `json:"permissionUUID"`
-	}
+	var req RolePermissionRuleReq
 
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "请求参数错误"})
 	}
 
-	if err := h.ApiPermissionService.RemoveApiPermissionFromRole(appID, roleUUID, req.PermissionUUID); err != nil {
+	if err := h.ApiPermissionService.RemoveApiPermissionRuleFromRole(appID, roleUUID, req.PermissionUUID, req.Act, req.Effect); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
@@ -328,7 +371,7 @@ func (h *ApiPermissionHandler) RemoveApiPermissionFromRole(c echo.Context) error
 		Action:     "UNASSIGN",
 		Resource:   "ROLE_PERMISSION",
 		ResourceID: roleUUID,
-		Content:    fmt.Sprintf("移除角色权限: %s", req.PermissionUUID),
+		Content:    fmt.Sprintf("移除角色权限: %s, 方法: %s, 效果: %s", req.PermissionUUID, ruleOrDefault(req.Act, model.HTTP_ALL), ruleOrDefault(req.Effect, model.EFFECT_ALLOW)),
 		IP:         c.RealIP(),
 		Status:     1,
 	})
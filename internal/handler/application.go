@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -237,3 +240,380 @@ func (h *ApplicationHandler) GetApplicationByCode(c echo.Context) error {
 		"app": app,
 	})
 }
+
+// UpdateRedirectURIsRequest 更新授权回调地址请求
+type UpdateRedirectURIsRequest struct {
+	RedirectURIs []string `json:"redirectUris"`
+}
+
+// UpdateRedirectURIs 更新应用登记的授权回调地址（授权码模式，系统级操作，仅系统管理员可调用）
+func (h *ApplicationHandler) UpdateRedirectURIs(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	var req UpdateRedirectURIsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	app, err := h.ApplicationService.UpdateRedirectURIs(appID, req.RedirectURIs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "UPDATE",
+		Resource:   "APPLICATION",
+		ResourceID: fmt.Sprintf("%d", app.ID),
+		Content:    fmt.Sprintf("更新应用回调地址: %s %v", app.Code, app.RedirectURIs),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"app":     app,
+		"message": "Redirect URIs updated successfully",
+	})
+}
+
+// UpdatePasswordPolicyRequest 更新应用密码策略请求
+type UpdatePasswordPolicyRequest struct {
+	PasswordMinLength   int  `json:"passwordMinLength"`
+	PasswordMinClasses  int  `json:"passwordMinClasses"`
+	PasswordCheckCommon bool `json:"passwordCheckCommon"`
+	PasswordHistory     int  `json:"passwordHistory"`
+	PasswordMaxAgeDays  int  `json:"passwordMaxAgeDays"`
+}
+
+// UpdatePasswordPolicy 更新应用的密码策略（对之后的创建用户与修改密码生效，有效期对全部用户生效；系统级操作，仅系统管理员可调用）
+func (h *ApplicationHandler) UpdatePasswordPolicy(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	var req UpdatePasswordPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	app, err := h.ApplicationService.UpdatePasswordPolicy(appID, &model.Application{
+		PasswordMinLength:   req.PasswordMinLength,
+		PasswordMinClasses:  req.PasswordMinClasses,
+		PasswordCheckCommon: req.PasswordCheckCommon,
+		PasswordHistory:     req.PasswordHistory,
+		PasswordMaxAgeDays:  req.PasswordMaxAgeDays,
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "UPDATE",
+		Resource:   "APPLICATION",
+		ResourceID: fmt.Sprintf("%d", app.ID),
+		Content: fmt.Sprintf("更新应用密码策略: %s (最小长度 %d, 字符类别 %d, 弱密码检查 %t, 历史 %d, 有效期 %d 天)",
+			app.Code, app.PasswordMinLength, app.PasswordMinClasses, app.PasswordCheckCommon, app.PasswordHistory, app.PasswordMaxAgeDays),
+		IP:     c.RealIP(),
+		Status: 1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"app":     app,
+		"message": "Password policy updated successfully",
+	})
+}
+
+// CreateAppSecretRequest 创建应用密钥请求
+type CreateAppSecretRequest struct {
+	Label         string `json:"label" binding:"required"`
+	ExpiresInDays int    `json:"expiresInDays"` // 有效天数，0 表示长期有效
+}
+
+// CreateAppClientCertRequest 登记受信任客户端证书请求
+type CreateAppClientCertRequest struct {
+	Label       string `json:"label" binding:"required"`
+	Type        string `json:"type" binding:"required"` // fingerprint / ca
+	Fingerprint string `json:"fingerprint"`             // fingerprint 类型：SHA-256 指纹（与 certificate 二选一）
+	Certificate string `json:"certificate"`             // PEM 证书；ca 类型必填
+	SubjectCN   string `json:"subjectCn"`               // ca 类型可选：限定客户端证书的 CN
+}
+
+// parseAppID 解析路径中的应用ID
+func parseAppID(c echo.Context) (uint, bool) {
+	var appID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &appID); err != nil || appID == 0 {
+		return 0, false
+	}
+	return appID, true
+}
+
+// ListAppSecrets 列出应用的密钥（只展示前缀、有效期与最近使用情况）
+func (h *ApplicationHandler) ListAppSecrets(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	secrets, err := h.ApplicationService.ListSecrets(appID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get application secrets"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"secrets": secrets,
+		"total":   len(secrets),
+	})
+}
+
+// CreateAppSecret 为应用新增密钥（已有密钥继续有效，用于无停机轮换），明文只返回一次
+func (h *ApplicationHandler) CreateAppSecret(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	var req CreateAppSecretRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	app, err := h.ApplicationService.GetApplicationByID(fmt.Sprintf("%d", appID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "Application not found"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	raw, secret, err := h.ApplicationService.CreateSecret(app.ID, req.Label, time.Duration(req.ExpiresInDays)*24*time.Hour, operatorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "CREATE",
+		Resource:   "APP_SECRET",
+		ResourceID: fmt.Sprintf("%d", secret.ID),
+		Content:    fmt.Sprintf("新增应用密钥: %s %s (%s...)", app.Code, secret.Label, secret.Prefix),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"secretKey": raw,
+		"secret":    secret,
+		"message":   "Application secret created successfully, it will not be shown again",
+	})
+}
+
+// RevokeAppSecret 吊销应用的一个密钥（不能吊销最后一个有效密钥）
+func (h *ApplicationHandler) RevokeAppSecret(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+	secretID, err := strconv.ParseUint(c.Param("secretId"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid secret ID"})
+	}
+
+	if err := h.ApplicationService.RevokeSecret(appID, uint(secretID)); err != nil {
+		switch {
+		case errors.Is(err, service.ErrAppSecretNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "Application secret not found"})
+		case errors.Is(err, service.ErrLastAppSecret):
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		service.Log.Errorf("RevokeAppSecret: %v, appID=%d, secretID=%d", err, appID, secretID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to revoke application secret"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "REVOKE",
+		Resource:   "APP_SECRET",
+		ResourceID: fmt.Sprintf("%d", secretID),
+		Content:    fmt.Sprintf("吊销应用密钥: 应用ID %d", appID),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Application secret revoked successfully"})
+}
+
+// ResetAppSecret 重置应用密钥：立即吊销全部现有密钥并生成新密钥（密钥泄露时使用，会中断仍在使用旧密钥的后端）
+func (h *ApplicationHandler) ResetAppSecret(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	raw, secret, err := h.ApplicationService.ResetSecretKey(appID, operatorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "RESET",
+		Resource:   "APP_SECRET",
+		ResourceID: fmt.Sprintf("%d", secret.ID),
+		Content:    fmt.Sprintf("重置应用密钥（吊销全部旧密钥）: 应用ID %d", appID),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"secretKey": raw,
+		"secret":    secret,
+		"message":   "Application secret reset successfully, all previous secrets are revoked",
+	})
+}
+
+// ListAppClientCerts 列出应用受信任的客户端证书
+func (h *ApplicationHandler) ListAppClientCerts(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	certs, err := h.ApplicationService.ListClientCerts(appID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get client certificates"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"certs": certs,
+		"total": len(certs),
+	})
+}
+
+// CreateAppClientCert 为应用登记受信任的客户端证书（指纹或 CA）
+func (h *ApplicationHandler) CreateAppClientCert(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	var req CreateAppClientCertRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	app, err := h.ApplicationService.GetApplicationByID(fmt.Sprintf("%d", appID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "Application not found"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	cert, err := h.ApplicationService.AddClientCert(app.ID, &model.AppClientCert{
+		Label:       req.Label,
+		Type:        req.Type,
+		Fingerprint: req.Fingerprint,
+		Certificate: req.Certificate,
+		SubjectCN:   req.SubjectCN,
+	}, operatorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "CREATE",
+		Resource:   "APP_CLIENT_CERT",
+		ResourceID: fmt.Sprintf("%d", cert.ID),
+		Content:    fmt.Sprintf("登记客户端证书: %s %s (%s %s)", app.Code, cert.Label, cert.Type, cert.Fingerprint),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"cert":    cert,
+		"message": "Client certificate trusted successfully",
+	})
+}
+
+// DeleteAppClientCert 移除应用受信任的客户端证书
+func (h *ApplicationHandler) DeleteAppClientCert(c echo.Context) error {
+	appID, ok := parseAppID(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+	certID, err := strconv.ParseUint(c.Param("certId"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid certificate ID"})
+	}
+
+	if err := h.ApplicationService.DeleteClientCert(appID, uint(certID)); err != nil {
+		if errors.Is(err, service.ErrClientCertNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "Client certificate not found"})
+		}
+		service.Log.Errorf("DeleteAppClientCert: %v, appID=%d, certID=%d", err, appID, certID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to delete client certificate"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "DELETE",
+		Resource:   "APP_CLIENT_CERT",
+		ResourceID: fmt.Sprintf("%d", certID),
+		Content:    fmt.Sprintf("移除客户端证书: 应用ID %d", appID),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Client certificate removed successfully"})
+}
+
+// signedAppFromContext 获取已通过请求签名校验的应用，未签名的请求返回 nil
+func signedAppFromContext(c echo.Context) *model.Application {
+	app, _ := c.Get("signedApp").(*model.Application)
+	return app
+}
+
+// resolveCallerApp 获取调用方应用：签名请求以签名应用为准（请求体中的 appCode 可省略，填写时必须一致），否则按 appCode 查询
+func resolveCallerApp(c echo.Context, applicationService *service.ApplicationService, appCode string) (*model.Application, error) {
+	if signed := signedAppFromContext(c); signed != nil {
+		if appCode != "" && appCode != signed.Code {
+			return nil, fmt.Errorf("application code does not match the signed application")
+		}
+		return signed, nil
+	}
+	return applicationService.GetApplicationByCode(appCode)
+}
+
+// verifyCallerApp 校验调用方应用身份：签名请求或携带受信任客户端证书（mTLS）的请求无需再传 appSecret，否则校验 appSecret
+func verifyCallerApp(c echo.Context, applicationService *service.ApplicationService, app *model.Application, appSecret string) bool {
+	if signed := signedAppFromContext(c); signed != nil {
+		return signed.ID == app.ID
+	}
+	if tlsState := c.Request().TLS; tlsState != nil && len(tlsState.PeerCertificates) > 0 {
+		if applicationService.VerifyClientCert(app, tlsState.PeerCertificates, c.RealIP()) {
+			return true
+		}
+	}
+	return applicationService.VerifySecret(app, appSecret, c.RealIP())
+}
@@ -38,7 +38,7 @@ func (h *AuditLogHandler) ListAuditLogs(c echo.Context) error {
 
 // ListSystemAuditLogs 查询全局（系统级）审计日志列表，仅供系统管理员使用
 func (h *AuditLogHandler) ListSystemAuditLogs(c echo.Context) error {
-	// 系统管理员权限检查通常由中间件处理
+	// 系统管理员权限检查由 SystemAdminMiddleware 处理
 	action := c.QueryParam("action")
 	resource := c.QueryParam("resource")
 	username := c.QueryParam("username")
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
@@ -11,73 +14,72 @@ import (
 	"Authos/internal/service"
 )
 
-// getAppIDFromToken 从 JWT token 或请求头中获取应用ID
+// getAppIDFromToken 从 JWT token 中获取应用ID
+// 只有系统管理员（见 JWT 中间件）可以通过 X-App-ID 请求头切换管理的应用，其他令牌一律使用令牌中的应用ID
 func getAppIDFromToken(c echo.Context) (uint, error) {
-
-	// 尝试从上下文获取 appID（JWT token）
-	var tokenAppID uint
-	appIDInterface := c.Get("appID")
-	if appIDInterface != nil {
-		// 尝试类型断言
-		switch v := appIDInterface.(type) {
-		case uint:
-			tokenAppID = v
-		case float64:
-			tokenAppID = uint(v)
-		case int:
-			tokenAppID = uint(v)
-		default:
-			fmt.Printf("getAppIDFromToken: Type assertion failed for appID from token, actual type: %T\n", appIDInterface)
+	tokenAppID, _ := c.Get("appID").(uint)
+
+	if isSystemAdmin, _ := c.Get("isSystemAdmin").(bool); isSystemAdmin {
+		var headerAppID uint
+		if appIDStr := c.Request().Header.Get("X-App-ID"); appIDStr != "" {
+			if _, err := fmt.Sscanf(appIDStr, "%d", &headerAppID); err == nil && headerAppID > 0 {
+				return headerAppID, nil
+			}
 		}
 	}
 
-	// 尝试从请求头获取 appID
-	var headerAppID uint
-	appIDStr := c.Request().Header.Get("X-App-ID")
-	if appIDStr != "" {
-		if _, err := fmt.Sscanf(appIDStr, "%d", &headerAppID); err == nil && headerAppID > 0 {
-			fmt.Printf("getAppIDFromToken: Got appID from header: %d\n", headerAppID)
-		}
+	if tokenAppID > 0 {
+		return tokenAppID, nil
 	}
 
-	// 逻辑判断：
-	// 1. 如果 Token 中的 AppID 为 1 (系统管理员)，且 Header 中有指定的 AppID，则使用 Header 中的 AppID
-	// 2. 否则，优先使用 Token 中的 AppID
-	// 3. 如果都没有，则返回错误
+	return 0, echo.NewHTTPError(http.StatusUnauthorized, "App ID not found in token")
+}
 
-	if tokenAppID == 1 && headerAppID > 0 {
-		fmt.Printf("getAppIDFromToken: System admin switching context to appID: %d\n", headerAppID)
-		return headerAppID, nil
-	}
+// getOperatorFromContext 从上下文获取当前操作人（用于审计日志）
+func getOperatorFromContext(c echo.Context) (uint, string) {
+	var userID uint
+	var username string
 
-	if tokenAppID > 0 {
-		return tokenAppID, nil
+	if userIDInterface := c.Get("userID"); userIDInterface != nil {
+		if u, ok := userIDInterface.(uint); ok {
+			userID = u
+		} else if f, ok := userIDInterface.(float64); ok {
+			userID = uint(f)
+		}
 	}
 
-	if headerAppID > 0 {
-		// 兼容逻辑：如果没有 Token 但有 Header (且未被鉴权中间件拦截)，则使用 Header
-		return headerAppID, nil
+	if usernameInterface := c.Get("username"); usernameInterface != nil {
+		if s, ok := usernameInterface.(string); ok {
+			username = s
+		}
 	}
 
-	fmt.Printf("getAppIDFromToken: appID not found in token or header\n")
-	return 0, echo.NewHTTPError(http.StatusUnauthorized, "App ID not found in token or header")
+	return userID, username
 }
 
 // AuthHandler 认证处理器
 type AuthHandler struct {
-	UserService        *service.UserService
-	ApplicationService *service.ApplicationService
-	AuditLogService    *service.AuditLogService
-	JWTConfig          *service.JWTConfig
+	UserService            *service.UserService
+	ApplicationService     *service.ApplicationService
+	AuditLogService        *service.AuditLogService
+	RefreshTokenService    *service.RefreshTokenService
+	TokenRevocationService *service.TokenRevocationService
+	MFAService             *service.MFAService
+	LoginGuardService      *service.LoginGuardService
+	JWTConfig              *service.JWTConfig
 }
 
 // NewAuthHandler 创建认证处理器实例
-func NewAuthHandler(userService *service.UserService, applicationService *service.ApplicationService, auditLogService *service.AuditLogService, jwtConfig *service.JWTConfig) *AuthHandler {
+func NewAuthHandler(userService *service.UserService, applicationService *service.ApplicationService, auditLogService *service.AuditLogService, refreshTokenService *service.RefreshTokenService, tokenRevocationService *service.TokenRevocationService, mfaService *service.MFAService, loginGuardService *service.LoginGuardService, jwtConfig *service.JWTConfig) *AuthHandler {
 	return &AuthHandler{
-		UserService:        userService,
-		ApplicationService: applicationService,
-		AuditLogService:    auditLogService,
-		JWTConfig:          jwtConfig,
+		UserService:            userService,
+		ApplicationService:     applicationService,
+		AuditLogService:        auditLogService,
+		RefreshTokenService:    refreshTokenService,
+		TokenRevocationService: tokenRevocationService,
+		MFAService:             mfaService,
+		LoginGuardService:      loginGuardService,
+		JWTConfig:              jwtConfig,
 	}
 }
 
@@ -102,12 +104,128 @@ type AppLoginRequest struct {
 
 // ProxyLoginRequest 代理登录请求
 type ProxyLoginRequest struct {
-	AppCode   string `json:"appCode" binding:"required"`
-	AppSecret string `json:"appSecret" binding:"required"`
+	AppCode   string `json:"appCode"`   // 签名请求可省略
+	AppSecret string `json:"appSecret"` // 签名请求可省略
 	Username  string `json:"username" binding:"required"`
 	Password  string `json:"password" binding:"required"`
 }
 
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	AppCode      string `json:"appCode"`   // 签名请求可省略
+	AppSecret    string `json:"appSecret"` // 签名请求可省略
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// LogoutRequest 登出请求（均为可选，访问令牌也可通过 X-Authos-Token 头传递）
+type LogoutRequest struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// issueTokens 签发短期访问令牌和可轮换的刷新令牌
+func (h *AuthHandler) issueTokens(c echo.Context, user *model.User, app *model.Application) (string, string, error) {
+	token, err := h.JWTConfig.GenerateToken(user.ID, user.Username, app.ID, app.UUID)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := h.RefreshTokenService.IssueRefreshToken(user.ID, app.ID, c.RealIP())
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+// 登录方式，同时作为审计日志的 Action
+const (
+	LoginActionLogin  = "LOGIN"
+	LoginActionProxy  = "PROXY_LOGIN"
+	LoginActionSystem = "SYSTEM_LOGIN"
+)
+
+// loginMessages 各登录方式成功时的提示信息
+var loginMessages = map[string]string{
+	LoginActionLogin:  "Login successful",
+	LoginActionProxy:  "Proxy login successful",
+	LoginActionSystem: "System login successful",
+}
+
+// completeLogin 身份校验（含两步验证）全部通过后签发令牌、记录审计日志并返回登录结果，extra 为附加的响应字段
+func (h *AuthHandler) completeLogin(c echo.Context, user *model.User, app *model.Application, action string, extra map[string]interface{}) error {
+	token, refreshToken, err := h.issueTokens(c, user, app)
+	if err != nil {
+		service.Log.Errorf("%s: Failed to generate token: %v", action, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to generate token"})
+	}
+
+	// 登录成功，清零该用户的连续失败次数
+	if err := h.LoginGuardService.ResetUser(app.ID, user.ID); err != nil {
+		service.Log.Warnf("%s: Failed to reset login failures: %v, userID=%d", action, err, user.ID)
+	}
+
+	auditLog := &model.AuditLog{
+		AppID:    app.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   action,
+		Resource: "USER",
+		IP:       c.RealIP(),
+		Status:   1,
+	}
+	if action == LoginActionSystem {
+		// 系统管理员登录记录为系统日志
+		auditLog.AppID = 0
+		auditLog.Resource = "APPLICATION"
+	}
+	h.AuditLogService.Record(auditLog)
+
+	body := map[string]interface{}{
+		"token":        token,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(h.JWTConfig.ExpireTime.Seconds()),
+		"user":         user,
+		"app":          app,
+		"message":      loginMessages[action],
+	}
+	for key, value := range extra {
+		body[key] = value
+	}
+	return c.JSON(http.StatusOK, body)
+}
+
+// rejectExpiredPassword 密码已超过应用配置的有效期时拒绝登录，提示先修改密码
+func rejectExpiredPassword(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]interface{}{
+		"passwordExpired": true,
+		"message":         "Password expired, please change it via /api/public/change-password",
+	})
+}
+
+// requireMFA 密码校验通过后，按用户状态与应用策略判断是否需要两步验证；
+// 需要时签发挑战令牌并返回 mfa_required 响应，handled 为 true 表示响应已写出
+func (h *AuthHandler) requireMFA(c echo.Context, user *model.User, app *model.Application, action string) (bool, error) {
+	required, enrollmentRequired := h.MFAService.IsRequired(user, app)
+	if !required {
+		return false, nil
+	}
+
+	mfaToken, err := h.MFAService.CreateChallenge(user.ID, app.ID, action)
+	if err != nil {
+		service.Log.Errorf("%s: Failed to create mfa challenge: %v", action, err)
+		return true, c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to create mfa challenge"})
+	}
+
+	return true, c.JSON(http.StatusOK, map[string]interface{}{
+		"mfaRequired":           true,
+		"mfaEnrollmentRequired": enrollmentRequired,
+		"mfaToken":              mfaToken,
+		"expiresIn":             int(h.MFAService.ChallengeExpireTime.Seconds()),
+		"message":               "mfa_required",
+	})
+}
+
 // SystemLogin 系统管理员登录接口
 func (h *AuthHandler) SystemLogin(c echo.Context) error {
 	var req SystemLoginRequest
@@ -115,61 +233,64 @@ func (h *AuthHandler) SystemLogin(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
 	}
 
-	// 添加调试日志
-	fmt.Printf("SystemLogin attempt: username=%s\n", req.Username)
+	service.Log.Debugf("SystemLogin attempt: username=%s", req.Username)
 
 	// 查询系统管理员账号（username为admin）
 	// 系统管理员不与特定应用关联，appID为1（系统默认应用）
 	user, err := h.UserService.GetUserByUsernameForSystem(req.Username)
+	appID := uint(1)
+	if err == nil {
+		appID = user.AppID
+	}
+
+	// 获取应用信息（使用user的AppID），用于登录锁定策略与两步验证策略
+	var app model.Application
+	if err := h.UserService.DB.First(&app, appID).Error; err != nil {
+		service.Log.Errorf("SystemLogin: Application not found, appID=%d", appID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Application not found"})
+	}
+
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, &app, user); lockedFor > 0 {
+		service.Log.Warnf("SystemLogin: login locked, username=%s", req.Username)
+		return rejectLockedLogin(c, lockedFor)
+	}
+
 	if err != nil {
 		service.Log.Errorf("SystemLogin: user not found or not admin: %v, username=%s", err, req.Username)
+		recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, &app, nil, req.Username, LoginActionSystem, "user not found")
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid admin credentials"})
 	}
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		service.Log.Errorf("SystemLogin: password mismatch, username=%s", req.Username)
+		if lockout := recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, &app, user, req.Username, LoginActionSystem, "password mismatch"); lockout > 0 {
+			return rejectLockedLogin(c, lockout)
+		}
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid admin credentials"})
 	}
 
-	// 获取应用信息（使用user的AppID）
-	var app model.Application
-	if err := h.UserService.DB.First(&app, user.AppID).Error; err != nil {
-		service.Log.Errorf("SystemLogin: Application not found, appID=%d", user.AppID)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Application not found"})
+	// 密码已过期时须先修改密码
+	if service.PasswordExpired(&app, user) {
+		service.Log.Warnf("SystemLogin: password expired, username=%s", user.Username)
+		return rejectExpiredPassword(c)
 	}
 
-	// 生成系统管理员JWT令牌（包含应用信息）
-	token, err := h.JWTConfig.GenerateToken(user.ID, user.Username, app.ID, app.UUID)
-	if err != nil {
-		service.Log.Errorf("SystemLogin: Failed to generate token: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to generate token"})
+	// 两步验证（按默认应用的策略）
+	if handled, err := h.requireMFA(c, user, &app, LoginActionSystem); handled {
+		return err
 	}
 
-	// 记录审计日志
-	h.AuditLogService.Record(&model.AuditLog{
-		AppID:    0, // 系统管理员登录记录为系统日志
-		UserID:   user.ID,
-		Username: user.Username,
-		Action:   "SYSTEM_LOGIN",
-		Resource: "APPLICATION",
-		IP:       c.RealIP(),
-		Status:   1,
-	})
-
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"token":   token,
-		"user":    user,
-		"app":     app,
-		"message": "System login successful",
-	})
+	// 生成系统管理员JWT令牌（包含应用信息）及刷新令牌
+	return h.completeLogin(c, user, &app, LoginActionSystem, nil)
 }
 
 // AppLogin 应用登录接口
+// Deprecated: 请使用 OAuth 2.0 令牌端点 /oauth/token (grant_type=client_credentials)
 func (h *AuthHandler) AppLogin(c echo.Context) error {
 	var req struct {
 		AppUUID   string `json:"appUuid" binding:"required"` // 使用UUID而不是AppID
-		AppSecret string `json:"appSecret" binding:"required"`
+		AppSecret string `json:"appSecret"`                  // 签名请求可省略
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -183,8 +304,8 @@ func (h *AuthHandler) AppLogin(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application UUID"})
 	}
 
-	// 检查应用密钥
-	if app.SecretKey != req.AppSecret {
+	// 检查应用密钥（或请求签名）
+	if !verifyCallerApp(c, h.ApplicationService, app, req.AppSecret) {
 		service.Log.Warnf("AppLogin: Invalid application secret, appUUID=%s", req.AppUUID)
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application secret"})
 	}
@@ -219,20 +340,21 @@ func (h *AuthHandler) AppLogin(c echo.Context) error {
 }
 
 // ProxyLogin 代理登录接口（后端透传模式）
+// Deprecated: 请使用 OAuth 2.0 令牌端点 /oauth/token (grant_type=password)
 func (h *AuthHandler) ProxyLogin(c echo.Context) error {
 	var req ProxyLoginRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
 	}
 
-	// 1. 验证应用身份 (AppCode + Secret)
-	app, err := h.ApplicationService.GetApplicationByCode(req.AppCode)
+	// 1. 验证应用身份 (AppCode + Secret，或请求签名)
+	app, err := resolveCallerApp(c, h.ApplicationService, req.AppCode)
 	if err != nil {
 		service.Log.Errorf("ProxyLogin: Invalid application code: %v, appCode=%s", err, req.AppCode)
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application code"})
 	}
 
-	if app.SecretKey != req.AppSecret {
+	if !verifyCallerApp(c, h.ApplicationService, app, req.AppSecret) {
 		service.Log.Warnf("ProxyLogin: Invalid application secret, appCode=%s", req.AppCode)
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application secret"})
 	}
@@ -242,48 +364,47 @@ func (h *AuthHandler) ProxyLogin(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Application is disabled"})
 	}
 
-	// 2. 验证用户身份
+	// 2. 验证用户身份（锁定期内直接拒绝）
 	user, err := h.UserService.GetUserByUsername(req.Username, app.ID)
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		service.Log.Warnf("ProxyLogin: login locked, username=%s, appID=%d", req.Username, app.ID)
+		return rejectLockedLogin(c, lockedFor)
+	}
+
 	if err != nil {
 		service.Log.Errorf("ProxyLogin: Invalid username or password, username=%s, appID=%d", req.Username, app.ID)
+		recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, nil, req.Username, LoginActionProxy, "user not found")
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid username or password"})
 	}
 
 	if user.Status == 0 {
 		service.Log.Warnf("ProxyLogin: User is disabled, username=%s", req.Username)
+		recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, req.Username, LoginActionProxy, "user disabled")
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User is disabled"})
 	}
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		service.Log.Errorf("ProxyLogin: password mismatch, username=%s", req.Username)
+		if lockout := recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, req.Username, LoginActionProxy, "password mismatch"); lockout > 0 {
+			return rejectLockedLogin(c, lockout)
+		}
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid username or password"})
 	}
 
-	// 3. 生成 Token
-	token, err := h.JWTConfig.GenerateToken(user.ID, user.Username, app.ID, app.UUID)
-	if err != nil {
-		service.Log.Errorf("ProxyLogin: Failed to generate token: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to generate token"})
+	// 密码已过期时须先修改密码
+	if service.PasswordExpired(app, user) {
+		service.Log.Warnf("ProxyLogin: password expired, username=%s", user.Username)
+		return rejectExpiredPassword(c)
 	}
 
-	// 4. 记录审计日志
-	h.AuditLogService.Record(&model.AuditLog{
-		AppID:    app.ID,
-		UserID:   user.ID,
-		Username: user.Username,
-		Action:   "PROXY_LOGIN",
-		Resource: "USER",
-		IP:       c.RealIP(),
-		Status:   1,
-	})
+	// 3. 两步验证
+	if handled, err := h.requireMFA(c, user, app, LoginActionProxy); handled {
+		return err
+	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"token":   token,
-		"user":    user,
-		"app":     app,
-		"message": "Proxy login successful",
-	})
+	// 4. 生成 Token 及刷新令牌，记录审计日志
+	return h.completeLogin(c, user, app, LoginActionProxy, nil)
 }
 
 // Login 登录接口（多租户）
@@ -306,71 +427,304 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Application is disabled"})
 	}
 
-	// 查找用户（按应用隔离）
+	// 查找用户（按应用隔离），锁定期内直接拒绝
 	user, err := h.UserService.GetUserByUsername(req.Username, app.ID)
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		service.Log.Warnf("Login: login locked, username=%s, appID=%d", req.Username, app.ID)
+		return rejectLockedLogin(c, lockedFor)
+	}
+
 	if err != nil {
 		service.Log.Errorf("Login: Invalid username or password, username=%s, appID=%d", req.Username, app.ID)
+		recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, nil, req.Username, LoginActionLogin, "user not found")
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid username or password"})
 	}
 
 	// 检查用户状态
 	if user.Status == 0 {
 		service.Log.Warnf("Login: User is disabled, username=%s", req.Username)
+		recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, req.Username, LoginActionLogin, "user disabled")
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User is disabled"})
 	}
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		service.Log.Errorf("Login: password mismatch, username=%s", req.Username)
+		if lockout := recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, req.Username, LoginActionLogin, "password mismatch"); lockout > 0 {
+			return rejectLockedLogin(c, lockout)
+		}
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid username or password"})
+	}
+
+	// 密码已过期时须先修改密码
+	if service.PasswordExpired(app, user) {
+		service.Log.Warnf("Login: password expired, username=%s", user.Username)
+		return rejectExpiredPassword(c)
+	}
+
+	// 两步验证
+	if handled, err := h.requireMFA(c, user, app, LoginActionLogin); handled {
+		return err
+	}
+
+	// 生成JWT令牌（包含应用ID和UUID）及刷新令牌
+	return h.completeLogin(c, user, app, LoginActionLogin, nil)
+}
+
+// ChangePasswordRequest 凭旧密码修改密码请求（无需登录，用于密码过期后修改密码）
+type ChangePasswordRequest struct {
+	AppCode     string `json:"appCode" binding:"required"`
+	Username    string `json:"username" binding:"required"`
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+	Code        string `json:"code"` // 已启用 MFA 的用户需提供验证码或恢复码
+}
+
+// ChangePassword 凭用户名与旧密码修改密码（无需登录，按应用的密码策略校验新密码）
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	var req ChangePasswordRequest
+	if err := c.Bind(&req); err != nil || req.AppCode == "" || req.Username == "" || req.OldPassword == "" || req.NewPassword == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	app, err := h.ApplicationService.GetApplicationByCode(req.AppCode)
+	if err != nil || app.Status == 0 {
+		service.Log.Errorf("ChangePassword: Invalid application code, appCode=%s", req.AppCode)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application code"})
+	}
+
+	user, err := h.UserService.GetUserByUsername(req.Username, app.ID)
+	if err != nil {
+		user = nil
+	}
+	return h.changePassword(c, app, user, req.Username, req.OldPassword, req.NewPassword, req.Code)
+}
+
+// ChangeMyPasswordRequest 当前登录用户修改密码请求
+type ChangeMyPasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+	Code        string `json:"code"` // 已启用 MFA 的用户需提供验证码或恢复码
+}
+
+// ChangeMyPassword 当前登录用户凭旧密码修改自己的密码，校验规则与 ChangePassword 相同
+func (h *AuthHandler) ChangeMyPassword(c echo.Context) error {
+	if apiKeyFromContext(c) != nil {
+		return rejectAPIKeyAccess(c)
+	}
+	userID, _ := c.Get("userID").(uint)
+	appID, _ := c.Get("appID").(uint)
+	if userID == 0 || appID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+
+	var req ChangeMyPasswordRequest
+	if err := c.Bind(&req); err != nil || req.OldPassword == "" || req.NewPassword == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	user, err := h.UserService.GetUserByID(userID, appID)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+	app, err := h.ApplicationService.GetApplicationByID(fmt.Sprintf("%d", appID))
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Application not found"})
+	}
+
+	return h.changePassword(c, app, user, user.Username, req.OldPassword, req.NewPassword, req.Code)
+}
+
+// changePassword 校验旧密码（及 MFA）后修改密码。user 为 nil 表示用户不存在。
+// 与登录一样计入连续失败次数；修改成功后吊销该用户已签发的全部令牌
+func (h *AuthHandler) changePassword(c echo.Context, app *model.Application, user *model.User, username, oldPassword, newPassword, code string) error {
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		return rejectLockedLogin(c, lockedFor)
+	}
+
+	if user == nil || user.Status == 0 || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)) != nil {
+		service.Log.Errorf("ChangePassword: Invalid username or password, username=%s, appID=%d", username, app.ID)
+		if lockout := recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, username, "CHANGE_PASSWORD", "invalid username or password"); lockout > 0 {
+			return rejectLockedLogin(c, lockout)
+		}
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid username or password"})
 	}
 
-	// 生成JWT令牌（包含应用ID和UUID）
+	// 已启用 MFA 的用户修改密码同样需要第二因素
+	if user.MFAEnabled {
+		if code == "" {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"mfaRequired": true,
+				"message":     "MFA code is required",
+			})
+		}
+		if err := h.MFAService.Verify(user, code); err != nil {
+			service.Log.Warnf("ChangePassword: mfa verification failed: %v, username=%s", err, user.Username)
+			if lockout := recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, username, "CHANGE_PASSWORD", "invalid mfa code"); lockout > 0 {
+				return rejectLockedLogin(c, lockout)
+			}
+			return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid mfa code"})
+		}
+	}
+
+	if err := h.UserService.UpdateUserPassword(user.ID, app.ID, newPassword); err != nil {
+		if violations, ok := service.IsPasswordPolicyError(err); ok {
+			return passwordPolicyErrorResponse(c, violations)
+		}
+		service.Log.Errorf("ChangePassword: Failed to update password: %v, userID=%d", err, user.ID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to change password"})
+	}
+
+	if err := h.LoginGuardService.ResetUser(app.ID, user.ID); err != nil {
+		service.Log.Warnf("ChangePassword: Failed to reset login failures: %v, userID=%d", err, user.ID)
+	}
+	if err := h.TokenRevocationService.RevokeUserTokens(user.ID); err != nil {
+		service.Log.Errorf("ChangePassword: Failed to revoke user tokens: %v, userID=%d", err, user.ID)
+	}
+
+	h.AuditLogService.Record(&model.AuditLog{
+		AppID:      app.ID,
+		UserID:     user.ID,
+		Username:   user.Username,
+		Action:     "CHANGE_PASSWORD",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    "用户修改密码",
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password changed successfully, please log in again"})
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌（刷新令牌同时轮换）
+func (h *AuthHandler) RefreshToken(c echo.Context) error {
+	var req RefreshTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	// 1. 验证应用身份 (AppCode + Secret，或请求签名)
+	app, err := resolveCallerApp(c, h.ApplicationService, req.AppCode)
+	if err != nil {
+		service.Log.Errorf("RefreshToken: Invalid application code: %v, appCode=%s", err, req.AppCode)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application code"})
+	}
+
+	if !verifyCallerApp(c, h.ApplicationService, app, req.AppSecret) {
+		service.Log.Warnf("RefreshToken: Invalid application secret, appCode=%s", req.AppCode)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application secret"})
+	}
+
+	if app.Status == 0 {
+		service.Log.Warnf("RefreshToken: Application is disabled, appCode=%s", req.AppCode)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Application is disabled"})
+	}
+
+	// 2. 轮换刷新令牌
+	current, refreshToken, err := h.RefreshTokenService.RotateRefreshToken(req.RefreshToken, app.ID, c.RealIP())
+	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			service.Log.Warnf("RefreshToken: refresh token reuse detected, family revoked, userID=%d, appID=%d", current.UserID, app.ID)
+			h.AuditLogService.Record(&model.AuditLog{
+				AppID:    app.ID,
+				UserID:   current.UserID,
+				Action:   "REFRESH_TOKEN_REUSE",
+				Resource: "USER",
+				Content:  fmt.Sprintf("刷新令牌被重复使用，已吊销令牌家族: %s", current.FamilyID),
+				IP:       c.RealIP(),
+				Status:   0,
+				ErrorMsg: err.Error(),
+			})
+			return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Refresh token reuse detected"})
+		}
+		service.Log.Warnf("RefreshToken: %v, appCode=%s", err, req.AppCode)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired refresh token"})
+	}
+
+	// 3. 确认用户仍然可用
+	user, err := h.UserService.GetUserByID(current.UserID, app.ID)
+	if err != nil || user.Status == 0 {
+		service.Log.Warnf("RefreshToken: user not found or disabled, userID=%d, appID=%d", current.UserID, app.ID)
+		_ = h.RefreshTokenService.RevokeFamily(current.FamilyID)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User is disabled"})
+	}
+
+	// 4. 签发新的访问令牌
 	token, err := h.JWTConfig.GenerateToken(user.ID, user.Username, app.ID, app.UUID)
 	if err != nil {
-		service.Log.Errorf("Login: Failed to generate token: %v", err)
+		service.Log.Errorf("RefreshToken: Failed to generate token: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to generate token"})
 	}
 
-	// 记录审计日志
+	// 5. 记录审计日志
 	h.AuditLogService.Record(&model.AuditLog{
 		AppID:    app.ID,
 		UserID:   user.ID,
 		Username: user.Username,
-		Action:   "LOGIN",
+		Action:   "REFRESH_TOKEN",
 		Resource: "USER",
 		IP:       c.RealIP(),
 		Status:   1,
 	})
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"token":   token,
-		"user":    user,
-		"app":     app,
-		"message": "Login successful",
+		"token":        token,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(h.JWTConfig.ExpireTime.Seconds()),
+		"message":      "Token refreshed successfully",
 	})
 }
 
-// Logout 登出接口
+// Logout 登出接口：吊销当前访问令牌及其刷新令牌家族
 func (h *AuthHandler) Logout(c echo.Context) error {
-	// 尝试记录审计日志
-	appIDInterface := c.Get("appID")
-	userIDInterface := c.Get("userID")
-	usernameInterface := c.Get("username")
-
-	if appIDInterface != nil && userIDInterface != nil {
-		h.AuditLogService.Record(&model.AuditLog{
-			AppID:    appIDInterface.(uint),
-			UserID:   userIDInterface.(uint),
-			Username: usernameInterface.(string),
-			Action:   "LOGOUT",
-			Resource: "USER",
-			IP:       c.RealIP(),
-			Status:   1,
-		})
-	}
-
-	// JWT是无状态的，登出只需客户端删除令牌即可
+	var req LogoutRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	tokenString := req.Token
+	if tokenString == "" {
+		tokenString = strings.TrimPrefix(c.Request().Header.Get("X-Authos-Token"), "Bearer ")
+	}
+
+	if tokenString != "" {
+		// 已过期或无效的令牌无需吊销
+		if claims, err := h.JWTConfig.ParseMapClaims(tokenString); err == nil {
+			jti, _ := claims["jti"].(string)
+			var expiresAt time.Time
+			if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+				expiresAt = exp.Time
+			}
+			if jti != "" {
+				if err := h.TokenRevocationService.RevokeToken(jti, expiresAt); err != nil {
+					service.Log.Errorf("Logout: Failed to revoke token: %v", err)
+					return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to revoke token"})
+				}
+			}
+
+			// 记录审计日志
+			userIDFloat, _ := claims["userId"].(float64)
+			appIDFloat, _ := claims["appId"].(float64)
+			username, _ := claims["username"].(string)
+			h.AuditLogService.Record(&model.AuditLog{
+				AppID:    uint(appIDFloat),
+				UserID:   uint(userIDFloat),
+				Username: username,
+				Action:   "LOGOUT",
+				Resource: "USER",
+				IP:       c.RealIP(),
+				Status:   1,
+			})
+		}
+	}
+
+	if req.RefreshToken != "" {
+		if err := h.RefreshTokenService.RevokeRefreshToken(req.RefreshToken); err != nil {
+			service.Log.Warnf("Logout: Failed to revoke refresh token: %v", err)
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{"message": "Logout successful"})
 }
 
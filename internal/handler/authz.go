@@ -1,8 +1,9 @@
 package handler
 
 import (
-	"log"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -12,34 +13,62 @@ import (
 
 // AuthzHandler 权限处理器
 type AuthzHandler struct {
-	CasbinService        *service.CasbinService
-	MenuService          *service.MenuService
-	ApplicationService   *service.ApplicationService
-	ApiPermissionService *service.ApiPermissionService
-	JWTConfig            *service.JWTConfig
+	CasbinService          *service.CasbinService
+	MenuService            *service.MenuService
+	ApplicationService     *service.ApplicationService
+	ApiPermissionService   *service.ApiPermissionService
+	TokenRevocationService *service.TokenRevocationService
+	APIKeyService          *service.APIKeyService
+	JWTConfig              *service.JWTConfig
 }
 
 // NewAuthzHandler 创建权限处理器实例
-func NewAuthzHandler(casbinService *service.CasbinService, menuService *service.MenuService, applicationService *service.ApplicationService, apiPermissionService *service.ApiPermissionService, jwtConfig *service.JWTConfig) *AuthzHandler {
+func NewAuthzHandler(casbinService *service.CasbinService, menuService *service.MenuService, applicationService *service.ApplicationService, apiPermissionService *service.ApiPermissionService, tokenRevocationService *service.TokenRevocationService, apiKeyService *service.APIKeyService, jwtConfig *service.JWTConfig) *AuthzHandler {
 	return &AuthzHandler{
-		CasbinService:        casbinService,
-		MenuService:          menuService,
-		ApplicationService:   applicationService,
-		ApiPermissionService: apiPermissionService,
-		JWTConfig:            jwtConfig,
+		CasbinService:          casbinService,
+		MenuService:            menuService,
+		ApplicationService:     applicationService,
+		ApiPermissionService:   apiPermissionService,
+		TokenRevocationService: tokenRevocationService,
+		APIKeyService:          apiKeyService,
+		JWTConfig:              jwtConfig,
 	}
 }
 
 // CheckPermissionReq 权限检查请求
 type CheckPermissionReq struct {
-	UserID uint   `json:"userId" binding:"required"`
-	Obj    string `json:"obj" binding:"required"`
-	Act    string `json:"act" binding:"required"`
+	UserID  uint   `json:"userId" binding:"required"`
+	Obj     string `json:"obj" binding:"required"`
+	Act     string `json:"act" binding:"required"`
+	Explain bool   `json:"explain"` // 是否返回逐个角色的检查过程
+	// Context 权限条件的求值上下文（ip、time、attributes）
+	Context *service.PermissionContext `json:"context"`
 }
 
 type CheckPermissionByKeyReq struct {
-	UserID     uint   `json:"userId" binding:"required"`
-	Permission string `json:"permission" binding:"required"`
+	UserID     uint                       `json:"userId" binding:"required"`
+	Permission string                     `json:"permission" binding:"required"`
+	Context    *service.PermissionContext `json:"context"` // 权限条件的求值上下文
+}
+
+// checkUserInApp 校验被检查的用户属于当前应用，避免跨租户探测用户的角色与权限（explain 会返回判定过程）；
+// 系统管理员可以检查任意用户。校验通过时返回状态码 0
+func (h *AuthzHandler) checkUserInApp(c echo.Context, userID uint) (int, string) {
+	if isSystemAdmin, _ := c.Get("isSystemAdmin").(bool); isSystemAdmin {
+		return 0, ""
+	}
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return http.StatusUnauthorized, "Unauthorized"
+	}
+	var count int64
+	if err := h.CasbinService.DB.Model(&model.User{}).Where("id = ? AND app_id = ?", userID, appID).Count(&count).Error; err != nil {
+		return http.StatusInternalServerError, "Failed to check permission"
+	}
+	if count == 0 {
+		return http.StatusNotFound, "User not found"
+	}
+	return 0, ""
 }
 
 // CheckPermission 检查权限
@@ -49,8 +78,24 @@ func (h *AuthzHandler) CheckPermission(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
 	}
 
+	if status, message := h.checkUserInApp(c, req.UserID); status != 0 {
+		return c.JSON(status, map[string]string{"message": message})
+	}
+
+	if req.Explain {
+		explanation, err := h.CasbinService.ExplainPermission(req.UserID, req.Obj, req.Act, req.Context)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"allowed": explanation.Allowed,
+			"explain": explanation,
+			"message": "Permission checked successfully",
+		})
+	}
+
 	// 调用 Casbin 检查权限
-	allowed, err := h.CasbinService.CheckPermission(req.UserID, req.Obj, req.Act)
+	allowed, err := h.CasbinService.CheckPermission(req.UserID, req.Obj, req.Act, req.Context)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
 	}
@@ -63,68 +108,294 @@ func (h *AuthzHandler) CheckPermission(c echo.Context) error {
 
 // CheckPermissionWithSecretReq 统一鉴权请求（带Secret）
 type CheckPermissionWithSecretReq struct {
-	AppCode   string `json:"appCode" binding:"required"`
-	AppSecret string `json:"appSecret" binding:"required"`
-	Token     string `json:"token" binding:"required"`
-	Obj       string `json:"obj" binding:"required"` // 访问路径
-	Act       string `json:"act" binding:"required"` // 访问方法
+	AppCode   string `json:"appCode"`                  // 签名请求可省略
+	AppSecret string `json:"appSecret"`                // 签名请求可省略
+	Token     string `json:"token" binding:"required"` // 用户令牌或个人 API 密钥
+	Obj       string `json:"obj" binding:"required"`   // 访问路径
+	Act       string `json:"act" binding:"required"`   // 访问方法
+	Explain   bool   `json:"explain"`                  // 是否返回判定过程（匹配的接口权限、逐个角色的检查结果）
+	// Context 权限条件的求值上下文：终端用户 ip、请求时间 time 与任意属性 attributes
+	Context *service.PermissionContext `json:"context"`
 }
 
-// CheckPermissionWithSecret 统一鉴权接口
-func (h *AuthzHandler) CheckPermissionWithSecret(c echo.Context) error {
-	var req CheckPermissionWithSecretReq
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
-	}
+// accessCaller 统一鉴权的调用方：已验证的应用及令牌所属用户
+type accessCaller struct {
+	App    *model.Application
+	UserID uint
+	APIKey *model.APIKey // 使用个人 API 密钥时不为空
+}
 
-	// 1. 验证应用身份
-	app, err := h.ApplicationService.GetApplicationByCode(req.AppCode)
+// authenticateAccessCaller 验证应用身份与用户令牌（或个人 API 密钥），失败时返回 HTTP 状态码与错误信息
+func (h *AuthzHandler) authenticateAccessCaller(c echo.Context, appCode, appSecret, token string) (*accessCaller, int, string) {
+	// 1. 验证应用身份 (AppCode + Secret，或请求签名)
+	app, err := resolveCallerApp(c, h.ApplicationService, appCode)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application code"})
+		return nil, http.StatusUnauthorized, "Invalid application code"
 	}
 
-	if app.SecretKey != req.AppSecret {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application secret"})
+	if !verifyCallerApp(c, h.ApplicationService, app, appSecret) {
+		return nil, http.StatusUnauthorized, "Invalid application secret"
 	}
 
 	if app.Status == 0 {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Application is disabled"})
+		return nil, http.StatusUnauthorized, "Application is disabled"
 	}
 
-	// 2. 解析并验证 Token
-	claims, err := h.JWTConfig.ParseToken(req.Token)
-	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token"})
+	// 2. 解析并验证 Token（或个人 API 密钥）
+	if service.IsAPIKey(token) {
+		key, user, err := h.APIKeyService.Authenticate(token, c.RealIP())
+		if err != nil {
+			return nil, http.StatusUnauthorized, "Invalid api key"
+		}
+		if key.AppID != app.ID {
+			return nil, http.StatusUnauthorized, "Token does not belong to this application"
+		}
+		return &accessCaller{App: app, UserID: user.ID, APIKey: key}, 0, ""
+	}
+
+	claims, err := h.JWTConfig.ParseToken(token)
+	if err != nil || claims.Type != "user" || claims.UserID == 0 {
+		return nil, http.StatusUnauthorized, "Invalid token"
 	}
 
 	// 验证 Token 是否属于该应用
 	if claims.AppID != app.ID {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Token does not belong to this application"})
+		return nil, http.StatusUnauthorized, "Token does not belong to this application"
+	}
+
+	// 检查 Token 是否已被吊销（登出、禁用用户或管理员强制下线）
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := h.TokenRevocationService.IsTokenRevoked(claims.ID, claims.UserID, claims.AppID, issuedAt)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to check token revocation"
+	}
+	if revoked {
+		return nil, http.StatusUnauthorized, "Token has been revoked"
+	}
+	return &accessCaller{App: app, UserID: claims.UserID}, 0, ""
+}
+
+// CheckPermissionWithSecret 统一鉴权接口
+func (h *AuthzHandler) CheckPermissionWithSecret(c echo.Context) error {
+	var req CheckPermissionWithSecretReq
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	caller, status, message := h.authenticateAccessCaller(c, req.AppCode, req.AppSecret, req.Token)
+	if caller == nil {
+		return c.JSON(status, map[string]string{"message": message})
+	}
+	app, userID, apiKey := caller.App, caller.UserID, caller.APIKey
+
+	if req.Explain {
+		return h.explainCheckAccess(c, caller, req.Obj, req.Act, req.Context)
 	}
 
 	// 3. 根据路径和方法解析对应的接口权限（支持 * 通配方法）
 	permission, err := h.ApiPermissionService.GetApiPermissionByPathAndMethod(app.ID, req.Obj, req.Act)
 	if err != nil {
 		// 如果未找到对应权限，直接视为无权限
-		log.Printf("permission not found for appID=%d path=%s method=%s: %v", app.ID, req.Obj, req.Act, err)
+		service.Log.Debugf("CheckAccess: permission not found for appID=%d path=%s method=%s: %v", app.ID, req.Obj, req.Act, err)
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"allowed": false,
-			"userId":  claims.UserID,
+			"userId":  userID,
 			"message": "Permission not found",
 		})
 	}
 
 	// 4. 使用权限标识 + 请求方法 交给 Casbin 检查（策略里方法为 * 时也可匹配）
-	log.Printf("Checking permission for userID: %d, key: %s, path: %s, act: %s", claims.UserID, permission.Key, req.Obj, req.Act)
-	allowed, err := h.CasbinService.CheckPermission(claims.UserID, permission.Key, req.Act)
+	service.Log.Debugf("CheckAccess: userID=%d, key=%s, path=%s, act=%s", userID, permission.Key, req.Obj, req.Act)
+	allowed, dataScope, err := h.CasbinService.CheckPermissionWithDataScope(userID, permission.Key, req.Act, req.Context)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	// API 密钥限定了权限范围时，超出范围的权限一律拒绝
+	if allowed && apiKey != nil && !service.APIKeyAllows(apiKey, permission.Key) {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"allowed": false,
+			"userId":  userID,
+			"message": "Permission is outside the api key scopes",
+		})
+	}
+
+	response := map[string]interface{}{
 		"allowed": allowed,
-		"userId":  claims.UserID,
+		"userId":  userID,
 		"message": "Permission checked successfully",
+	}
+	// 放行时返回数据范围，供租户后端过滤查询
+	if allowed {
+		response["dataScope"] = dataScope
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// AccessExplanation 统一鉴权的判定过程（explain 模式）
+type AccessExplanation struct {
+	Match    *service.ApiPermissionMatch    `json:"match"`              // 路径匹配接口权限的过程
+	Decision *service.PermissionExplanation `json:"decision,omitempty"` // 权限标识的角色检查过程
+	Reason   string                         `json:"reason"`             // 最终结论
+}
+
+// explainCheckAccess 统一鉴权的 explain 模式：判定结果与普通模式一致，额外返回判定过程
+func (h *AuthzHandler) explainCheckAccess(c echo.Context, caller *accessCaller, obj, act string, ctx *service.PermissionContext) error {
+	match, err := h.ApiPermissionService.ExplainApiPermissionMatch(caller.App.ID, obj, act)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
+	}
+
+	explanation := &AccessExplanation{Match: match}
+	if match.Permission == nil {
+		explanation.Reason = "no api permission matched: " + match.Reason
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"allowed": false,
+			"userId":  caller.UserID,
+			"explain": explanation,
+			"message": "Permission not found",
+		})
+	}
+
+	decision, err := h.CasbinService.ExplainPermission(caller.UserID, match.Permission.Key, act, ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
+	}
+	explanation.Decision = decision
+	explanation.Reason = decision.Reason
+
+	message := "Permission checked successfully"
+	allowed := decision.Allowed
+	if allowed && caller.APIKey != nil && !service.APIKeyAllows(caller.APIKey, match.Permission.Key) {
+		allowed = false
+		message = "Permission is outside the api key scopes"
+		explanation.Reason = fmt.Sprintf("%s is outside the api key scopes %v", match.Permission.Key, caller.APIKey.Scopes)
+	}
+
+	response := map[string]interface{}{
+		"allowed": allowed,
+		"userId":  caller.UserID,
+		"explain": explanation,
+		"message": message,
+	}
+	if allowed {
+		_, dataScope, err := h.CasbinService.CheckPermissionWithDataScope(caller.UserID, match.Permission.Key, act, ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
+		}
+		response["dataScope"] = dataScope
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// batchCheckMaxItems 批量鉴权单次最多检查的条目数
+const batchCheckMaxItems = 100
+
+// AccessCheckItem 批量鉴权的一项：按访问路径 + 方法（obj/act）或按权限标识（permission）检查
+type AccessCheckItem struct {
+	Obj        string `json:"obj,omitempty"`        // 访问路径
+	Act        string `json:"act,omitempty"`        // 访问方法
+	Permission string `json:"permission,omitempty"` // 权限标识，填写时忽略 obj/act
+}
+
+// AccessDecision 批量鉴权单项结果
+type AccessDecision struct {
+	AccessCheckItem
+	Allowed   bool               `json:"allowed"`
+	DataScope *service.DataScope `json:"dataScope,omitempty"` // 放行时的数据范围
+	Message   string             `json:"message"`
+}
+
+// CheckAccessBatchReq 批量统一鉴权请求
+type CheckAccessBatchReq struct {
+	AppCode   string            `json:"appCode"`                  // 签名请求可省略
+	AppSecret string            `json:"appSecret"`                // 签名请求可省略
+	Token     string            `json:"token" binding:"required"` // 用户令牌或个人 API 密钥
+	Checks    []AccessCheckItem `json:"checks" binding:"required"`
+	// Context 权限条件的求值上下文，对全部检查项生效
+	Context *service.PermissionContext `json:"context"`
+}
+
+// CheckAccessBatch 批量统一鉴权接口：应用与令牌只校验一次，逐项返回鉴权结果（顺序与请求一致）
+func (h *AuthzHandler) CheckAccessBatch(c echo.Context) error {
+	var req CheckAccessBatchReq
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+	if len(req.Checks) == 0 || len(req.Checks) > batchCheckMaxItems {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": fmt.Sprintf("checks must contain 1 to %d items", batchCheckMaxItems)})
+	}
+
+	caller, status, message := h.authenticateAccessCaller(c, req.AppCode, req.AppSecret, req.Token)
+	if caller == nil {
+		return c.JSON(status, map[string]string{"message": message})
+	}
+
+	// 1. 将每一项解析为 权限标识 + 方法；按权限标识检查时不区分方法
+	var keys []string
+	for _, item := range req.Checks {
+		if item.Permission != "" {
+			keys = append(keys, item.Permission)
+		}
+	}
+	existingKeys, err := h.ApiPermissionService.GetExistingPermissionKeys(caller.App.ID, keys)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
+	}
+
+	results := make([]AccessDecision, len(req.Checks))
+	requests := make([]service.PermissionRequest, 0, len(req.Checks))
+	pending := make([]int, 0, len(req.Checks)) // requests[i] 对应 results[pending[i]]
+	for i, item := range req.Checks {
+		results[i] = AccessDecision{AccessCheckItem: item, Message: "Permission not found"}
+
+		switch {
+		case item.Permission != "":
+			if !existingKeys[item.Permission] {
+				continue
+			}
+			requests = append(requests, service.PermissionRequest{Obj: item.Permission, Act: model.HTTP_ALL})
+		case item.Obj != "" && item.Act != "":
+			permission, err := h.ApiPermissionService.GetApiPermissionByPathAndMethod(caller.App.ID, item.Obj, item.Act)
+			if err != nil {
+				continue
+			}
+			results[i].Permission = permission.Key
+			requests = append(requests, service.PermissionRequest{Obj: permission.Key, Act: item.Act})
+		default:
+			results[i].Message = "Either permission or obj and act is required"
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	// 2. 用户角色只加载一次，批量交给 Casbin 检查
+	if len(requests) > 0 {
+		allowed, dataScopes, err := h.CasbinService.CheckPermissionsWithDataScope(caller.UserID, requests, req.Context)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
+		}
+		for j, i := range pending {
+			results[i].Allowed = allowed[j]
+			results[i].DataScope = dataScopes[j]
+			results[i].Message = "Permission checked successfully"
+
+			// API 密钥限定了权限范围时，超出范围的权限一律拒绝
+			if allowed[j] && caller.APIKey != nil && !service.APIKeyAllows(caller.APIKey, requests[j].Obj) {
+				results[i].Allowed = false
+				results[i].DataScope = nil
+				results[i].Message = "Permission is outside the api key scopes"
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"results": results,
+		"userId":  caller.UserID,
+		"message": "Permissions checked successfully",
 	})
 }
 
@@ -135,7 +406,11 @@ func (h *AuthzHandler) CheckPermissionByKey(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
 	}
 
-	allowed, err := h.CasbinService.CheckPermission(req.UserID, req.Permission, model.HTTP_ALL)
+	if status, message := h.checkUserInApp(c, req.UserID); status != 0 {
+		return c.JSON(status, map[string]string{"message": message})
+	}
+
+	allowed, err := h.CasbinService.CheckPermission(req.UserID, req.Permission, model.HTTP_ALL, req.Context)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check permission"})
 	}
@@ -146,6 +421,66 @@ func (h *AuthzHandler) CheckPermissionByKey(c echo.Context) error {
 	})
 }
 
+// respondUserPermissions 返回有效权限，携带 ETag；请求的 If-None-Match 与当前版本一致时返回 304
+func respondUserPermissions(c echo.Context, permissions *service.UserPermissions) error {
+	etag := `"` + permissions.Version + `"`
+	c.Response().Header().Set("ETag", etag)
+	c.Response().Header().Set("Cache-Control", "private, no-cache")
+	if match := c.Request().Header.Get("If-None-Match"); match == etag || match == permissions.Version {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"permissions": permissions,
+		"message":     "User permissions retrieved successfully",
+	})
+}
+
+// GetMyPermissions 获取当前用户的有效权限（权限标识、接口与按钮标识），供前端缓存并控制按钮显示
+func (h *AuthzHandler) GetMyPermissions(c echo.Context) error {
+	userID, _ := c.Get("userID").(uint)
+	appID, _ := c.Get("appID").(uint)
+	if userID == 0 || appID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not authenticated"})
+	}
+
+	permissions, err := h.ApiPermissionService.GetUserPermissions(userID, appID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get user permissions"})
+	}
+	permissions.FilterByAPIKey(apiKeyFromContext(c))
+
+	return respondUserPermissions(c, permissions)
+}
+
+// UserPermissionsWithSecretReq 获取用户有效权限请求（带Secret）
+type UserPermissionsWithSecretReq struct {
+	AppCode   string `json:"appCode"`                  // 签名请求可省略
+	AppSecret string `json:"appSecret"`                // 签名请求可省略
+	Token     string `json:"token" binding:"required"` // 用户令牌或个人 API 密钥
+}
+
+// GetUserPermissionsWithSecret 租户后端获取令牌所属用户的有效权限
+func (h *AuthzHandler) GetUserPermissionsWithSecret(c echo.Context) error {
+	var req UserPermissionsWithSecretReq
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	caller, status, message := h.authenticateAccessCaller(c, req.AppCode, req.AppSecret, req.Token)
+	if caller == nil {
+		return c.JSON(status, map[string]string{"message": message})
+	}
+
+	permissions, err := h.ApiPermissionService.GetUserPermissions(caller.UserID, caller.App.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get user permissions"})
+	}
+	permissions.FilterByAPIKey(caller.APIKey)
+
+	return respondUserPermissions(c, permissions)
+}
+
 // GetUserNav 获取用户导航菜单
 func (h *AuthzHandler) GetUserNav(c echo.Context) error {
 	// 从上下文获取用户ID
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// DepartmentHandler 部门处理器
+type DepartmentHandler struct {
+	DepartmentService *service.DepartmentService
+}
+
+// NewDepartmentHandler 创建部门处理器实例
+func NewDepartmentHandler(departmentService *service.DepartmentService) *DepartmentHandler {
+	return &DepartmentHandler{DepartmentService: departmentService}
+}
+
+// DepartmentRequest 创建/更新部门请求
+type DepartmentRequest struct {
+	ParentID uint   `json:"parentId"` // 0 为根部门
+	Name     string `json:"name"`
+	Sort     int    `json:"sort"`
+	LeaderID uint   `json:"leaderId"` // 负责人用户ID，0 表示未设置
+}
+
+// departmentError 将部门服务的错误转换为响应：记录不存在返回 404，其余为请求错误
+func departmentError(c echo.Context, err error, notFound string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": notFound})
+	}
+	return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+}
+
+// audit 记录部门相关的审计日志
+func (h *DepartmentHandler) audit(c echo.Context, appID uint, action, resourceID, content string) {
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.DepartmentService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     action,
+		Resource:   "DEPARTMENT",
+		ResourceID: resourceID,
+		Content:    content,
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+}
+
+// CreateDepartment 创建部门
+func (h *DepartmentHandler) CreateDepartment(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	var req DepartmentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "name is required"})
+	}
+
+	dept := &model.Department{ParentID: req.ParentID, Name: strings.TrimSpace(req.Name), Sort: req.Sort, LeaderID: req.LeaderID, AppID: appID}
+	if err := h.DepartmentService.CreateDepartment(dept); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	h.audit(c, appID, "CREATE", fmt.Sprintf("%d", dept.ID), fmt.Sprintf("创建部门: %s", dept.Name))
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"department": dept,
+		"message":    "Department created successfully",
+	})
+}
+
+// UpdateDepartment 更新部门
+func (h *DepartmentHandler) UpdateDepartment(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid department ID"})
+	}
+
+	var req DepartmentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "name is required"})
+	}
+
+	dept := &model.Department{ParentID: req.ParentID, Name: strings.TrimSpace(req.Name), Sort: req.Sort, LeaderID: req.LeaderID, AppID: appID}
+	dept.ID = uint(id)
+	if err := h.DepartmentService.UpdateDepartment(dept); err != nil {
+		return departmentError(c, err, "Department not found")
+	}
+
+	h.audit(c, appID, "UPDATE", idStr, fmt.Sprintf("更新部门: %s", dept.Name))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"department": dept,
+		"message":    "Department updated successfully",
+	})
+}
+
+// DeleteDepartment 删除部门（存在子部门时不允许删除）
+func (h *DepartmentHandler) DeleteDepartment(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid department ID"})
+	}
+
+	if err := h.DepartmentService.DeleteDepartment(uint(id), appID); err != nil {
+		return departmentError(c, err, "Department not found")
+	}
+
+	h.audit(c, appID, "DELETE", idStr, fmt.Sprintf("删除部门ID: %d", id))
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Department deleted successfully"})
+}
+
+// GetDepartment 获取部门
+func (h *DepartmentHandler) GetDepartment(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid department ID"})
+	}
+
+	dept, err := h.DepartmentService.GetDepartmentByID(uint(id), appID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "Department not found"})
+	}
+
+	return c.JSON(http.StatusOK, dept)
+}
+
+// ListDepartments 列出所有部门（扁平结构）
+func (h *DepartmentHandler) ListDepartments(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	departments, err := h.DepartmentService.ListDepartmentsByApp(appID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get departments"})
+	}
+
+	return c.JSON(http.StatusOK, departments)
+}
+
+// GetDepartmentTree 获取部门树
+func (h *DepartmentHandler) GetDepartmentTree(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	tree, err := h.DepartmentService.GetDepartmentTreeByApp(appID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get department tree"})
+	}
+
+	return c.JSON(http.StatusOK, tree)
+}
+
+// ListDepartmentUsers 获取部门成员
+func (h *DepartmentHandler) ListDepartmentUsers(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid department ID"})
+	}
+
+	users, err := h.DepartmentService.ListDepartmentUsers(uint(id), appID)
+	if err != nil {
+		return departmentError(c, err, "Department not found")
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+// SetDepartmentUsersRequest 设置部门成员请求
+type SetDepartmentUsersRequest struct {
+	UserIDs []uint `json:"userIds"` // 为空表示清空成员
+}
+
+// SetDepartmentUsers 替换部门成员
+func (h *DepartmentHandler) SetDepartmentUsers(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid department ID"})
+	}
+
+	var req SetDepartmentUsersRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.DepartmentService.SetDepartmentUsers(uint(id), appID, req.UserIDs); err != nil {
+		return departmentError(c, err, "Department not found")
+	}
+
+	h.audit(c, appID, "UPDATE", idStr, fmt.Sprintf("设置部门成员, 部门ID: %d, 用户ID: %v", id, req.UserIDs))
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Department users updated successfully"})
+}
+
+// SetDepartmentRolesRequest 设置部门角色请求
+type SetDepartmentRolesRequest struct {
+	RoleIDs []uint `json:"roleIds"` // 为空表示清空角色
+}
+
+// SetDepartmentRoles 替换授予部门的角色，部门成员继承这些角色
+func (h *DepartmentHandler) SetDepartmentRoles(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid department ID"})
+	}
+
+	var req SetDepartmentRolesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.DepartmentService.SetDepartmentRoles(uint(id), appID, req.RoleIDs); err != nil {
+		return departmentError(c, err, "Department not found")
+	}
+
+	h.audit(c, appID, "UPDATE", idStr, fmt.Sprintf("设置部门角色, 部门ID: %d, 角色ID: %v", id, req.RoleIDs))
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Department roles updated successfully"})
+}
+
+// SetUserDepartmentsRequest 设置用户所属部门请求
+type SetUserDepartmentsRequest struct {
+	DepartmentIDs []uint `json:"departmentIds"` // 为空表示移出全部部门
+}
+
+// SetUserDepartments 替换用户所属的部门
+func (h *DepartmentHandler) SetUserDepartments(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user ID"})
+	}
+
+	var req SetUserDepartmentsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.DepartmentService.SetUserDepartments(uint(id), appID, req.DepartmentIDs); err != nil {
+		return departmentError(c, err, "User not found")
+	}
+
+	h.audit(c, appID, "UPDATE", idStr, fmt.Sprintf("设置用户部门, 用户ID: %d, 部门ID: %v", id, req.DepartmentIDs))
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User departments updated successfully"})
+}
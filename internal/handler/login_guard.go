@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// retryAfterSeconds 剩余锁定时长（向上取整到秒）
+func retryAfterSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// loginLockedFor 返回用户或客户端 IP 剩余的锁定时长，user 为 nil（用户不存在）时只检查 IP
+func loginLockedFor(c echo.Context, loginGuardService *service.LoginGuardService, app *model.Application, user *model.User) time.Duration {
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+	return loginGuardService.LockedFor(app, userID, c.RealIP())
+}
+
+// rejectLockedLogin 账号或 IP 处于锁定期时返回 429，并通过 Retry-After 告知剩余秒数
+func rejectLockedLogin(c echo.Context, lockedFor time.Duration) error {
+	seconds := retryAfterSeconds(lockedFor)
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+		"message":    "Too many failed login attempts, please try again later",
+		"retryAfter": seconds,
+	})
+}
+
+// recordLoginFailure 记录一次登录失败：累计用户与 IP 的失败次数并写入失败审计日志，返回因此触发的锁定时长。
+// user 为 nil 表示用户不存在，此时只按 IP 计数
+func recordLoginFailure(c echo.Context, loginGuardService *service.LoginGuardService, auditLogService *service.AuditLogService, app *model.Application, user *model.User, username, action, reason string) time.Duration {
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+
+	lockout, err := loginGuardService.RecordFailure(app, userID, c.RealIP())
+	if err != nil {
+		service.Log.Errorf("%s: %v", action, err)
+	}
+
+	content := fmt.Sprintf("登录失败: %s", username)
+	if lockout > 0 {
+		content = fmt.Sprintf("%s，连续失败次数过多，锁定 %s", content, lockout)
+		service.Log.Warnf("%s: login locked for %s, username=%s, appID=%d, ip=%s", action, lockout, username, app.ID, c.RealIP())
+	}
+
+	auditLog := &model.AuditLog{
+		AppID:    app.ID,
+		UserID:   userID,
+		Username: username,
+		Action:   action,
+		Resource: "USER",
+		Content:  content,
+		IP:       c.RealIP(),
+		Status:   0,
+		ErrorMsg: reason,
+	}
+	if action == LoginActionSystem {
+		// 与成功日志一致，系统管理员登录记录为系统日志
+		auditLog.AppID = 0
+		auditLog.Resource = "APPLICATION"
+	}
+	auditLogService.Record(auditLog)
+
+	return lockout
+}
+
+// recordMFAFailure 记录一次两步验证失败并写入失败审计日志，返回因此触发的锁定时长。
+// 验证码错误同样计入连续失败次数，挑战过期等其他错误只记录日志
+func recordMFAFailure(c echo.Context, loginGuardService *service.LoginGuardService, auditLogService *service.AuditLogService, app *model.Application, user *model.User, loginAction string, verifyErr error) time.Duration {
+	var lockout time.Duration
+	if errors.Is(verifyErr, service.ErrInvalidMFACode) {
+		var err error
+		lockout, err = loginGuardService.RecordFailure(app, user.ID, c.RealIP())
+		if err != nil {
+			service.Log.Errorf("%s: %v", loginAction, err)
+		}
+	}
+
+	content := fmt.Sprintf("两步验证失败 (%s)", loginAction)
+	if lockout > 0 {
+		content = fmt.Sprintf("%s，连续失败次数过多，锁定 %s", content, lockout)
+		service.Log.Warnf("%s: login locked for %s, username=%s, appID=%d, ip=%s", loginAction, lockout, user.Username, app.ID, c.RealIP())
+	}
+
+	auditLogService.Record(&model.AuditLog{
+		AppID:    app.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   "MFA_VERIFY",
+		Resource: "USER",
+		Content:  content,
+		IP:       c.RealIP(),
+		Status:   0,
+		ErrorMsg: verifyErr.Error(),
+	})
+
+	return lockout
+}
+
+// LoginGuardHandler 登录锁定管理处理器
+type LoginGuardHandler struct {
+	LoginGuardService  *service.LoginGuardService
+	UserService        *service.UserService
+	ApplicationService *service.ApplicationService
+}
+
+// NewLoginGuardHandler 创建登录锁定管理处理器实例
+func NewLoginGuardHandler(loginGuardService *service.LoginGuardService, userService *service.UserService, applicationService *service.ApplicationService) *LoginGuardHandler {
+	return &LoginGuardHandler{
+		LoginGuardService:  loginGuardService,
+		UserService:        userService,
+		ApplicationService: applicationService,
+	}
+}
+
+// GetUserLockout 查询用户的连续失败次数与锁定状态
+func (h *LoginGuardHandler) GetUserLockout(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user ID"})
+	}
+
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	user, err := h.UserService.GetUserByID(uint(id), appID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+
+	failure, err := h.LoginGuardService.GetUserFailure(appID, user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get lockout status"})
+	}
+
+	result := map[string]interface{}{
+		"failedCount": 0,
+		"locked":      false,
+	}
+	if failure != nil {
+		result["failedCount"] = failure.FailedCount
+		result["lastFailedAt"] = failure.LastFailedAt
+		if failure.LockedUntil != nil && failure.LockedUntil.After(time.Now()) {
+			result["locked"] = true
+			result["lockedUntil"] = failure.LockedUntil
+		}
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// UnlockUser 解除用户的登录锁定并清零失败次数
+func (h *LoginGuardHandler) UnlockUser(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user ID"})
+	}
+
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	user, err := h.UserService.GetUserByID(uint(id), appID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+
+	if err := h.LoginGuardService.ResetUser(appID, user.ID); err != nil {
+		service.Log.Errorf("UnlockUser: Failed to unlock user: %v, userID=%d", err, user.ID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to unlock user"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "UNLOCK",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    fmt.Sprintf("解除用户登录锁定: %s", user.Username),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User unlocked successfully"})
+}
+
+// UpdateLoginPolicyRequest 更新应用登录防暴力破解策略请求
+type UpdateLoginPolicyRequest struct {
+	LoginMaxFailures    int `json:"loginMaxFailures"`
+	LoginIPMaxFailures  int `json:"loginIpMaxFailures"`
+	LoginLockoutSeconds int `json:"loginLockoutSeconds"`
+}
+
+// UpdateLoginPolicy 更新应用的登录防暴力破解策略（系统级操作，仅系统管理员可调用）
+func (h *LoginGuardHandler) UpdateLoginPolicy(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	var req UpdateLoginPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	app, err := h.ApplicationService.UpdateLoginPolicy(uint(id), req.LoginMaxFailures, req.LoginIPMaxFailures, req.LoginLockoutSeconds)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "UPDATE",
+		Resource:   "APPLICATION",
+		ResourceID: fmt.Sprintf("%d", app.ID),
+		Content: fmt.Sprintf("更新应用登录锁定策略: %s (用户 %d 次, IP %d 次, 锁定 %d 秒)",
+			app.Code, app.LoginMaxFailures, app.LoginIPMaxFailures, app.LoginLockoutSeconds),
+		IP:     c.RealIP(),
+		Status: 1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"app":     app,
+		"message": "Login policy updated successfully",
+	})
+}
@@ -0,0 +1,387 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// mfaIssuer otpauth URI 中展示的签发方名称
+const mfaIssuer = "Authos"
+
+// mfaIssuerName otpauth URI 中展示的签发方（区分不同应用下的同名账号）
+func mfaIssuerName(app *model.Application) string {
+	return fmt.Sprintf("%s (%s)", mfaIssuer, app.Name)
+}
+
+// MFAChallengeRequest 两步登录请求
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code"` // TOTP 验证码或恢复码
+}
+
+// MFACodeRequest 验证码请求
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// loadMFAChallenge 读取挑战令牌及其关联的用户与应用。
+// loginOnly 为 true 时只接受 Login/ProxyLogin/SystemLogin 发起的挑战（OAuth 挑战须通过令牌端点完成）
+func (h *AuthHandler) loadMFAChallenge(mfaToken string, loginOnly bool) (*model.MFAChallenge, *model.User, *model.Application, error) {
+	challenge, err := h.MFAService.GetChallenge(mfaToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if _, ok := loginMessages[challenge.LoginAction]; loginOnly && !ok {
+		return nil, nil, nil, service.ErrInvalidMFAChallenge
+	}
+
+	user, err := h.UserService.GetUserByID(challenge.UserID, challenge.AppID)
+	if err != nil || user.Status == 0 {
+		return nil, nil, nil, service.ErrInvalidMFAChallenge
+	}
+
+	app, err := h.ApplicationService.GetApplicationByID(fmt.Sprintf("%d", challenge.AppID))
+	if err != nil || app.Status == 0 {
+		return nil, nil, nil, service.ErrInvalidMFAChallenge
+	}
+
+	return challenge, user, app, nil
+}
+
+// EnrollMFAChallenge 登录过程中绑定 MFA（应用策略要求但用户尚未绑定时），返回 TOTP 密钥与 otpauth URI。
+// OAuth password 授权返回的挑战令牌同样可用于绑定，随后通过 mfa-otp 授权换取令牌
+func (h *AuthHandler) EnrollMFAChallenge(c echo.Context) error {
+	var req MFAChallengeRequest
+	if err := c.Bind(&req); err != nil || req.MFAToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	_, user, app, err := h.loadMFAChallenge(req.MFAToken, false)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired mfa token"})
+	}
+
+	secret, uri, err := h.MFAService.BeginEnrollment(user, mfaIssuerName(app))
+	if err != nil {
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "MFA is already enabled"})
+		}
+		service.Log.Errorf("EnrollMFAChallenge: Failed to begin enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to begin mfa enrollment"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"secret":     secret,
+		"otpauthUri": uri,
+		"message":    "Scan the otpauth URI and submit a code to complete the login",
+	})
+}
+
+// VerifyMFAChallenge 两步登录第二步：提交挑战令牌与验证码（或恢复码）换取正式令牌。
+// 处于绑定流程中的用户会同时完成绑定，响应中附带一次性展示的恢复码
+func (h *AuthHandler) VerifyMFAChallenge(c echo.Context) error {
+	var req MFAChallengeRequest
+	if err := c.Bind(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	challenge, user, app, err := h.loadMFAChallenge(req.MFAToken, true)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired mfa token"})
+	}
+
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		return rejectLockedLogin(c, lockedFor)
+	}
+
+	recoveryCodes, err := h.MFAService.CompleteChallenge(challenge, user, req.Code)
+	if err != nil {
+		service.Log.Warnf("VerifyMFAChallenge: verification failed: %v, username=%s, appID=%d", err, user.Username, app.ID)
+		if lockedFor := recordMFAFailure(c, h.LoginGuardService, h.AuditLogService, app, user, challenge.LoginAction, err); lockedFor > 0 {
+			return rejectLockedLogin(c, lockedFor)
+		}
+		if errors.Is(err, service.ErrMFANotEnrolled) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "MFA enrollment required, call /api/public/mfa/enroll first"})
+		}
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid mfa code"})
+	}
+
+	var extra map[string]interface{}
+	if recoveryCodes != nil {
+		extra = map[string]interface{}{"recoveryCodes": recoveryCodes}
+	}
+	return h.completeLogin(c, user, app, challenge.LoginAction, extra)
+}
+
+// MFAHandler 两步验证管理处理器（用户自助绑定 / 解绑，管理员重置与策略配置）
+type MFAHandler struct {
+	MFAService         *service.MFAService
+	UserService        *service.UserService
+	ApplicationService *service.ApplicationService
+}
+
+// NewMFAHandler 创建两步验证管理处理器实例
+func NewMFAHandler(mfaService *service.MFAService, userService *service.UserService, applicationService *service.ApplicationService) *MFAHandler {
+	return &MFAHandler{
+		MFAService:         mfaService,
+		UserService:        userService,
+		ApplicationService: applicationService,
+	}
+}
+
+// currentUser 获取当前登录用户（仅用户令牌，不接受 API 密钥）
+func (h *MFAHandler) currentUser(c echo.Context) (*model.User, *model.Application, error) {
+	userID, _ := c.Get("userID").(uint)
+	appID, _ := c.Get("appID").(uint)
+	if userID == 0 || appID == 0 || apiKeyFromContext(c) != nil {
+		return nil, nil, errors.New("user token required")
+	}
+
+	user, err := h.UserService.GetUserByID(userID, appID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	app, err := h.ApplicationService.GetApplicationByID(fmt.Sprintf("%d", appID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, app, nil
+}
+
+// GetMyMFA 查询当前用户的 MFA 状态
+func (h *MFAHandler) GetMyMFA(c echo.Context) error {
+	user, app, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+
+	remaining, err := h.MFAService.CountRecoveryCodes(user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to count recovery codes"})
+	}
+
+	required, _ := h.MFAService.IsRequired(user, app)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"mfaEnabled":             user.MFAEnabled,
+		"mfaRequired":            required,
+		"mfaPolicy":              app.MFAPolicy,
+		"recoveryCodesRemaining": remaining,
+	})
+}
+
+// EnrollMyMFA 开始绑定 MFA，返回 TOTP 密钥与 otpauth URI
+func (h *MFAHandler) EnrollMyMFA(c echo.Context) error {
+	user, app, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+
+	secret, uri, err := h.MFAService.BeginEnrollment(user, mfaIssuerName(app))
+	if err != nil {
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "MFA is already enabled"})
+		}
+		service.Log.Errorf("EnrollMyMFA: Failed to begin enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to begin mfa enrollment"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"secret":     secret,
+		"otpauthUri": uri,
+	})
+}
+
+// ConfirmMyMFA 提交首个验证码确认绑定，返回恢复码（只展示一次）
+func (h *MFAHandler) ConfirmMyMFA(c echo.Context) error {
+	user, app, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+
+	var req MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	recoveryCodes, err := h.MFAService.ConfirmEnrollment(user, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrMFAAlreadyEnabled):
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "MFA is already enabled"})
+		case errors.Is(err, service.ErrMFANotEnrolled):
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "Call /api/v1/me/mfa/enroll first"})
+		case errors.Is(err, service.ErrInvalidMFACode):
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid mfa code"})
+		}
+		service.Log.Errorf("ConfirmMyMFA: Failed to confirm enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to enable mfa"})
+	}
+
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      app.ID,
+		UserID:     user.ID,
+		Username:   user.Username,
+		Action:     "MFA_ENABLE",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    "启用两步验证",
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"recoveryCodes": recoveryCodes,
+		"message":       "MFA enabled successfully",
+	})
+}
+
+// DisableMyMFA 关闭 MFA（需提交当前验证码或恢复码）
+func (h *MFAHandler) DisableMyMFA(c echo.Context) error {
+	user, app, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+
+	var req MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.MFAService.Verify(user, req.Code); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid mfa code"})
+	}
+
+	if err := h.MFAService.Disable(user.ID); err != nil {
+		service.Log.Errorf("DisableMyMFA: Failed to disable mfa: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to disable mfa"})
+	}
+
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      app.ID,
+		UserID:     user.ID,
+		Username:   user.Username,
+		Action:     "MFA_DISABLE",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    "关闭两步验证",
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "MFA disabled successfully"})
+}
+
+// RegenerateMyRecoveryCodes 重新生成恢复码（需提交当前验证码或恢复码）
+func (h *MFAHandler) RegenerateMyRecoveryCodes(c echo.Context) error {
+	user, _, err := h.currentUser(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "User not found"})
+	}
+
+	var req MFACodeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.MFAService.Verify(user, req.Code); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid mfa code"})
+	}
+
+	recoveryCodes, err := h.MFAService.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		service.Log.Errorf("RegenerateMyRecoveryCodes: Failed to regenerate recovery codes: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to regenerate recovery codes"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// ResetUserMFA 管理员重置用户的 MFA（用户丢失设备且恢复码用尽时）
+func (h *MFAHandler) ResetUserMFA(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user ID"})
+	}
+
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	user, err := h.UserService.GetUserByID(uint(id), appID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+
+	if err := h.MFAService.Disable(user.ID); err != nil {
+		service.Log.Errorf("ResetUserMFA: Failed to reset mfa: %v, userID=%d", err, user.ID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to reset mfa"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "MFA_RESET",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    fmt.Sprintf("重置用户两步验证: %s", user.Username),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User MFA reset successfully"})
+}
+
+// UpdateMFAPolicyRequest 更新应用 MFA 策略请求
+type UpdateMFAPolicyRequest struct {
+	MFAPolicy string `json:"mfaPolicy"`
+}
+
+// UpdateMFAPolicy 更新应用的两步验证策略（系统级操作，仅系统管理员可调用）
+func (h *MFAHandler) UpdateMFAPolicy(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid application ID"})
+	}
+
+	var req UpdateMFAPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	app, err := h.ApplicationService.UpdateMFAPolicy(uint(id), req.MFAPolicy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "UPDATE",
+		Resource:   "APPLICATION",
+		ResourceID: fmt.Sprintf("%d", app.ID),
+		Content:    fmt.Sprintf("更新应用两步验证策略: %s -> %s", app.Code, app.MFAPolicy),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"app":     app,
+		"message": "MFA policy updated successfully",
+	})
+}
@@ -0,0 +1,956 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// OAuth 2.0 授权类型 (RFC 6749)
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypePassword          = "password"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeMFAOTP            = "urn:authos:params:oauth:grant-type:mfa-otp" // 密码模式触发两步验证后，凭 mfa_token + otp 换取令牌
+)
+
+// OAuth 2.0 错误码 (RFC 6749 5.2)
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrServerError             = "server_error"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrMFARequired             = "mfa_required"
+)
+
+// OAuth 流程中发起两步验证挑战的登录方式
+const (
+	oauthMFAActionToken     = "OAUTH_TOKEN"
+	oauthMFAActionAuthorize = "OAUTH_AUTHORIZE"
+)
+
+// ScopeOpenID OIDC 授权范围，包含时令牌响应中会附带 id_token
+const ScopeOpenID = "openid"
+
+// oauthCSRFCookie 托管登录页的 CSRF Cookie 名称
+const oauthCSRFCookie = "authos_oauth_csrf"
+
+//go:embed templates/authorize.html
+var authorizeTemplateContent string
+
+// authorizeTemplate 托管登录页模板
+var authorizeTemplate = template.Must(template.New("authorize").Parse(authorizeTemplateContent))
+
+// OAuthHandler OAuth 2.0 处理器
+// client_id 对应应用代码 (appCode)，client_secret 对应应用密钥 (appSecret)
+type OAuthHandler struct {
+	UserService              *service.UserService
+	ApplicationService       *service.ApplicationService
+	AuditLogService          *service.AuditLogService
+	RefreshTokenService      *service.RefreshTokenService
+	AuthorizationCodeService *service.AuthorizationCodeService
+	TokenRevocationService   *service.TokenRevocationService
+	MFAService               *service.MFAService
+	LoginGuardService        *service.LoginGuardService
+	CasbinService            *service.CasbinService
+	JWTConfig                *service.JWTConfig
+}
+
+// NewOAuthHandler 创建 OAuth 2.0 处理器实例
+func NewOAuthHandler(userService *service.UserService, applicationService *service.ApplicationService, auditLogService *service.AuditLogService, refreshTokenService *service.RefreshTokenService, authorizationCodeService *service.AuthorizationCodeService, tokenRevocationService *service.TokenRevocationService, mfaService *service.MFAService, loginGuardService *service.LoginGuardService, casbinService *service.CasbinService, jwtConfig *service.JWTConfig) *OAuthHandler {
+	return &OAuthHandler{
+		UserService:              userService,
+		ApplicationService:       applicationService,
+		AuditLogService:          auditLogService,
+		RefreshTokenService:      refreshTokenService,
+		AuthorizationCodeService: authorizationCodeService,
+		TokenRevocationService:   tokenRevocationService,
+		MFAService:               mfaService,
+		LoginGuardService:        loginGuardService,
+		CasbinService:            casbinService,
+		JWTConfig:                jwtConfig,
+	}
+}
+
+// oauthError 返回 RFC 6749 格式的错误响应
+func oauthError(c echo.Context, status int, code, description string) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+	if status == http.StatusUnauthorized {
+		c.Response().Header().Set("WWW-Authenticate", `Basic realm="authos"`)
+	}
+	return c.JSON(status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// oauthTokenResponse 返回 RFC 6749 格式的令牌响应
+func oauthTokenResponse(c echo.Context, body map[string]interface{}) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+	return c.JSON(http.StatusOK, body)
+}
+
+// authenticateClient 校验客户端凭证，支持 HTTP Basic (client_secret_basic) 与表单参数 (client_secret_post)。
+// allowPublic 为 true 时允许只提交 client_id 的公共客户端（如浏览器 SPA），此时由 PKCE 保证授权码安全
+func (h *OAuthHandler) authenticateClient(c echo.Context, allowPublic bool) (*model.Application, error) {
+	clientID, clientSecret, ok := c.Request().BasicAuth()
+	if ok {
+		// RFC 6749 2.3.1: Basic 认证中的凭证需先做 form-urlencoded 解码
+		if decoded, err := url.QueryUnescape(clientID); err == nil {
+			clientID = decoded
+		}
+		if decoded, err := url.QueryUnescape(clientSecret); err == nil {
+			clientSecret = decoded
+		}
+	} else {
+		clientID = c.FormValue("client_id")
+		clientSecret = c.FormValue("client_secret")
+	}
+
+	if clientID == "" || (clientSecret == "" && !allowPublic) {
+		return nil, errors.New("client authentication required")
+	}
+
+	app, err := h.ApplicationService.GetApplicationByCode(clientID)
+	if err != nil || (clientSecret != "" && !h.ApplicationService.VerifySecret(app, clientSecret, c.RealIP())) {
+		return nil, errors.New("invalid client credentials")
+	}
+
+	if app.Status == 0 {
+		return nil, errors.New("client is disabled")
+	}
+
+	return app, nil
+}
+
+// Token OAuth 2.0 令牌端点 (/oauth/token)
+// 支持 client_credentials（替代 app-login）、password（替代 proxy-login）、authorization_code 与 refresh_token
+func (h *OAuthHandler) Token(c echo.Context) error {
+	grantType := c.FormValue("grant_type")
+	if grantType == "" {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidRequest, "grant_type is required")
+	}
+
+	app, err := h.authenticateClient(c, grantType == GrantTypeAuthorizationCode)
+	if err != nil {
+		service.Log.Warnf("OAuthToken: client authentication failed: %v", err)
+		return oauthError(c, http.StatusUnauthorized, OAuthErrInvalidClient, err.Error())
+	}
+
+	switch grantType {
+	case GrantTypeClientCredentials:
+		return h.clientCredentialsGrant(c, app)
+	case GrantTypePassword:
+		return h.passwordGrant(c, app)
+	case GrantTypeAuthorizationCode:
+		return h.authorizationCodeGrant(c, app)
+	case GrantTypeMFAOTP:
+		return h.mfaOTPGrant(c, app)
+	case GrantTypeRefreshToken:
+		return h.refreshTokenGrant(c, app)
+	default:
+		return oauthError(c, http.StatusBadRequest, OAuthErrUnsupportedGrantType, fmt.Sprintf("grant_type %s is not supported", grantType))
+	}
+}
+
+// clientCredentialsGrant 客户端凭证模式：签发应用令牌
+func (h *OAuthHandler) clientCredentialsGrant(c echo.Context, app *model.Application) error {
+	token, err := h.JWTConfig.GenerateAppToken(app.ID, app.UUID, app.Code)
+	if err != nil {
+		service.Log.Errorf("OAuthToken: Failed to generate app token: %v", err)
+		return oauthError(c, http.StatusInternalServerError, OAuthErrServerError, "failed to generate token")
+	}
+
+	h.AuditLogService.Record(&model.AuditLog{
+		AppID:    app.ID,
+		Action:   "OAUTH_TOKEN",
+		Resource: "APPLICATION",
+		Content:  "grant_type=client_credentials",
+		IP:       c.RealIP(),
+		Status:   1,
+	})
+
+	return oauthTokenResponse(c, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(h.JWTConfig.ExpireTime.Seconds()),
+	})
+}
+
+// oauthLockedError 账号或 IP 处于锁定期时返回 invalid_grant，并通过 Retry-After 告知剩余秒数
+func oauthLockedError(c echo.Context, lockedFor time.Duration) error {
+	seconds := retryAfterSeconds(lockedFor)
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, fmt.Sprintf("too many failed login attempts, retry after %d seconds", seconds))
+}
+
+// passwordGrant 资源所有者密码模式：在应用隔离域内校验用户名密码并签发用户令牌
+func (h *OAuthHandler) passwordGrant(c echo.Context, app *model.Application) error {
+	username := c.FormValue("username")
+	password := c.FormValue("password")
+	if username == "" || password == "" {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidRequest, "username and password are required")
+	}
+
+	user, err := h.UserService.GetUserByUsername(username, app.ID)
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		service.Log.Warnf("OAuthToken: login locked, username=%s, appID=%d", username, app.ID)
+		return oauthLockedError(c, lockedFor)
+	}
+
+	if err != nil {
+		service.Log.Errorf("OAuthToken: Invalid username or password, username=%s, appID=%d", username, app.ID)
+		recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, nil, username, oauthMFAActionToken, "user not found")
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "invalid username or password")
+	}
+
+	if user.Status == 0 {
+		service.Log.Warnf("OAuthToken: User is disabled, username=%s", username)
+		recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, username, oauthMFAActionToken, "user disabled")
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "user is disabled")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		service.Log.Errorf("OAuthToken: password mismatch, username=%s", username)
+		if lockout := recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, username, oauthMFAActionToken, "password mismatch"); lockout > 0 {
+			return oauthLockedError(c, lockout)
+		}
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "invalid username or password")
+	}
+
+	if service.PasswordExpired(app, user) {
+		service.Log.Warnf("OAuthToken: password expired, username=%s", username)
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "password expired, change it via /api/public/change-password")
+	}
+
+	// 两步验证：返回 mfa_required 与挑战令牌，客户端随后使用 mfa-otp 授权类型换取令牌
+	if required, enrollmentRequired := h.MFAService.IsRequired(user, app); required {
+		mfaToken, err := h.MFAService.CreateChallenge(user.ID, app.ID, oauthMFAActionToken)
+		if err != nil {
+			service.Log.Errorf("OAuthToken: Failed to create mfa challenge: %v", err)
+			return oauthError(c, http.StatusInternalServerError, OAuthErrServerError, "failed to create mfa challenge")
+		}
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error":                   OAuthErrMFARequired,
+			"error_description":       "multi-factor authentication required",
+			"mfa_token":               mfaToken,
+			"mfa_enrollment_required": enrollmentRequired,
+		})
+	}
+
+	return h.issueUserTokens(c, app, user, c.FormValue("scope"), GrantTypePassword, "", time.Now())
+}
+
+// mfaOTPGrant 两步验证授权：校验 mfa_token 与 otp（TOTP 验证码或恢复码）后签发用户令牌
+func (h *OAuthHandler) mfaOTPGrant(c echo.Context, app *model.Application) error {
+	mfaToken := c.FormValue("mfa_token")
+	otp := c.FormValue("otp")
+	if mfaToken == "" || otp == "" {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidRequest, "mfa_token and otp are required")
+	}
+
+	challenge, err := h.MFAService.GetChallenge(mfaToken)
+	if err != nil || challenge.AppID != app.ID || challenge.LoginAction != oauthMFAActionToken {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "invalid or expired mfa_token")
+	}
+
+	user, err := h.UserService.GetUserByID(challenge.UserID, app.ID)
+	if err != nil || user.Status == 0 {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "user is disabled")
+	}
+
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		return oauthLockedError(c, lockedFor)
+	}
+
+	if _, err := h.MFAService.CompleteChallenge(challenge, user, otp); err != nil {
+		service.Log.Warnf("OAuthToken: mfa verification failed: %v, username=%s", err, user.Username)
+		if lockedFor := recordMFAFailure(c, h.LoginGuardService, h.AuditLogService, app, user, oauthMFAActionToken, err); lockedFor > 0 {
+			return oauthLockedError(c, lockedFor)
+		}
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "invalid otp")
+	}
+
+	return h.issueUserTokens(c, app, user, c.FormValue("scope"), GrantTypeMFAOTP, "", time.Now())
+}
+
+// issueUserTokens 签发用户访问令牌、刷新令牌，scope 包含 openid 时附带 id_token（nonce 与 authTime 写入 id_token）
+func (h *OAuthHandler) issueUserTokens(c echo.Context, app *model.Application, user *model.User, scope, grantType, nonce string, authTime time.Time) error {
+	token, err := h.JWTConfig.GenerateToken(user.ID, user.Username, app.ID, app.UUID)
+	if err != nil {
+		service.Log.Errorf("OAuthToken: Failed to generate token: %v", err)
+		return oauthError(c, http.StatusInternalServerError, OAuthErrServerError, "failed to generate token")
+	}
+
+	refreshToken, err := h.RefreshTokenService.IssueRefreshToken(user.ID, app.ID, c.RealIP())
+	if err != nil {
+		service.Log.Errorf("OAuthToken: Failed to issue refresh token: %v", err)
+		return oauthError(c, http.StatusInternalServerError, OAuthErrServerError, "failed to generate token")
+	}
+
+	body := map[string]interface{}{
+		"access_token":  token,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.JWTConfig.ExpireTime.Seconds()),
+		"refresh_token": refreshToken,
+	}
+	if scope != "" {
+		body["scope"] = scope
+	}
+
+	if service.HasScope(scope, ScopeOpenID) {
+		roles, err := h.CasbinService.EffectiveRoleNames(user.ID)
+		if err != nil {
+			service.Log.Errorf("OAuthToken: Failed to load user roles: %v", err)
+			return oauthError(c, http.StatusInternalServerError, OAuthErrServerError, "failed to generate token")
+		}
+		idToken, err := h.JWTConfig.GenerateIDToken(h.issuer(c), app.Code, user.ID, user.Username, roles, nonce, authTime)
+		if err != nil {
+			service.Log.Errorf("OAuthToken: Failed to generate id token: %v", err)
+			return oauthError(c, http.StatusInternalServerError, OAuthErrServerError, "failed to generate token")
+		}
+		body["id_token"] = idToken
+	}
+
+	h.AuditLogService.Record(&model.AuditLog{
+		AppID:    app.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   "OAUTH_TOKEN",
+		Resource: "USER",
+		Content:  "grant_type=" + grantType,
+		IP:       c.RealIP(),
+		Status:   1,
+	})
+
+	return oauthTokenResponse(c, body)
+}
+
+// refreshTokenGrant 刷新令牌模式：轮换刷新令牌并签发新的用户令牌
+func (h *OAuthHandler) refreshTokenGrant(c echo.Context, app *model.Application) error {
+	raw := c.FormValue("refresh_token")
+	if raw == "" {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidRequest, "refresh_token is required")
+	}
+
+	current, refreshToken, err := h.RefreshTokenService.RotateRefreshToken(raw, app.ID, c.RealIP())
+	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			service.Log.Warnf("OAuthToken: refresh token reuse detected, family revoked, userID=%d, appID=%d", current.UserID, app.ID)
+			h.AuditLogService.Record(&model.AuditLog{
+				AppID:    app.ID,
+				UserID:   current.UserID,
+				Action:   "REFRESH_TOKEN_REUSE",
+				Resource: "USER",
+				Content:  fmt.Sprintf("刷新令牌被重复使用，已吊销令牌家族: %s", current.FamilyID),
+				IP:       c.RealIP(),
+				Status:   0,
+				ErrorMsg: err.Error(),
+			})
+		}
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "invalid or expired refresh token")
+	}
+
+	user, err := h.UserService.GetUserByID(current.UserID, app.ID)
+	if err != nil || user.Status == 0 {
+		_ = h.RefreshTokenService.RevokeFamily(current.FamilyID)
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "user is disabled")
+	}
+
+	token, err := h.JWTConfig.GenerateToken(user.ID, user.Username, app.ID, app.UUID)
+	if err != nil {
+		service.Log.Errorf("OAuthToken: Failed to generate token: %v", err)
+		return oauthError(c, http.StatusInternalServerError, OAuthErrServerError, "failed to generate token")
+	}
+
+	h.AuditLogService.Record(&model.AuditLog{
+		AppID:    app.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   "REFRESH_TOKEN",
+		Resource: "USER",
+		Content:  "grant_type=refresh_token",
+		IP:       c.RealIP(),
+		Status:   1,
+	})
+
+	return oauthTokenResponse(c, map[string]interface{}{
+		"access_token":  token,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.JWTConfig.ExpireTime.Seconds()),
+		"refresh_token": refreshToken,
+	})
+}
+
+// authorizationCodeGrant 授权码模式：校验 PKCE 后兑换授权码并签发用户令牌
+func (h *OAuthHandler) authorizationCodeGrant(c echo.Context, app *model.Application) error {
+	code := c.FormValue("code")
+	redirectURI := c.FormValue("redirect_uri")
+	codeVerifier := c.FormValue("code_verifier")
+	if code == "" || redirectURI == "" || codeVerifier == "" {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidRequest, "code, redirect_uri and code_verifier are required")
+	}
+
+	authCode, err := h.AuthorizationCodeService.ExchangeCode(code, app.ID, redirectURI, codeVerifier)
+	if err != nil {
+		service.Log.Warnf("OAuthToken: authorization code exchange failed: %v, appID=%d", err, app.ID)
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, err.Error())
+	}
+
+	user, err := h.UserService.GetUserByID(authCode.UserID, app.ID)
+	if err != nil || user.Status == 0 {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidGrant, "user is disabled")
+	}
+
+	// auth_time 为用户在托管登录页完成认证（签发授权码）的时间
+	return h.issueUserTokens(c, app, user, authCode.Scope, GrantTypeAuthorizationCode, authCode.Nonce, authCode.CreatedAt)
+}
+
+// authorizeRequest 授权请求参数 (RFC 6749 4.1.1 / RFC 7636 4.3)
+type authorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// authorizePage 托管登录页渲染数据
+type authorizePage struct {
+	authorizeRequest
+	AppName   string
+	CSRFToken string
+	Username  string
+	Error     string
+	Fatal     bool // 为 true 时只展示错误，不展示登录表单
+
+	// 两步验证步骤
+	MFAToken      string   // 挑战令牌，不为空时展示验证码表单
+	MFASecret     string   // 需要绑定时展示的 TOTP 密钥
+	MFAURI        string   // 需要绑定时展示的 otpauth URI
+	RecoveryCodes []string // 绑定完成后一次性展示的恢复码
+	ContinueURL   string   // 展示恢复码后继续回调的地址
+}
+
+// parseAuthorizeRequest 读取授权请求参数（GET 取查询参数，POST 取表单参数）
+func parseAuthorizeRequest(c echo.Context) authorizeRequest {
+	return authorizeRequest{
+		ResponseType:        c.FormValue("response_type"),
+		ClientID:            c.FormValue("client_id"),
+		RedirectURI:         c.FormValue("redirect_uri"),
+		Scope:               c.FormValue("scope"),
+		State:               c.FormValue("state"),
+		Nonce:               c.FormValue("nonce"),
+		CodeChallenge:       c.FormValue("code_challenge"),
+		CodeChallengeMethod: c.FormValue("code_challenge_method"),
+	}
+}
+
+// renderAuthorizePage 渲染托管登录页
+func renderAuthorizePage(c echo.Context, status int, page *authorizePage) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("X-Frame-Options", "DENY")
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(status)
+	return authorizeTemplate.Execute(c.Response(), page)
+}
+
+// renderLockedAuthorizePage 账号或 IP 处于锁定期时展示登录页并提示剩余时间
+func renderLockedAuthorizePage(c echo.Context, page *authorizePage, lockedFor time.Duration) error {
+	seconds := retryAfterSeconds(lockedFor)
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	page.MFAToken, page.MFASecret, page.MFAURI = "", "", ""
+	page.Error = fmt.Sprintf("登录失败次数过多，请 %d 分钟后再试", (seconds+59)/60)
+	return renderAuthorizePage(c, http.StatusTooManyRequests, page)
+}
+
+// buildRedirectURL 在回调地址上追加查询参数
+func buildRedirectURL(redirectURI string, params map[string]string) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// redirectWithParams 在回调地址上追加查询参数并重定向
+func redirectWithParams(c echo.Context, redirectURI string, params map[string]string) error {
+	target, err := buildRedirectURL(redirectURI, params)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid redirect_uri"})
+	}
+	return c.Redirect(http.StatusFound, target)
+}
+
+// validateAuthorizeRequest 校验授权请求。
+// client_id 或 redirect_uri 无效时不能重定向（防止开放重定向），直接展示错误页；
+// 其余错误按 RFC 6749 4.1.2.1 重定向回客户端
+func (h *OAuthHandler) validateAuthorizeRequest(c echo.Context, req authorizeRequest) (*model.Application, error) {
+	app, err := h.ApplicationService.GetApplicationByCode(req.ClientID)
+	if err != nil || app.Status == 0 {
+		return nil, renderAuthorizePage(c, http.StatusBadRequest, &authorizePage{Error: "无效的应用 (client_id)", Fatal: true})
+	}
+
+	if req.RedirectURI == "" || !service.IsRedirectURIRegistered(app, req.RedirectURI) {
+		return nil, renderAuthorizePage(c, http.StatusBadRequest, &authorizePage{AppName: app.Name, Error: "回调地址 (redirect_uri) 未登记", Fatal: true})
+	}
+
+	if req.ResponseType != "code" {
+		return nil, redirectWithParams(c, req.RedirectURI, map[string]string{
+			"error":             OAuthErrUnsupportedResponseType,
+			"error_description": "only response_type=code is supported",
+			"state":             req.State,
+		})
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != model.CodeChallengeMethodS256 {
+		return nil, redirectWithParams(c, req.RedirectURI, map[string]string{
+			"error":             OAuthErrInvalidRequest,
+			"error_description": "code_challenge with code_challenge_method=S256 is required",
+			"state":             req.State,
+		})
+	}
+
+	return app, nil
+}
+
+// generateRandomToken 生成随机令牌（base64url 编码）
+func generateRandomToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// setCSRFCookie 为托管登录页生成 CSRF 令牌并写入 Cookie
+func setCSRFCookie(c echo.Context) (string, error) {
+	token, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     oauthCSRFCookie,
+		Value:    token,
+		Path:     "/oauth",
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// Authorize 授权端点 GET /oauth/authorize：展示托管登录页
+func (h *OAuthHandler) Authorize(c echo.Context) error {
+	req := parseAuthorizeRequest(c)
+	app, err := h.validateAuthorizeRequest(c, req)
+	if app == nil {
+		return err
+	}
+
+	csrfToken, err := setCSRFCookie(c)
+	if err != nil {
+		service.Log.Errorf("OAuthAuthorize: Failed to generate csrf token: %v", err)
+		return renderAuthorizePage(c, http.StatusInternalServerError, &authorizePage{AppName: app.Name, Error: "服务器内部错误", Fatal: true})
+	}
+
+	return renderAuthorizePage(c, http.StatusOK, &authorizePage{
+		authorizeRequest: req,
+		AppName:          app.Name,
+		CSRFToken:        csrfToken,
+	})
+}
+
+// AuthorizeSubmit 授权端点 POST /oauth/authorize：校验用户名密码，签发授权码并重定向回客户端
+func (h *OAuthHandler) AuthorizeSubmit(c echo.Context) error {
+	req := parseAuthorizeRequest(c)
+	app, err := h.validateAuthorizeRequest(c, req)
+	if app == nil {
+		return err
+	}
+
+	username := strings.TrimSpace(c.FormValue("username"))
+	password := c.FormValue("password")
+	csrfToken := c.FormValue("csrf_token")
+	page := &authorizePage{
+		authorizeRequest: req,
+		AppName:          app.Name,
+		CSRFToken:        csrfToken,
+		Username:         username,
+	}
+
+	cookie, err := c.Cookie(oauthCSRFCookie)
+	if err != nil || csrfToken == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(csrfToken)) != 1 {
+		service.Log.Warnf("OAuthAuthorize: csrf token mismatch, appID=%d", app.ID)
+		page.CSRFToken, _ = setCSRFCookie(c)
+		page.Error = "页面已过期，请重新登录"
+		return renderAuthorizePage(c, http.StatusBadRequest, page)
+	}
+
+	// 两步验证步骤
+	if mfaToken := c.FormValue("mfa_token"); mfaToken != "" {
+		return h.authorizeMFA(c, app, req, page, mfaToken)
+	}
+
+	user, err := h.UserService.GetUserByUsername(username, app.ID)
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		service.Log.Warnf("OAuthAuthorize: login locked, username=%s, appID=%d", username, app.ID)
+		return renderLockedAuthorizePage(c, page, lockedFor)
+	}
+
+	if err != nil || user.Status == 0 || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		service.Log.Errorf("OAuthAuthorize: Invalid username or password, username=%s, appID=%d", username, app.ID)
+		if err != nil {
+			user = nil
+		}
+		if lockout := recordLoginFailure(c, h.LoginGuardService, h.AuditLogService, app, user, username, oauthMFAActionAuthorize, "invalid username or password"); lockout > 0 {
+			return renderLockedAuthorizePage(c, page, lockout)
+		}
+		page.Error = "用户名或密码错误"
+		return renderAuthorizePage(c, http.StatusUnauthorized, page)
+	}
+
+	if service.PasswordExpired(app, user) {
+		service.Log.Warnf("OAuthAuthorize: password expired, username=%s", username)
+		page.Error = "密码已过期，请修改密码后再登录"
+		return renderAuthorizePage(c, http.StatusForbidden, page)
+	}
+
+	if required, enrollmentRequired := h.MFAService.IsRequired(user, app); required {
+		mfaToken, err := h.MFAService.CreateChallenge(user.ID, app.ID, oauthMFAActionAuthorize)
+		if err != nil {
+			service.Log.Errorf("OAuthAuthorize: Failed to create mfa challenge: %v", err)
+			page.Error = "服务器内部错误"
+			return renderAuthorizePage(c, http.StatusInternalServerError, page)
+		}
+		page.MFAToken = mfaToken
+		if enrollmentRequired {
+			page.MFASecret, page.MFAURI, err = h.MFAService.BeginEnrollment(user, mfaIssuerName(app))
+			if err != nil {
+				service.Log.Errorf("OAuthAuthorize: Failed to begin mfa enrollment: %v", err)
+				page.Error = "服务器内部错误"
+				return renderAuthorizePage(c, http.StatusInternalServerError, page)
+			}
+		}
+		return renderAuthorizePage(c, http.StatusOK, page)
+	}
+
+	return h.issueAuthorizationCode(c, app, user, req, nil)
+}
+
+// authorizeMFA 托管登录页的两步验证步骤：校验验证码后签发授权码
+func (h *OAuthHandler) authorizeMFA(c echo.Context, app *model.Application, req authorizeRequest, page *authorizePage, mfaToken string) error {
+	challenge, err := h.MFAService.GetChallenge(mfaToken)
+	if err != nil || challenge.AppID != app.ID || challenge.LoginAction != oauthMFAActionAuthorize {
+		page.Error = "验证已过期，请重新登录"
+		return renderAuthorizePage(c, http.StatusUnauthorized, page)
+	}
+
+	user, err := h.UserService.GetUserByID(challenge.UserID, app.ID)
+	if err != nil || user.Status == 0 {
+		page.Error = "用户名或密码错误"
+		return renderAuthorizePage(c, http.StatusUnauthorized, page)
+	}
+
+	if lockedFor := loginLockedFor(c, h.LoginGuardService, app, user); lockedFor > 0 {
+		return renderLockedAuthorizePage(c, page, lockedFor)
+	}
+
+	recoveryCodes, err := h.MFAService.CompleteChallenge(challenge, user, strings.TrimSpace(c.FormValue("mfa_code")))
+	if err != nil {
+		service.Log.Warnf("OAuthAuthorize: mfa verification failed: %v, username=%s", err, user.Username)
+		if lockedFor := recordMFAFailure(c, h.LoginGuardService, h.AuditLogService, app, user, oauthMFAActionAuthorize, err); lockedFor > 0 {
+			return renderLockedAuthorizePage(c, page, lockedFor)
+		}
+		if errors.Is(err, service.ErrInvalidMFAChallenge) {
+			page.Error = "验证已过期，请重新登录"
+			return renderAuthorizePage(c, http.StatusUnauthorized, page)
+		}
+		page.MFAToken = mfaToken
+		if !user.MFAEnabled {
+			if secret, err := h.MFAService.TOTPSecret(user); err == nil {
+				page.MFASecret = secret
+				page.MFAURI = service.TOTPURI(mfaIssuerName(app), user.Username, secret)
+			}
+		}
+		page.Error = "验证码错误"
+		return renderAuthorizePage(c, http.StatusUnauthorized, page)
+	}
+
+	return h.issueAuthorizationCode(c, app, user, req, recoveryCodes)
+}
+
+// issueAuthorizationCode 签发授权码并重定向回客户端；刚完成 MFA 绑定时先展示恢复码
+func (h *OAuthHandler) issueAuthorizationCode(c echo.Context, app *model.Application, user *model.User, req authorizeRequest, recoveryCodes []string) error {
+	code, err := h.AuthorizationCodeService.CreateCode(app.ID, user.ID, req.RedirectURI, req.Scope, req.Nonce, req.CodeChallenge, req.CodeChallengeMethod)
+	if err != nil {
+		service.Log.Errorf("OAuthAuthorize: Failed to create authorization code: %v", err)
+		return redirectWithParams(c, req.RedirectURI, map[string]string{
+			"error":             OAuthErrServerError,
+			"error_description": "failed to create authorization code",
+			"state":             req.State,
+		})
+	}
+
+	h.AuditLogService.Record(&model.AuditLog{
+		AppID:    app.ID,
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   "OAUTH_AUTHORIZE",
+		Resource: "USER",
+		Content:  fmt.Sprintf("托管登录页授权, redirect_uri=%s", req.RedirectURI),
+		IP:       c.RealIP(),
+		Status:   1,
+	})
+
+	params := map[string]string{
+		"code":  code,
+		"state": req.State,
+	}
+	if len(recoveryCodes) == 0 {
+		return redirectWithParams(c, req.RedirectURI, params)
+	}
+
+	continueURL, err := buildRedirectURL(req.RedirectURI, params)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid redirect_uri"})
+	}
+	return renderAuthorizePage(c, http.StatusOK, &authorizePage{
+		AppName:       app.Name,
+		RecoveryCodes: recoveryCodes,
+		ContinueURL:   continueURL,
+	})
+}
+
+// issuer 返回 OIDC 签发者地址，未配置时按请求的协议与主机推导
+func (h *OAuthHandler) issuer(c echo.Context) string {
+	if h.JWTConfig.Issuer != "" {
+		return strings.TrimRight(h.JWTConfig.Issuer, "/")
+	}
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+// OpenIDConfiguration OIDC 发现文档 (/.well-known/openid-configuration)
+func (h *OAuthHandler) OpenIDConfiguration(c echo.Context) error {
+	issuer := h.issuer(c)
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"introspection_endpoint":                issuer + "/oauth/introspect",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials, GrantTypePassword},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{h.JWTConfig.SigningAlgorithm()},
+		"scopes_supported":                      []string{ScopeOpenID, "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{model.CodeChallengeMethodS256},
+		"claims_supported":                      []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "roles"},
+	})
+}
+
+// userInfoError 返回 RFC 6750 格式的 Bearer 令牌错误
+func userInfoError(c echo.Context, description string) error {
+	c.Response().Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description="%s"`, description))
+	return c.JSON(http.StatusUnauthorized, map[string]string{
+		"error":             "invalid_token",
+		"error_description": description,
+	})
+}
+
+// UserInfo OIDC 用户信息端点 (/userinfo)，使用 Authorization: Bearer <access_token>
+func (h *OAuthHandler) UserInfo(c echo.Context) error {
+	authHeader := c.Request().Header.Get("Authorization")
+	if len(authHeader) < 7 || !strings.EqualFold(authHeader[:7], "Bearer ") {
+		return userInfoError(c, "bearer token is required")
+	}
+
+	claims, err := h.JWTConfig.ParseToken(strings.TrimSpace(authHeader[7:]))
+	if err != nil || claims.Type != "user" {
+		return userInfoError(c, "invalid access token")
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := h.TokenRevocationService.IsTokenRevoked(claims.ID, claims.UserID, claims.AppID, issuedAt)
+	if err != nil {
+		service.Log.Errorf("UserInfo: Failed to check token revocation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": OAuthErrServerError})
+	}
+	if revoked {
+		return userInfoError(c, "token has been revoked")
+	}
+
+	user, err := h.UserService.GetUserByID(claims.UserID, claims.AppID)
+	if err != nil || user.Status == 0 {
+		return userInfoError(c, "user not found or disabled")
+	}
+
+	roles, err := h.CasbinService.EffectiveRoleNames(user.ID)
+	if err != nil {
+		service.Log.Errorf("UserInfo: Failed to load user roles: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": OAuthErrServerError})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"sub":                fmt.Sprintf("%d", user.ID),
+		"preferred_username": user.Username,
+		"roles":              roles,
+	})
+}
+
+// Introspect 令牌内省端点 (RFC 7662)，需以 appCode + appSecret 认证。
+// 只有属于调用方应用的令牌才可能返回 active=true；令牌被吊销、用户或应用被禁用时返回 active=false
+func (h *OAuthHandler) Introspect(c echo.Context) error {
+	app, err := h.authenticateClient(c, false)
+	if err != nil {
+		service.Log.Warnf("Introspect: client authentication failed: %v", err)
+		return oauthError(c, http.StatusUnauthorized, OAuthErrInvalidClient, err.Error())
+	}
+
+	token := c.FormValue("token")
+	if token == "" {
+		return oauthError(c, http.StatusBadRequest, OAuthErrInvalidRequest, "token is required")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	// token_type_hint 仅作为查找顺序的提示 (RFC 7662 2.1)
+	lookups := []func(string, *model.Application) (map[string]interface{}, bool){h.introspectAccessToken, h.introspectRefreshToken}
+	if c.FormValue("token_type_hint") == GrantTypeRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		if body, ok := lookup(token, app); ok {
+			return c.JSON(http.StatusOK, body)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"active": false})
+}
+
+// introspectAccessToken 内省访问令牌（用户令牌或应用令牌）
+func (h *OAuthHandler) introspectAccessToken(token string, app *model.Application) (map[string]interface{}, bool) {
+	claims, err := h.JWTConfig.ParseToken(token)
+	if err != nil || claims.AppID != app.ID {
+		return nil, false
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	body := map[string]interface{}{
+		"active":     true,
+		"client_id":  app.Code,
+		"token_type": "Bearer",
+		"jti":        claims.ID,
+		"app":        app.Code,
+		"appUuid":    app.UUID,
+	}
+	if claims.ExpiresAt != nil {
+		body["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		body["iat"] = claims.IssuedAt.Unix()
+	}
+
+	switch claims.Type {
+	case "user":
+		revoked, err := h.TokenRevocationService.IsTokenRevoked(claims.ID, claims.UserID, app.ID, issuedAt)
+		if err != nil || revoked {
+			return nil, false
+		}
+		user, err := h.UserService.GetUserByID(claims.UserID, app.ID)
+		if err != nil || user.Status == 0 {
+			return nil, false
+		}
+		roles, err := h.CasbinService.EffectiveRoleNames(user.ID)
+		if err != nil {
+			service.Log.Errorf("Introspect: Failed to load user roles: %v", err)
+			return nil, false
+		}
+		body["sub"] = fmt.Sprintf("%d", user.ID)
+		body["username"] = user.Username
+		body["roles"] = roles
+		return body, true
+	case "app":
+		revoked, err := h.TokenRevocationService.IsTokenRevoked(claims.ID, 0, app.ID, issuedAt)
+		if err != nil || revoked {
+			return nil, false
+		}
+		body["sub"] = app.Code
+		return body, true
+	default:
+		return nil, false
+	}
+}
+
+// introspectRefreshToken 内省刷新令牌
+func (h *OAuthHandler) introspectRefreshToken(token string, app *model.Application) (map[string]interface{}, bool) {
+	refreshToken, err := h.RefreshTokenService.GetActiveRefreshToken(token)
+	if err != nil || refreshToken.AppID != app.ID {
+		return nil, false
+	}
+
+	user, err := h.UserService.GetUserByID(refreshToken.UserID, app.ID)
+	if err != nil || user.Status == 0 {
+		return nil, false
+	}
+	roles, err := h.CasbinService.EffectiveRoleNames(user.ID)
+	if err != nil {
+		service.Log.Errorf("Introspect: Failed to load user roles: %v", err)
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		"active":     true,
+		"client_id":  app.Code,
+		"token_type": GrantTypeRefreshToken,
+		"exp":        refreshToken.ExpiresAt.Unix(),
+		"iat":        refreshToken.CreatedAt.Unix(),
+		"sub":        fmt.Sprintf("%d", user.ID),
+		"username":   user.Username,
+		"roles":      roles,
+		"app":        app.Code,
+		"appUuid":    app.UUID,
+	}, true
+}
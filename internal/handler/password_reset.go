@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// PasswordResetHandler 密码重置处理器（管理员签发重置令牌，用户凭令牌设置新密码）
+type PasswordResetHandler struct {
+	PasswordResetService   *service.PasswordResetService
+	UserService            *service.UserService
+	ApplicationService     *service.ApplicationService
+	AuditLogService        *service.AuditLogService
+	TokenRevocationService *service.TokenRevocationService
+	LoginGuardService      *service.LoginGuardService
+	Mailer                 *service.Mailer
+}
+
+// NewPasswordResetHandler 创建密码重置处理器实例
+func NewPasswordResetHandler(passwordResetService *service.PasswordResetService, userService *service.UserService, applicationService *service.ApplicationService, auditLogService *service.AuditLogService, tokenRevocationService *service.TokenRevocationService, loginGuardService *service.LoginGuardService, mailer *service.Mailer) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		PasswordResetService:   passwordResetService,
+		UserService:            userService,
+		ApplicationService:     applicationService,
+		AuditLogService:        auditLogService,
+		TokenRevocationService: tokenRevocationService,
+		LoginGuardService:      loginGuardService,
+		Mailer:                 mailer,
+	}
+}
+
+// ResetPasswordRequest 凭重置令牌设置新密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// resetPasswordMailBody 密码重置邮件正文
+func resetPasswordMailBody(app *model.Application, user *model.User, raw, link string, expiresIn time.Duration) string {
+	body := fmt.Sprintf("%s，您好：\n\n管理员为您在「%s」的账号发起了密码重置。", user.Username, app.Name)
+	if link != "" {
+		body += fmt.Sprintf("请在 %d 分钟内打开以下链接设置新密码（链接只能使用一次）：\n\n%s\n", int(expiresIn.Minutes()), link)
+	} else {
+		body += fmt.Sprintf("请在 %d 分钟内使用以下重置令牌设置新密码（只能使用一次）：\n\n%s\n", int(expiresIn.Minutes()), raw)
+	}
+	return body + "\n如果您并未申请重置密码，请联系管理员。\n"
+}
+
+// CreatePasswordReset 管理员为用户签发一次性密码重置令牌（之前未使用的令牌失效）。
+// 已配置 SMTP 且用户填写了邮箱时，同时发送重置邮件
+func (h *PasswordResetHandler) CreatePasswordReset(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user ID"})
+	}
+
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	user, err := h.UserService.GetUserByID(uint(id), appID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+	if user.Status == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "User is disabled"})
+	}
+
+	app, err := h.ApplicationService.GetApplicationByID(fmt.Sprintf("%d", appID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "Application not found"})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	raw, token, err := h.PasswordResetService.CreateToken(user, operatorID)
+	if err != nil {
+		service.Log.Errorf("CreatePasswordReset: %v, userID=%d", err, user.ID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to create password reset token"})
+	}
+	link := h.PasswordResetService.ResetLink(raw)
+
+	emailSent := false
+	if h.Mailer.Enabled() && user.Email != "" {
+		body := resetPasswordMailBody(app, user, raw, link, h.PasswordResetService.ExpireTime)
+		if err := h.Mailer.Send(user.Email, fmt.Sprintf("[%s] 重置密码", app.Name), body); err != nil {
+			service.Log.Errorf("CreatePasswordReset: %v, userID=%d", err, user.ID)
+		} else {
+			emailSent = true
+		}
+	}
+
+	h.AuditLogService.Record(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "ISSUE_PASSWORD_RESET",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    fmt.Sprintf("为用户 %s 签发密码重置令牌（邮件发送: %t）", user.Username, emailSent),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	response := map[string]interface{}{
+		"resetToken": raw,
+		"expiresAt":  token.ExpiresAt,
+		"emailSent":  emailSent,
+		"message":    "Password reset token created successfully",
+	}
+	if link != "" {
+		response["resetUrl"] = link
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword 凭重置令牌设置新密码（按应用的密码策略校验），成功后令牌失效、解除登录锁定并吊销该用户已签发的全部令牌
+func (h *PasswordResetHandler) ResetPassword(c echo.Context) error {
+	var req ResetPasswordRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" || req.NewPassword == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	token, err := h.PasswordResetService.GetValidToken(req.Token)
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidResetToken) {
+			service.Log.Errorf("ResetPassword: %v", err)
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid or expired reset token"})
+	}
+
+	user, err := h.UserService.GetUserByID(token.UserID, token.AppID)
+	if err != nil || user.Status == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid or expired reset token"})
+	}
+	app, err := h.ApplicationService.GetApplicationByID(fmt.Sprintf("%d", token.AppID))
+	if err != nil || app.Status == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid or expired reset token"})
+	}
+
+	// 密码策略校验失败时令牌仍可继续使用；校验通过后在同一事务中先认领令牌再更新密码，并发请求只有一个成功
+	err = h.UserService.ResetUserPassword(user.ID, app.ID, req.NewPassword, func(tx *gorm.DB) error {
+		return h.PasswordResetService.MarkUsedTx(tx, token)
+	})
+	if err != nil {
+		if violations, ok := service.IsPasswordPolicyError(err); ok {
+			return passwordPolicyErrorResponse(c, violations)
+		}
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid or expired reset token"})
+		}
+		service.Log.Errorf("ResetPassword: Failed to update password: %v, userID=%d", err, user.ID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to reset password"})
+	}
+
+	if err := h.LoginGuardService.ResetUser(app.ID, user.ID); err != nil {
+		service.Log.Warnf("ResetPassword: Failed to reset login failures: %v, userID=%d", err, user.ID)
+	}
+	if err := h.TokenRevocationService.RevokeUserTokens(user.ID); err != nil {
+		service.Log.Errorf("ResetPassword: Failed to revoke user tokens: %v, userID=%d", err, user.ID)
+	}
+
+	h.AuditLogService.Record(&model.AuditLog{
+		AppID:      app.ID,
+		UserID:     user.ID,
+		Username:   user.Username,
+		Action:     "RESET_PASSWORD",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    "用户通过重置令牌设置新密码",
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password reset successfully, please log in again"})
+}
@@ -1,12 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 
 	"Authos/internal/model"
 	"Authos/internal/service"
@@ -46,7 +47,7 @@ func (h *RoleHandler) CreateRole(c echo.Context) error {
 
 	if err := h.RoleService.CreateRole(&role); err != nil {
 		// 记录详细错误信息
-		log.Printf("Failed to create role: %v", err)
+		service.Log.Errorf("CreateRole: Failed to create role: %v", err)
 
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": fmt.Sprintf("Failed to create role: %v", err)})
 	}
@@ -165,7 +166,7 @@ func (h *RoleHandler) DeleteRole(c echo.Context) error {
 
 	if err := h.RoleService.DeleteRole(uint(id), appID); err != nil {
 		// Log the actual error for debugging
-		log.Printf("Error deleting role %d: %v", id, err)
+		service.Log.Errorf("DeleteRole: Failed to delete role %d: %v", id, err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": fmt.Sprintf("Failed to delete role: %v", err)})
 	}
 
@@ -223,6 +224,11 @@ func (h *RoleHandler) GetRole(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, map[string]string{"message": "Role not found"})
 	}
 
+	// 父角色与继承的接口权限
+	if err := h.RoleService.FillRoleHierarchy(role); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get role inheritance"})
+	}
+
 	return c.JSON(http.StatusOK, role)
 }
 
@@ -291,7 +297,9 @@ func (h *RoleHandler) ListRoles(c echo.Context) error {
 				role.ApiPermPreview = make([]string, 0, apiPreviewCount)
 				for i := 0; i < apiPreviewCount; i++ {
 					// 检查 policies[i] 的长度，防止索引越界
-					if len(policies[i]) > 2 {
+					if len(policies[i]) > 3 && policies[i][3] == model.EFFECT_DENY {
+						role.ApiPermPreview = append(role.ApiPermPreview, fmt.Sprintf("DENY %s %s", policies[i][2], policies[i][1]))
+					} else if len(policies[i]) > 2 {
 						role.ApiPermPreview = append(role.ApiPermPreview, fmt.Sprintf("%s %s", policies[i][2], policies[i][1]))
 					} else if len(policies[i]) > 1 {
 						role.ApiPermPreview = append(role.ApiPermPreview, policies[i][1])
@@ -299,11 +307,21 @@ func (h *RoleHandler) ListRoles(c echo.Context) error {
 				}
 			} else {
 				// Casbin 服务未初始化，记录日志并跳过权限信息
-				log.Printf("Warning: CasbinService or Enforcer is nil when listing roles")
+				service.Log.Warnf("ListRoles: CasbinService or Enforcer is nil")
 				role.ApiPermCount = 0
 				role.ApiPermPreview = []string{}
 			}
 		}
+
+		// 直接继承的父角色
+		role.ParentIDs = []uint{}
+		if h.RoleService.CasbinService != nil && h.RoleService.CasbinService.Enforcer != nil {
+			if parents, err := h.RoleService.CasbinService.GetRoleParents(role); err == nil {
+				for _, parent := range parents {
+					role.ParentIDs = append(role.ParentIDs, parent.ID)
+				}
+			}
+		}
 	}
 
 	return c.JSON(http.StatusOK, roles)
@@ -388,6 +406,184 @@ func (h *RoleHandler) UpdateRoleMenus(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"message": "Role menus updated successfully"})
 }
 
+// SetRoleParentsRequest 设置父角色请求
+type SetRoleParentsRequest struct {
+	ParentIDs []uint `json:"parentIds"` // 为空表示取消继承
+}
+
+// SetRoleParents 设置角色继承的父角色（同一应用内，禁止循环继承）
+func (h *RoleHandler) SetRoleParents(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid role ID"})
+	}
+
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	var req SetRoleParentsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.RoleService.SetRoleParents(uint(id), appID, req.ParentIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "Role not found"})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.RoleService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "UPDATE",
+		Resource:   "ROLE_PARENT",
+		ResourceID: fmt.Sprintf("%d", id),
+		Content:    fmt.Sprintf("设置角色继承, 角色ID: %d, 父角色ID: %v", id, req.ParentIDs),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Role parents updated successfully"})
+}
+
+// SetDataScopeRequest 设置数据范围请求
+type SetDataScopeRequest struct {
+	Scope   string `json:"scope"`   // ALL、DEPT、DEPT_AND_CHILDREN、SELF、CUSTOM
+	DeptIDs []uint `json:"deptIds"` // Scope 为 CUSTOM 时的部门ID
+}
+
+// SetRoleDataScope 设置角色默认的数据范围
+func (h *RoleHandler) SetRoleDataScope(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid role ID"})
+	}
+
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	var req SetDataScopeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.RoleService.SetRoleDataScope(uint(id), appID, req.Scope, req.DeptIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "Role not found"})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.RoleService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "UPDATE",
+		Resource:   "ROLE_DATA_SCOPE",
+		ResourceID: fmt.Sprintf("%d", id),
+		Content:    fmt.Sprintf("设置角色数据范围, 角色ID: %d, 范围: %s, 部门ID: %v", id, req.Scope, req.DeptIDs),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Role data scope updated successfully"})
+}
+
+// ListRoleDataScopes 获取角色在各接口权限上单独设置的数据范围
+func (h *RoleHandler) ListRoleDataScopes(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid role ID"})
+	}
+
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	scopes, err := h.RoleService.ListRolePermissionDataScopes(uint(id), appID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "Role not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get role data scopes"})
+	}
+	return c.JSON(http.StatusOK, scopes)
+}
+
+// SetRolePermissionDataScope 设置角色在指定接口权限上的数据范围（覆盖角色默认范围）
+func (h *RoleHandler) SetRolePermissionDataScope(c echo.Context) error {
+	var req SetDataScopeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+	if req.Scope == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "scope is required"})
+	}
+	return h.updateRolePermissionDataScope(c, req)
+}
+
+// DeleteRolePermissionDataScope 删除角色在指定接口权限上的数据范围，恢复使用角色默认范围
+func (h *RoleHandler) DeleteRolePermissionDataScope(c echo.Context) error {
+	return h.updateRolePermissionDataScope(c, SetDataScopeRequest{})
+}
+
+// updateRolePermissionDataScope 设置或删除（Scope 为空）角色在接口权限上的数据范围
+func (h *RoleHandler) updateRolePermissionDataScope(c echo.Context, req SetDataScopeRequest) error {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid role ID"})
+	}
+	permissionUUID := c.Param("permissionUUID")
+
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	if err := h.RoleService.SetRolePermissionDataScope(uint(id), appID, permissionUUID, req.Scope, req.DeptIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "Role or api permission not found"})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	action, content := "UPDATE", fmt.Sprintf("设置角色接口数据范围, 角色ID: %d, 接口权限: %s, 范围: %s, 部门ID: %v", id, permissionUUID, req.Scope, req.DeptIDs)
+	if req.Scope == "" {
+		action, content = "DELETE", fmt.Sprintf("删除角色接口数据范围, 角色ID: %d, 接口权限: %s", id, permissionUUID)
+	}
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.RoleService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     action,
+		Resource:   "ROLE_DATA_SCOPE",
+		ResourceID: fmt.Sprintf("%d", id),
+		Content:    content,
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Role data scope updated successfully"})
+}
+
 // AssignPermissionsRequest 分配权限请求
 type AssignPermissionsRequest struct {
 	Permissions []map[string]string `json:"permissions" binding:"required"`
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// SessionHandler 会话（令牌）管理处理器
+type SessionHandler struct {
+	TokenRevocationService *service.TokenRevocationService
+	UserService            *service.UserService
+	ApplicationService     *service.ApplicationService
+}
+
+// NewSessionHandler 创建会话管理处理器实例
+func NewSessionHandler(tokenRevocationService *service.TokenRevocationService, userService *service.UserService, applicationService *service.ApplicationService) *SessionHandler {
+	return &SessionHandler{
+		TokenRevocationService: tokenRevocationService,
+		UserService:            userService,
+		ApplicationService:     applicationService,
+	}
+}
+
+// RevokeUserSessions 强制下线指定用户（吊销其全部令牌）
+func (h *SessionHandler) RevokeUserSessions(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user ID"})
+	}
+
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	// 确认用户属于当前应用
+	user, err := h.UserService.GetUserByID(uint(id), appID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
+	}
+
+	if err := h.TokenRevocationService.RevokeUserTokens(user.ID); err != nil {
+		service.Log.Errorf("Failed to revoke user sessions: %v, userID=%d", err, user.ID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to revoke user sessions"})
+	}
+
+	// 记录审计日志
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "REVOKE_SESSIONS",
+		Resource:   "USER",
+		ResourceID: fmt.Sprintf("%d", user.ID),
+		Content:    fmt.Sprintf("吊销用户全部会话: %s", user.Username),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User sessions revoked successfully"})
+}
+
+// RevokeRoleSessions 强制下线持有指定角色的全部用户
+func (h *SessionHandler) RevokeRoleSessions(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid role ID"})
+	}
+
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	count, err := h.TokenRevocationService.RevokeRoleTokens(uint(id), appID)
+	if err != nil {
+		service.Log.Errorf("Failed to revoke role sessions: %v, roleID=%d", err, id)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to revoke role sessions"})
+	}
+
+	// 记录审计日志
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.UserService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "REVOKE_SESSIONS",
+		Resource:   "ROLE",
+		ResourceID: fmt.Sprintf("%d", id),
+		Content:    fmt.Sprintf("吊销角色下全部用户会话, 角色ID: %d, 用户数量: %d", id, count),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"users":   count,
+		"message": "Role sessions revoked successfully",
+	})
+}
+
+// RevokeApplicationSessions 强制下线指定应用的全部会话（系统级操作，仅系统管理员可调用）
+func (h *SessionHandler) RevokeApplicationSessions(c echo.Context) error {
+	id := c.Param("id")
+
+	app, err := h.ApplicationService.GetApplicationByID(id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "Application not found"})
+	}
+
+	if err := h.TokenRevocationService.RevokeAppTokens(app.ID); err != nil {
+		service.Log.Errorf("Failed to revoke application sessions: %v, appID=%d", err, app.ID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to revoke application sessions"})
+	}
+
+	// 记录审计日志
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.ApplicationService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "REVOKE_SESSIONS",
+		Resource:   "APPLICATION",
+		ResourceID: id,
+		Content:    fmt.Sprintf("吊销应用全部会话: %s (%s)", app.Name, app.Code),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Application sessions revoked successfully"})
+}
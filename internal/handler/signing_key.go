@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// SigningKeyHandler 签名密钥处理器
+type SigningKeyHandler struct {
+	SigningKeyService *service.SigningKeyService
+	JWTConfig         *service.JWTConfig
+}
+
+// NewSigningKeyHandler 创建签名密钥处理器实例
+func NewSigningKeyHandler(signingKeyService *service.SigningKeyService, jwtConfig *service.JWTConfig) *SigningKeyHandler {
+	return &SigningKeyHandler{
+		SigningKeyService: signingKeyService,
+		JWTConfig:         jwtConfig,
+	}
+}
+
+// JWKS 发布令牌验证公钥 (/.well-known/jwks.json)
+func (h *SigningKeyHandler) JWKS(c echo.Context) error {
+	jwks, err := h.JWTConfig.JWKS()
+	if err != nil {
+		service.Log.Errorf("JWKS: Failed to build key set: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to build key set"})
+	}
+
+	// 允许客户端短时间缓存公钥
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, jwks)
+}
+
+// ListSigningKeys 列出全部签名密钥（不含私钥）
+func (h *SigningKeyHandler) ListSigningKeys(c echo.Context) error {
+	keys, err := h.SigningKeyService.ListKeys()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to list signing keys"})
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// RotateSigningKeyRequest 轮换签名密钥请求
+type RotateSigningKeyRequest struct {
+	Algorithm string `json:"algorithm"` // 可选，RS256 / ES256，默认沿用当前算法
+}
+
+// RotateSigningKey 轮换签名密钥：新密钥立即用于签发，旧密钥在访问令牌有效期内继续用于校验
+func (h *SigningKeyHandler) RotateSigningKey(c echo.Context) error {
+	var req RotateSigningKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if h.JWTConfig.Keys.Empty() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Asymmetric signing is not enabled"})
+	}
+	if req.Algorithm != "" && req.Algorithm != service.AlgorithmRS256 && req.Algorithm != service.AlgorithmES256 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Unsupported algorithm"})
+	}
+
+	key, err := h.SigningKeyService.RotateKey(req.Algorithm)
+	if err != nil {
+		service.Log.Errorf("RotateSigningKey: Failed to rotate signing key: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to rotate signing key"})
+	}
+
+	if err := h.reloadKeyStore(); err != nil {
+		service.Log.Errorf("RotateSigningKey: Failed to reload key store: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to reload signing keys"})
+	}
+
+	// 记录审计日志
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.SigningKeyService.DB.Create(&model.AuditLog{
+		AppID:      0, // 系统级操作
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "ROTATE",
+		Resource:   "SIGNING_KEY",
+		ResourceID: key.KID,
+		Content:    fmt.Sprintf("轮换签名密钥, 新密钥: %s (%s)", key.KID, key.Algorithm),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"key":     key,
+		"message": "Signing key rotated successfully",
+	})
+}
+
+// RetireSigningKey 手动退役一把 verifying 密钥，其签发的令牌将立即无法通过校验
+func (h *SigningKeyHandler) RetireSigningKey(c echo.Context) error {
+	kid := c.Param("kid")
+
+	if err := h.SigningKeyService.RetireKey(kid); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := h.reloadKeyStore(); err != nil {
+		service.Log.Errorf("RetireSigningKey: Failed to reload key store: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to reload signing keys"})
+	}
+
+	// 记录审计日志
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.SigningKeyService.DB.Create(&model.AuditLog{
+		AppID:      0,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     "RETIRE",
+		Resource:   "SIGNING_KEY",
+		ResourceID: kid,
+		Content:    fmt.Sprintf("退役签名密钥: %s", kid),
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Signing key retired successfully"})
+}
+
+// reloadKeyStore 退役过期密钥并重新加载密钥库
+func (h *SigningKeyHandler) reloadKeyStore() error {
+	return h.SigningKeyService.RefreshKeyStore(h.JWTConfig.Keys, h.JWTConfig.ExpireTime)
+}
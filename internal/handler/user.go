@@ -3,6 +3,7 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"net/mail"
 	"strconv"
 	"strings"
 
@@ -22,14 +23,34 @@ func NewUserHandler(userService *service.UserService) *UserHandler {
 	return &UserHandler{UserService: userService}
 }
 
+// passwordPolicyErrorResponse 密码不符合应用密码策略时返回 400 及全部未满足的规则
+func passwordPolicyErrorResponse(c echo.Context, violations []string) error {
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"message":    "Password " + strings.Join(violations, "; "),
+		"violations": violations,
+	})
+}
+
+// validEmail 校验邮箱格式（为空表示未填写）
+func validEmail(email string) bool {
+	if email == "" {
+		return true
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && len(email) <= 100
+}
+
 // CreateUser 创建用户
 func (h *UserHandler) CreateUser(c echo.Context) error {
 	// 定义创建用户请求结构体
 	type CreateUserRequest struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		Email    string `json:"email"`
 		Status   int    `json:"status"`
 		RoleIDs  []uint `json:"roleIds"`
+		// Attributes 用户属性，用于权限条件（user.<属性名>）
+		Attributes map[string]string `json:"attributes"`
 	}
 
 	var req CreateUserRequest
@@ -47,6 +68,9 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 	if req.Password == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Password is required"})
 	}
+	if !validEmail(req.Email) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid email"})
+	}
 
 	// 从 JWT token 中获取 appID
 	appID, err := getAppIDFromToken(c)
@@ -56,11 +80,13 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 
 	// 创建用户对象
 	user := &model.User{
-		Username: req.Username,
-		Password: req.Password,
-		Status:   req.Status,
-		RoleIDs:  req.RoleIDs,
-		AppID:    appID,
+		Username:   req.Username,
+		Password:   req.Password,
+		Email:      req.Email,
+		Status:     req.Status,
+		RoleIDs:    req.RoleIDs,
+		AppID:      appID,
+		Attributes: req.Attributes,
 	}
 
 	if err := h.UserService.CreateUser(user); err != nil {
@@ -68,6 +94,9 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 		service.Log.Errorf("Failed to create user: %v, username=%s, appID=%d", err, user.Username, user.AppID)
 
 		// 根据错误类型返回不同的错误信息
+		if violations, ok := service.IsPasswordPolicyError(err); ok {
+			return passwordPolicyErrorResponse(c, violations)
+		}
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			return c.JSON(http.StatusConflict, map[string]string{"message": fmt.Sprintf("Username '%s' already exists", user.Username)})
 		}
@@ -128,20 +157,28 @@ func (h *UserHandler) UpdateUser(c echo.Context) error {
 	type UpdateUserRequest struct {
 		Username string `json:"username"`
 		Password string `json:"password,omitempty"`
+		Email    string `json:"email"`
 		Status   int    `json:"status"`
 		RoleIDs  []uint `json:"roleIds"`
+		// Attributes 用户属性，用于权限条件（user.<属性名>）
+		Attributes map[string]string `json:"attributes"`
 	}
 
 	var req UpdateUserRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
 	}
+	if !validEmail(req.Email) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid email"})
+	}
 
 	// 创建用户对象并设置ID和RoleIDs
 	user := &model.User{
-		Username: req.Username,
-		Status:   req.Status,
-		RoleIDs:  req.RoleIDs,
+		Username:   req.Username,
+		Email:      req.Email,
+		Status:     req.Status,
+		RoleIDs:    req.RoleIDs,
+		Attributes: req.Attributes,
 	}
 	// 设置ID（ID字段来自嵌入式gorm.Model）
 	user.ID = uint(id)
@@ -152,6 +189,17 @@ func (h *UserHandler) UpdateUser(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
 	}
 
+	// 如果提供了密码，先按密码策略校验并更新密码，校验失败时不修改其他信息
+	if req.Password != "" {
+		if err := h.UserService.UpdateUserPassword(uint(id), appID, req.Password); err != nil {
+			if violations, ok := service.IsPasswordPolicyError(err); ok {
+				return passwordPolicyErrorResponse(c, violations)
+			}
+			service.Log.Errorf("Failed to update user password: %v, userID=%d", err, id)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to update password"})
+		}
+	}
+
 	// 更新用户信息
 	if err := h.UserService.UpdateUser(user, appID); err != nil {
 		service.Log.Errorf("Failed to update user: %v, userID=%d, username=%s", err, user.ID, user.Username)
@@ -190,14 +238,6 @@ func (h *UserHandler) UpdateUser(c echo.Context) error {
 		Status:     1,
 	})
 
-	// 如果提供了密码，则更新密码
-	if req.Password != "" {
-		if err := h.UserService.UpdateUserPassword(uint(id), req.Password); err != nil {
-			service.Log.Errorf("Failed to update user password: %v, userID=%d", err, id)
-			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to update password"})
-		}
-	}
-
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"user":    user,
 		"message": "User updated successfully",
@@ -291,7 +331,7 @@ func (h *UserHandler) ListUsers(c echo.Context) error {
 	status := c.QueryParam("status")
 
 	var users []*model.User
-	db := h.UserService.DB.Preload("Roles").Where("app_id = ?", appID)
+	db := h.UserService.DB.Preload("Roles").Preload("Departments").Preload("Groups").Where("app_id = ?", appID)
 	if username != "" {
 		db = db.Where("username LIKE ?", "%"+username+"%")
 	}
@@ -303,12 +343,18 @@ func (h *UserHandler) ListUsers(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get users"})
 	}
 
-	// 填充 RoleIDs
+	// 填充 RoleIDs、DepartmentIDs 与 GroupIDs
 	for _, user := range users {
 		user.RoleIDs = make([]uint, 0, len(user.Roles))
 		for _, role := range user.Roles {
 			user.RoleIDs = append(user.RoleIDs, role.ID)
 		}
+		for _, dept := range user.Departments {
+			user.DepartmentIDs = append(user.DepartmentIDs, dept.ID)
+		}
+		for _, group := range user.Groups {
+			user.GroupIDs = append(user.GroupIDs, group.ID)
+		}
 	}
 
 	return c.JSON(http.StatusOK, users)
@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+	"Authos/internal/service"
+)
+
+// UserGroupHandler 用户组处理器
+type UserGroupHandler struct {
+	UserGroupService *service.UserGroupService
+}
+
+// NewUserGroupHandler 创建用户组处理器实例
+func NewUserGroupHandler(userGroupService *service.UserGroupService) *UserGroupHandler {
+	return &UserGroupHandler{UserGroupService: userGroupService}
+}
+
+// UserGroupRequest 创建/更新用户组请求
+type UserGroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// UserGroupMembersRequest 用户组成员增删请求
+type UserGroupMembersRequest struct {
+	UserIDs []uint `json:"userIds"`
+}
+
+// SetUserGroupRolesRequest 设置用户组角色请求
+type SetUserGroupRolesRequest struct {
+	RoleIDs []uint `json:"roleIds"` // 为空表示清空角色
+}
+
+// userGroupError 将用户组服务的错误转换为响应：记录不存在返回 404，名称重复返回 409，其余为请求错误
+func userGroupError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User group not found"})
+	case errors.Is(err, service.ErrUserGroupNameExists):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+}
+
+// audit 记录用户组相关的审计日志
+func (h *UserGroupHandler) audit(c echo.Context, appID uint, action, resourceID, content string) {
+	operatorID, operatorName := getOperatorFromContext(c)
+	h.UserGroupService.DB.Create(&model.AuditLog{
+		AppID:      appID,
+		UserID:     operatorID,
+		Username:   operatorName,
+		Action:     action,
+		Resource:   "USER_GROUP",
+		ResourceID: resourceID,
+		Content:    content,
+		IP:         c.RealIP(),
+		Status:     1,
+	})
+}
+
+// CreateUserGroup 创建用户组
+func (h *UserGroupHandler) CreateUserGroup(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	var req UserGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	group := &model.UserGroup{Name: req.Name, Description: req.Description, AppID: appID}
+	if err := h.UserGroupService.CreateUserGroup(group); err != nil {
+		return userGroupError(c, err)
+	}
+
+	h.audit(c, appID, "CREATE", fmt.Sprintf("%d", group.ID), fmt.Sprintf("创建用户组: %s", group.Name))
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"group":   group,
+		"message": "User group created successfully",
+	})
+}
+
+// UpdateUserGroup 更新用户组
+func (h *UserGroupHandler) UpdateUserGroup(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user group ID"})
+	}
+
+	var req UserGroupRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	group := &model.UserGroup{Name: req.Name, Description: req.Description, AppID: appID}
+	group.ID = uint(id)
+	if err := h.UserGroupService.UpdateUserGroup(group); err != nil {
+		return userGroupError(c, err)
+	}
+
+	h.audit(c, appID, "UPDATE", idStr, fmt.Sprintf("更新用户组: %s", group.Name))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"group":   group,
+		"message": "User group updated successfully",
+	})
+}
+
+// DeleteUserGroup 删除用户组
+func (h *UserGroupHandler) DeleteUserGroup(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user group ID"})
+	}
+
+	if err := h.UserGroupService.DeleteUserGroup(uint(id), appID); err != nil {
+		return userGroupError(c, err)
+	}
+
+	h.audit(c, appID, "DELETE", idStr, fmt.Sprintf("删除用户组ID: %d", id))
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User group deleted successfully"})
+}
+
+// GetUserGroup 获取用户组
+func (h *UserGroupHandler) GetUserGroup(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user group ID"})
+	}
+
+	group, err := h.UserGroupService.GetUserGroupByID(uint(id), appID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "User group not found"})
+	}
+
+	return c.JSON(http.StatusOK, group)
+}
+
+// ListUserGroups 列出用户组
+func (h *UserGroupHandler) ListUserGroups(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	groups, err := h.UserGroupService.ListUserGroupsByApp(appID, c.QueryParam("name"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to get user groups"})
+	}
+
+	return c.JSON(http.StatusOK, groups)
+}
+
+// ListUserGroupMembers 获取用户组成员
+func (h *UserGroupHandler) ListUserGroupMembers(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user group ID"})
+	}
+
+	users, err := h.UserGroupService.ListUserGroupMembers(uint(id), appID)
+	if err != nil {
+		return userGroupError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+// AddUserGroupMembers 将用户加入用户组
+func (h *UserGroupHandler) AddUserGroupMembers(c echo.Context) error {
+	return h.updateUserGroupMembers(c, true)
+}
+
+// RemoveUserGroupMembers 将用户移出用户组
+func (h *UserGroupHandler) RemoveUserGroupMembers(c echo.Context) error {
+	return h.updateUserGroupMembers(c, false)
+}
+
+// updateUserGroupMembers 批量加入（add 为 true）或移出用户组成员
+func (h *UserGroupHandler) updateUserGroupMembers(c echo.Context, add bool) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user group ID"})
+	}
+
+	var req UserGroupMembersRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if add {
+		err = h.UserGroupService.AddUserGroupMembers(uint(id), appID, req.UserIDs)
+	} else {
+		err = h.UserGroupService.RemoveUserGroupMembers(uint(id), appID, req.UserIDs)
+	}
+	if err != nil {
+		return userGroupError(c, err)
+	}
+
+	content := fmt.Sprintf("添加用户组成员, 用户组ID: %d, 用户ID: %v", id, req.UserIDs)
+	if !add {
+		content = fmt.Sprintf("移除用户组成员, 用户组ID: %d, 用户ID: %v", id, req.UserIDs)
+	}
+	h.audit(c, appID, "UPDATE", idStr, content)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User group members updated successfully"})
+}
+
+// SetUserGroupRoles 替换授予用户组的角色，组内成员继承这些角色
+func (h *UserGroupHandler) SetUserGroupRoles(c echo.Context) error {
+	// 从 JWT token 中获取 appID
+	appID, err := getAppIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "获取应用ID失败"})
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid user group ID"})
+	}
+
+	var req SetUserGroupRolesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid request"})
+	}
+
+	if err := h.UserGroupService.SetUserGroupRoles(uint(id), appID, req.RoleIDs); err != nil {
+		return userGroupError(c, err)
+	}
+
+	h.audit(c, appID, "UPDATE", idStr, fmt.Sprintf("设置用户组角色, 用户组ID: %d, 角色ID: %v", id, req.RoleIDs))
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User group roles updated successfully"})
+}
@@ -4,22 +4,35 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 
 	"Authos/internal/service"
 )
 
+// scopedAPIKeyRoutes 限定了 scopes 的个人 API 密钥在 /api/v1 下可访问的接口（方法 + 路由）。
+// scopes 是租户业务的接口权限标识，无法对应到 Authos 自身的管理接口，因此此类密钥只能查询范围内的有效权限，
+// 其余管理接口一律拒绝；鉴权请使用 /api/public/check-access 等接口，范围在那里生效
+var scopedAPIKeyRoutes = map[string]bool{
+	http.MethodGet + " /api/v1/me/permissions": true,
+}
+
 // JWTMiddleware JWT 认证中间件
 type JWTMiddleware struct {
-	JWTConfig *service.JWTConfig
+	JWTConfig              *service.JWTConfig
+	TokenRevocationService *service.TokenRevocationService
+	APIKeyService          *service.APIKeyService
+	CasbinService          *service.CasbinService
 }
 
 // NewJWTMiddleware 创建 JWT 中间件实例
-func NewJWTMiddleware(jwtConfig *service.JWTConfig) *JWTMiddleware {
+func NewJWTMiddleware(jwtConfig *service.JWTConfig, tokenRevocationService *service.TokenRevocationService, apiKeyService *service.APIKeyService, casbinService *service.CasbinService) *JWTMiddleware {
 	return &JWTMiddleware{
-		JWTConfig: jwtConfig,
+		JWTConfig:              jwtConfig,
+		TokenRevocationService: tokenRevocationService,
+		APIKeyService:          apiKeyService,
+		CasbinService:          casbinService,
 	}
 }
 
@@ -49,31 +62,37 @@ func (j *JWTMiddleware) Middleware() echo.MiddlewareFunc {
 				tokenString = strings.TrimPrefix(authHeader, "Bearer ")
 			}
 
-			// 2. 解析 Token (使用 MapClaims 以支持多种类型)
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				return []byte(j.JWTConfig.SecretKey), nil
-			})
-
-			if err != nil || !token.Valid {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired token"})
+			// 个人 API 密钥：以所属用户身份访问，不参与 JWT 吊销检查
+			if j.APIKeyService != nil && service.IsAPIKey(tokenString) {
+				key, user, err := j.APIKeyService.Authenticate(tokenString, c.RealIP())
+				if err != nil {
+					return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired api key"})
+				}
+				if len(key.Scopes) > 0 && !scopedAPIKeyRoutes[c.Request().Method+" "+c.Path()] {
+					return c.JSON(http.StatusForbidden, map[string]string{"message": "Scoped api keys cannot access management endpoints"})
+				}
+				c.Set("userID", user.ID)
+				c.Set("username", user.Username)
+				c.Set("appID", user.AppID)
+				c.Set("apiKey", key)
+				return next(c)
 			}
 
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token claims"})
+			// 2. 解析 Token (使用 MapClaims 以支持多种类型)
+			claims, err := j.JWTConfig.ParseMapClaims(tokenString)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid or expired token"})
 			}
 
 			// 3. 识别 Token 类型
 			tokenType, _ := claims["type"].(string)
 
-			// 如果没有 type 字段 (旧 Token)，尝试通过特征推断
+			// 如果没有 type 字段 (旧 Token)，尝试通过特征推断；无法识别的令牌（如 ID Token）一律拒绝
 			if tokenType == "" {
 				if _, ok := claims["isAdmin"]; ok {
 					tokenType = "system"
 				} else if _, ok := claims["appCode"]; ok && claims["username"] == nil {
 					tokenType = "app"
-				} else {
-					tokenType = "user"
 				}
 			}
 
@@ -96,6 +115,9 @@ func (j *JWTMiddleware) Middleware() echo.MiddlewareFunc {
 			case "app":
 				appIDFloat, _ := claims["appId"].(float64) // JSON 数字通常解析为 float64
 				appCode, _ := claims["appCode"].(string)
+				if appIDFloat <= 0 {
+					return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token claims"})
+				}
 				
 				c.Set("isAppToken", true)
 				c.Set("appID", uint(appIDFloat))
@@ -105,15 +127,53 @@ func (j *JWTMiddleware) Middleware() echo.MiddlewareFunc {
 				userIDFloat, _ := claims["userId"].(float64)
 				username, _ := claims["username"].(string)
 				appIDFloat, _ := claims["appId"].(float64)
+				if userIDFloat <= 0 || appIDFloat <= 0 {
+					return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token claims"})
+				}
 				
 				c.Set("userID", uint(userIDFloat))
 				c.Set("username", username)
 				c.Set("appID", uint(appIDFloat))
 
+				// 系统默认应用中拥有超级管理员身份的用户即系统管理员，可通过 X-App-ID 切换管理的应用
+				if uint(appIDFloat) == service.SystemAppID && j.CasbinService != nil {
+					superAdmin, err := j.CasbinService.IsSuperAdmin(uint(userIDFloat))
+					if err != nil {
+						return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to resolve user roles"})
+					}
+					c.Set("isSystemAdmin", superAdmin)
+				}
+
+			case "id":
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "ID tokens cannot be used as access tokens"})
+
 			default:
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unknown token type"})
 			}
 
+			// 5. 检查令牌是否已被吊销
+			jti, _ := claims["jti"].(string)
+			c.Set("jti", jti)
+			if j.TokenRevocationService != nil {
+				var issuedAt time.Time
+				if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+					issuedAt = iat.Time
+				}
+				userID, _ := c.Get("userID").(uint)
+				appID, _ := c.Get("appID").(uint)
+				if tokenType == "system" {
+					// 系统令牌的 appID 来自 X-App-ID 头，不参与应用级吊销
+					appID = 0
+				}
+				revoked, err := j.TokenRevocationService.IsTokenRevoked(jti, userID, appID, issuedAt)
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to check token revocation"})
+				}
+				if revoked {
+					return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Token has been revoked"})
+				}
+			}
+
 			return next(c)
 		}
 	}
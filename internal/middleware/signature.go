@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"Authos/internal/service"
+)
+
+// signatureMaxBodySize 参与签名的请求体大小上限
+const signatureMaxBodySize = 1 << 20
+
+// SignatureMiddleware 请求签名中间件：租户后端以应用密钥对请求签名，替代在请求体中传递 appSecret
+type SignatureMiddleware struct {
+	ApplicationService *service.ApplicationService
+}
+
+// NewSignatureMiddleware 创建请求签名中间件实例
+func NewSignatureMiddleware(applicationService *service.ApplicationService) *SignatureMiddleware {
+	return &SignatureMiddleware{
+		ApplicationService: applicationService,
+	}
+}
+
+// Middleware 返回请求签名中间件函数
+// 未携带 X-Authos-Signature 的请求直接放行（仍按原方式校验 appSecret）；
+// 携带签名时必须校验通过，通过后将签名应用写入上下文 "signedApp"
+func (s *SignatureMiddleware) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			signature := req.Header.Get(service.SignatureHeaderSignature)
+			if signature == "" {
+				return next(c)
+			}
+
+			appCode := req.Header.Get(service.SignatureHeaderApp)
+			timestamp := req.Header.Get(service.SignatureHeaderTimestamp)
+			nonce := req.Header.Get(service.SignatureHeaderNonce)
+			if appCode == "" || timestamp == "" || nonce == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "X-Authos-App, X-Authos-Timestamp and X-Authos-Nonce headers are required for signed requests"})
+			}
+
+			app, err := s.ApplicationService.GetApplicationByCode(appCode)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid application code"})
+			}
+
+			// 读取请求体用于计算摘要，之后恢复供处理器绑定
+			body, err := io.ReadAll(io.LimitReader(req.Body, signatureMaxBodySize+1))
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"message": "Failed to read request body"})
+			}
+			if len(body) > signatureMaxBodySize {
+				return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"message": "Request body is too large"})
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			if err := s.ApplicationService.VerifyRequestSignature(app, req.Method, req.RequestURI, timestamp, nonce, signature, body, c.RealIP()); err != nil {
+				if service.Log != nil {
+					service.Log.Warnf("Request signature rejected: %v, appCode=%s, path=%s", err, appCode, req.URL.Path)
+				}
+				switch {
+				case errors.Is(err, service.ErrSignatureExpired):
+					return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Request timestamp is out of range"})
+				case errors.Is(err, service.ErrNonceReplayed):
+					return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Request nonce has already been used"})
+				case errors.Is(err, service.ErrInvalidSignature):
+					return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid request signature"})
+				default:
+					return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to verify request signature"})
+				}
+			}
+
+			c.Set("signedApp", app)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SystemAdminMiddleware 系统管理员中间件：全局（跨应用）管理接口仅允许系统管理员访问
+// 系统管理员由 JWT 中间件识别：系统令牌，或系统默认应用中拥有超级管理员身份
+// （包括经用户组、部门、角色继承获得）的用户令牌；个人 API 密钥一律拒绝
+type SystemAdminMiddleware struct{}
+
+// NewSystemAdminMiddleware 创建系统管理员中间件实例
+func NewSystemAdminMiddleware() *SystemAdminMiddleware {
+	return &SystemAdminMiddleware{}
+}
+
+// Middleware 返回系统管理员中间件函数，须在 JWT 中间件之后使用
+func (m *SystemAdminMiddleware) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Get("apiKey") != nil {
+				return c.JSON(http.StatusForbidden, map[string]string{"message": "API keys cannot access system management endpoints"})
+			}
+			if isSystemAdmin, _ := c.Get("isSystemAdmin").(bool); !isSystemAdmin {
+				return c.JSON(http.StatusForbidden, map[string]string{"message": "System administrator privileges required"})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey 用户个人 API 密钥（只保存哈希），供脚本等机器访问代替用户令牌
+// 密钥以用户身份访问，Scopes 非空时只允许其中列出的接口权限
+type APIKey struct {
+	gorm.Model
+	Name       string     `gorm:"size:100;not null" json:"name"`                 // 密钥名称
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`                // 密钥明文前缀，用于辨识
+	KeyHash    string     `gorm:"uniqueIndex;size:64;not null" json:"-"`         // 密钥 SHA-256 哈希
+	AppID      uint       `gorm:"index;not null" json:"appId"`                   // 所属应用ID
+	UserID     uint       `gorm:"index;not null" json:"userId"`                  // 所属用户ID
+	Scopes     []string   `gorm:"serializer:json;type:text" json:"scopes"`       // 允许的接口权限标识，为空表示与用户权限一致
+	ExpiresAt  time.Time  `gorm:"not null" json:"expiresAt"`                     // 过期时间
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`                          // 最近使用时间
+	LastUsedIP string     `gorm:"column:last_used_ip;size:50" json:"lastUsedIp"` // 最近使用的客户端 IP
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`                           // 吊销时间
+	CreatedBy  uint       `json:"createdBy"`                                     // 创建者ID（用户本人或管理员）
+}
@@ -38,6 +38,15 @@ const (
 	HTTP_OPTIONS = "OPTIONS"
 )
 
+// 策略效果常量：角色与接口权限的绑定可以是允许或拒绝，拒绝优先
+const (
+	EFFECT_ALLOW = "allow"
+	EFFECT_DENY  = "deny"
+)
+
+// CONDITION_ALWAYS 无附加条件的策略，条件字段以 true 存储
+const CONDITION_ALWAYS = "true"
+
 // 获取所有HTTP方法
 func GetAllHttpMethods() []string {
 	return []string{
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 受信任客户端证书的类型
+const (
+	ClientCertTypeFingerprint = "fingerprint" // 按证书指纹精确匹配
+	ClientCertTypeCA          = "ca"          // 由该 CA 签发的客户端证书均可（可限定证书 CN）
+)
+
+// AppClientCert 应用受信任的客户端证书（mTLS），租户后端可用客户端证书代替 appSecret 认证应用身份
+type AppClientCert struct {
+	gorm.Model
+	AppID       uint       `gorm:"index;not null" json:"appId"`                   // 所属应用ID
+	Label       string     `gorm:"size:100;not null" json:"label"`                // 标签（如部署环境）
+	Type        string     `gorm:"size:20;not null" json:"type"`                  // fingerprint / ca
+	Fingerprint string     `gorm:"size:64;not null;index" json:"fingerprint"`     // 证书 SHA-256 指纹（十六进制小写）；ca 类型为 CA 证书指纹
+	Certificate string     `gorm:"type:text" json:"certificate,omitempty"`        // PEM 证书，ca 类型必填
+	Subject     string     `gorm:"size:255" json:"subject"`                       // 证书主题（仅展示）
+	SubjectCN   string     `gorm:"column:subject_cn;size:255" json:"subjectCn"`   // ca 类型可选：限定客户端证书的 CN
+	NotAfter    *time.Time `json:"notAfter,omitempty"`                            // 证书到期时间（仅展示）
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`                          // 最近使用时间
+	LastUsedIP  string     `gorm:"column:last_used_ip;size:50" json:"lastUsedIp"` // 最近使用的客户端 IP
+	CreatedBy   uint       `json:"createdBy"`                                     // 创建者ID
+}
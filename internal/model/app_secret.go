@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AppSecret 应用密钥（只保存哈希）。一个应用可同时持有多个有效密钥，
+// 轮换时先创建新密钥、租户后端切换后再吊销旧密钥，全程无需停机
+type AppSecret struct {
+	gorm.Model
+	AppID      uint       `gorm:"index;not null" json:"appId"`                   // 所属应用ID
+	Label      string     `gorm:"size:100;not null" json:"label"`                // 密钥标签（如部署环境）
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`                // 密钥明文前缀，列表中只展示前缀
+	SecretHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"`         // 密钥 SHA-256 哈希
+	SigningKey string     `gorm:"size:255" json:"-"`                             // 请求签名密钥（服务端密钥加密保存），为空时该密钥不能用于请求签名
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`                           // 过期时间，为空表示长期有效
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`                          // 最近使用时间
+	LastUsedIP string     `gorm:"column:last_used_ip;size:50" json:"lastUsedIp"` // 最近使用的客户端 IP
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`                           // 吊销时间
+	CreatedBy  uint       `json:"createdBy"`                                     // 创建者ID
+}
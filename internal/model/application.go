@@ -12,10 +12,28 @@ type Application struct {
 	UUID        string `gorm:"uniqueIndex;size:36;not null" json:"uuid"`     // 唯一标识, UUID格式
 	Name        string `gorm:"size:100;not null" json:"name"`                // 应用名称
 	Code        string `gorm:"uniqueIndex;size:50;not null" json:"code"`     // 应用代码
-	SecretKey   string `gorm:"size:100;not null" json:"secretKey,omitempty"` // 应用密钥（仅在创建时返回）
+	SecretKey   string `gorm:"size:100;not null" json:"secretKey,omitempty"` // 已废弃：旧版明文密钥，启动时迁移到 AppSecret 后清空；创建应用时用于返回一次新密钥明文
 	Status      int    `gorm:"default:1" json:"status"`                      // 1=Enable, 0=Disable
 	Description string `gorm:"size:255" json:"description"`                  // 描述
 
+	// RedirectURIs 授权码模式下允许回调的地址（精确匹配）
+	RedirectURIs []string `gorm:"serializer:json;type:text" json:"redirectUris"`
+
+	// MFAPolicy 两步验证策略：off / all / super_admin
+	MFAPolicy string `gorm:"column:mfa_policy;size:20;default:off" json:"mfaPolicy"`
+
+	// 登录防暴力破解：连续失败达到阈值后锁定，此后每次失败锁定时长翻倍（0 表示不限制）
+	LoginMaxFailures    int `gorm:"column:login_max_failures;default:5" json:"loginMaxFailures"`         // 单个用户允许的连续失败次数
+	LoginIPMaxFailures  int `gorm:"column:login_ip_max_failures;default:20" json:"loginIpMaxFailures"`   // 单个 IP 允许的连续失败次数
+	LoginLockoutSeconds int `gorm:"column:login_lockout_seconds;default:300" json:"loginLockoutSeconds"` // 首次锁定时长（秒）
+
+	// 密码策略：创建用户、管理员修改密码与用户自助修改密码时校验
+	PasswordMinLength   int  `gorm:"column:password_min_length;default:8" json:"passwordMinLength"`        // 最小长度
+	PasswordMinClasses  int  `gorm:"column:password_min_classes;default:2" json:"passwordMinClasses"`      // 至少包含的字符类别数（小写 / 大写 / 数字 / 符号）
+	PasswordCheckCommon bool `gorm:"column:password_check_common;default:true" json:"passwordCheckCommon"` // 禁止常见弱密码及包含用户名的密码
+	PasswordHistory     int  `gorm:"column:password_history;default:0" json:"passwordHistory"`             // 禁止重复使用最近 N 次密码（0 表示不限制）
+	PasswordMaxAgeDays  int  `gorm:"column:password_max_age_days;default:0" json:"passwordMaxAgeDays"`     // 密码有效期（天），过期后须修改密码才能登录（0 表示不过期）
+
 	// 关联关系
 	Users []*User `gorm:"foreignKey:AppID" json:"users,omitempty"`
 }
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PKCE 挑战方法，只接受 S256
+const CodeChallengeMethodS256 = "S256"
+
+// AuthorizationCode OAuth 2.0 授权码模型（只保存授权码哈希，不保存明文）
+// 授权码一次性有效，换取令牌时需提交与 CodeChallenge 匹配的 code_verifier (PKCE)
+type AuthorizationCode struct {
+	gorm.Model
+	CodeHash            string     `gorm:"uniqueIndex;size:64;not null" json:"-"` // 授权码 SHA-256 哈希
+	AppID               uint       `gorm:"index;not null" json:"appId"`           // 所属应用ID
+	UserID              uint       `gorm:"index;not null" json:"userId"`          // 授权用户ID
+	RedirectURI         string     `gorm:"size:500;not null" json:"redirectUri"`  // 授权请求中的回调地址
+	Scope               string     `gorm:"size:255" json:"scope"`                 // 授权范围
+	Nonce               string     `gorm:"size:255" json:"-"`                     // OIDC nonce，原样写入 ID Token
+	CodeChallenge       string     `gorm:"size:128;not null" json:"-"`            // PKCE code_challenge
+	CodeChallengeMethod string     `gorm:"size:10;not null" json:"-"`             // PKCE 挑战方法
+	ExpiresAt           time.Time  `gorm:"not null" json:"expiresAt"`             // 过期时间
+	UsedAt              *time.Time `json:"usedAt,omitempty"`                      // 兑换时间
+}
@@ -0,0 +1,34 @@
+package model
+
+import "gorm.io/gorm"
+
+// 数据范围常量：鉴权放行时返回，由租户后端据此过滤数据
+const (
+	DATA_SCOPE_ALL               = "ALL"               // 全部数据
+	DATA_SCOPE_DEPT              = "DEPT"              // 本部门数据
+	DATA_SCOPE_DEPT_AND_CHILDREN = "DEPT_AND_CHILDREN" // 本部门及下级部门数据
+	DATA_SCOPE_SELF              = "SELF"              // 仅本人数据
+	DATA_SCOPE_CUSTOM            = "CUSTOM"            // 指定部门数据
+)
+
+// GetAllDataScopes 获取所有数据范围
+func GetAllDataScopes() []string {
+	return []string{
+		DATA_SCOPE_ALL,
+		DATA_SCOPE_DEPT,
+		DATA_SCOPE_DEPT_AND_CHILDREN,
+		DATA_SCOPE_SELF,
+		DATA_SCOPE_CUSTOM,
+	}
+}
+
+// RoleDataScope 角色在某个接口权限上的数据范围，覆盖角色默认的数据范围
+type RoleDataScope struct {
+	gorm.Model
+	AppID           uint           `gorm:"index;not null" json:"appId"`
+	RoleID          uint           `gorm:"uniqueIndex:idx_role_data_scope;not null" json:"roleId"`
+	ApiPermissionID uint           `gorm:"uniqueIndex:idx_role_data_scope;not null" json:"apiPermissionId"`
+	Scope           string         `gorm:"size:30;not null" json:"scope"`
+	DeptIDs         []uint         `gorm:"serializer:json;type:text" json:"deptIds"` // Scope 为 CUSTOM 时的部门ID
+	ApiPermission   *ApiPermission `gorm:"foreignKey:ApiPermissionID" json:"apiPermission,omitempty"`
+}
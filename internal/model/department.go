@@ -0,0 +1,17 @@
+package model
+
+import "gorm.io/gorm"
+
+// Department 部门（组织单元）模型，按应用隔离的树形结构
+type Department struct {
+	gorm.Model
+	ParentID uint          `gorm:"default:0;index" json:"parentId"` // 树形结构，0 为根部门
+	Name     string        `gorm:"size:50;not null" json:"name"`
+	Sort     int           `gorm:"default:0" json:"sort"`
+	LeaderID uint          `gorm:"default:0" json:"leaderId"`   // 负责人（同一应用的用户ID），0 表示未设置
+	AppID    uint          `gorm:"index;not null" json:"appId"` // 所属应用ID
+	Users    []*User       `gorm:"many2many:user_departments;constraint:OnDelete:CASCADE" json:"users,omitempty"`
+	Roles    []*Role       `gorm:"many2many:department_roles;constraint:OnDelete:CASCADE" json:"roles,omitempty"` // 授予部门成员的角色
+	RoleIDs  []uint        `gorm:"-" json:"roleIds,omitempty"`                                                    // 用于回显，不存储到数据库
+	Children []*Department `gorm:"-" json:"children,omitempty"`                                                   // 用于树形返回，不存储到数据库
+}
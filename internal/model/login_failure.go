@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 登录失败计数的维度
+const (
+	LoginFailureScopeUser = "user" // 按用户计数，Subject 为用户ID
+	LoginFailureScopeIP   = "ip"   // 按客户端 IP 计数，Subject 为 IP
+)
+
+// LoginFailure 登录失败计数（按应用隔离）
+// 连续失败次数达到应用配置的阈值后进入锁定期，此后每次失败锁定时长翻倍
+type LoginFailure struct {
+	gorm.Model
+	AppID        uint       `gorm:"uniqueIndex:idx_login_failure_subject;not null" json:"appId"`           // 所属应用ID
+	Scope        string     `gorm:"uniqueIndex:idx_login_failure_subject;size:10;not null" json:"scope"`   // 计数维度：user / ip
+	Subject      string     `gorm:"uniqueIndex:idx_login_failure_subject;size:64;not null" json:"subject"` // 用户ID 或 IP
+	FailedCount  int        `gorm:"default:0;not null" json:"failedCount"`                                 // 连续失败次数
+	LastFailedAt time.Time  `json:"lastFailedAt"`                                                          // 最近一次失败时间
+	LockedUntil  *time.Time `json:"lockedUntil,omitempty"`                                                 // 锁定截止时间
+}
@@ -12,6 +12,7 @@ type Menu struct {
 	Path      string       `gorm:"size:200" json:"path"`
 	Component string       `gorm:"size:200" json:"component"`
 	Type      int          `gorm:"default:0" json:"type"` // 0=Directory, 1=Menu, 2=Button
+	Code      string       `gorm:"size:100" json:"code"`  // 按钮标识（Type=2 时使用），前端据此控制按钮显示
 	Sort      int          `gorm:"default:0" json:"sort"`
 	Hidden    bool         `gorm:"default:false" json:"hidden"`
 	IsSystem  bool         `gorm:"default:false" json:"isSystem"` // 是否为系统内置菜单
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 应用的两步验证策略
+// 已启用 MFA 的用户无论策略如何登录时都需要验证；
+// 策略要求但尚未绑定的用户，登录时需先完成绑定
+const (
+	MFAPolicyOff        = "off"         // 不强制
+	MFAPolicyAll        = "all"         // 应用内全部用户强制
+	MFAPolicySuperAdmin = "super_admin" // 仅持有超级管理员角色的用户强制
+)
+
+// MFARecoveryCode MFA 恢复码（只保存哈希，每个恢复码只能使用一次）
+type MFARecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"index;not null" json:"userId"` // 所属用户ID
+	CodeHash string     `gorm:"size:64;not null" json:"-"`    // 恢复码 SHA-256 哈希
+	UsedAt   *time.Time `json:"usedAt,omitempty"`             // 使用时间
+}
+
+// MFAChallenge 两步登录的挑战令牌（只保存哈希）
+// 密码校验通过后签发，凭挑战令牌与验证码换取正式令牌
+type MFAChallenge struct {
+	gorm.Model
+	TokenHash   string     `gorm:"uniqueIndex;size:64;not null" json:"-"` // 挑战令牌 SHA-256 哈希
+	UserID      uint       `gorm:"index;not null" json:"userId"`          // 用户ID
+	AppID       uint       `gorm:"not null" json:"appId"`                 // 登录的应用ID
+	LoginAction string     `gorm:"size:50;not null" json:"loginAction"`   // 发起挑战的登录方式（LOGIN / PROXY_LOGIN / SYSTEM_LOGIN / OAUTH_AUTHORIZE）
+	Attempts    int        `gorm:"default:0;not null" json:"attempts"`    // 验证失败次数
+	ExpiresAt   time.Time  `gorm:"not null" json:"expiresAt"`             // 过期时间
+	UsedAt      *time.Time `json:"usedAt,omitempty"`                      // 完成验证的时间
+}
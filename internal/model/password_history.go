@@ -0,0 +1,12 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// PasswordHistory 用户历史密码（bcrypt 哈希），用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	gorm.Model
+	UserID       uint   `gorm:"index;not null" json:"userId"` // 所属用户ID
+	PasswordHash string `gorm:"size:100;not null" json:"-"`   // 历史密码哈希
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken 管理员发起的密码重置令牌（只保存哈希，一次性有效）
+// 同一用户签发新令牌时，之前未使用的令牌全部失效
+type PasswordResetToken struct {
+	gorm.Model
+	TokenHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"` // 重置令牌 SHA-256 哈希
+	AppID     uint       `gorm:"index;not null" json:"appId"`           // 所属应用ID
+	UserID    uint       `gorm:"index;not null" json:"userId"`          // 被重置的用户ID
+	CreatedBy uint       `json:"createdBy"`                             // 发起重置的管理员ID
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`             // 过期时间
+	UsedAt    *time.Time `json:"usedAt,omitempty"`                      // 使用时间
+}
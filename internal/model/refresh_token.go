@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken 刷新令牌模型（只保存令牌哈希，不保存明文）
+// 同一次登录派生出的所有刷新令牌共享一个 FamilyID，
+// 一旦检测到已轮换的令牌被重复使用，整个家族将被吊销
+type RefreshToken struct {
+	gorm.Model
+	TokenHash string     `gorm:"uniqueIndex;size:64;not null" json:"-"`  // 令牌 SHA-256 哈希
+	FamilyID  string     `gorm:"index;size:36;not null" json:"familyId"` // 令牌家族ID
+	UserID    uint       `gorm:"index;not null" json:"userId"`           // 所属用户ID
+	AppID     uint       `gorm:"index;not null" json:"appId"`            // 所属应用ID
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`              // 过期时间
+	UsedAt    *time.Time `json:"usedAt,omitempty"`                       // 被轮换（使用）的时间
+	Revoked   bool       `gorm:"default:false;not null" json:"revoked"`  // 是否已吊销
+	IP        string     `gorm:"size:50" json:"ip"`                      // 签发时的客户端IP
+}
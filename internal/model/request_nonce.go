@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RequestNonce 已使用的请求签名随机串，在签名有效期内拒绝重复使用（防重放）
+type RequestNonce struct {
+	gorm.Model
+	AppID     uint      `gorm:"uniqueIndex:idx_request_nonce;not null" json:"appId"`         // 签名应用ID
+	Nonce     string    `gorm:"uniqueIndex:idx_request_nonce;size:64;not null" json:"nonce"` // 请求随机串
+	ExpiresAt time.Time `gorm:"index;not null" json:"expiresAt"`                             // 过期后可清理
+}
@@ -8,17 +8,34 @@ import (
 // Role 角色模型
 type Role struct {
 	gorm.Model
-	UUID           string       `gorm:"uniqueIndex;size:36;not null" json:"uuid"` // 唯一标识, UUID格式
-	Name           string       `gorm:"size:50;not null" json:"name"`
-	AppID          uint         `gorm:"not null" json:"appId"` // 所属应用ID
-	IsSuperAdmin   bool         `gorm:"default:false;not null" json:"isSuperAdmin"` // 是否为超级管理员
-	Users          []*User      `gorm:"many2many:user_roles;constraint:OnDelete:CASCADE" json:"users,omitempty"`
-	Menus          []*Menu      `gorm:"many2many:role_menus;constraint:OnDelete:CASCADE" json:"menus,omitempty"`
-	App            *Application `gorm:"foreignKey:AppID" json:"app,omitempty"`
-	MenuCount      int          `gorm:"-" json:"menuCount"`      // 用于列表展示
-	ApiPermCount   int          `gorm:"-" json:"apiPermCount"`   // 用于列表展示
-	MenuPreview    []string     `gorm:"-" json:"menuPreview"`    // 菜单预览（前几个名称）
-	ApiPermPreview []string     `gorm:"-" json:"apiPermPreview"` // 接口预览（前几个名称）
+	UUID         string `gorm:"uniqueIndex;size:36;not null" json:"uuid"` // 唯一标识, UUID格式
+	Name         string `gorm:"size:50;not null" json:"name"`
+	AppID        uint   `gorm:"not null" json:"appId"`                      // 所属应用ID
+	IsSuperAdmin bool   `gorm:"default:false;not null" json:"isSuperAdmin"` // 是否为超级管理员
+	// 角色默认的数据范围，接口权限可单独覆盖（见 RoleDataScope）
+	DataScope        string       `gorm:"size:30;default:ALL;not null" json:"dataScope"`
+	DataScopeDeptIDs []uint       `gorm:"serializer:json;type:text" json:"dataScopeDeptIds"` // DataScope 为 CUSTOM 时的部门ID
+	Users            []*User      `gorm:"many2many:user_roles;constraint:OnDelete:CASCADE" json:"users,omitempty"`
+	Menus            []*Menu      `gorm:"many2many:role_menus;constraint:OnDelete:CASCADE" json:"menus,omitempty"`
+	App              *Application `gorm:"foreignKey:AppID" json:"app,omitempty"`
+	MenuCount        int          `gorm:"-" json:"menuCount"`      // 用于列表展示
+	ApiPermCount     int          `gorm:"-" json:"apiPermCount"`   // 用于列表展示
+	MenuPreview      []string     `gorm:"-" json:"menuPreview"`    // 菜单预览（前几个名称）
+	ApiPermPreview   []string     `gorm:"-" json:"apiPermPreview"` // 接口预览（前几个名称）
+
+	// 角色继承（以 Casbin g 规则存储：g, role:子角色UUID, role:父角色UUID）
+	ParentIDs            []uint                `gorm:"-" json:"parentIds"`                      // 直接继承的父角色ID
+	InheritedSuperAdmin  bool                  `gorm:"-" json:"inheritedSuperAdmin,omitempty"`  // 是否从祖先角色继承了超级管理员
+	InheritedPermissions []InheritedPermission `gorm:"-" json:"inheritedPermissions,omitempty"` // 从祖先角色继承的接口权限
+}
+
+// InheritedPermission 从祖先角色继承的接口权限策略
+type InheritedPermission struct {
+	Obj          string `json:"obj"`          // 权限标识
+	Act          string `json:"act"`          // 方法
+	Effect       string `json:"effect"`       // allow 或 deny
+	FromRoleID   uint   `json:"fromRoleId"`   // 授予该权限的祖先角色ID
+	FromRoleName string `json:"fromRoleName"` // 授予该权限的祖先角色名称
 }
 
 // BeforeCreate GORM钩子，在创建前生成UUID
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 签名密钥状态
+// 轮换时旧的 active 密钥转为 verifying，继续用于校验尚未过期的令牌，
+// 超过访问令牌有效期后转为 retired，不再发布也不再用于校验
+const (
+	SigningKeyStatusActive    = "active"    // 当前用于签发令牌
+	SigningKeyStatusVerifying = "verifying" // 仅用于校验
+	SigningKeyStatusRetired   = "retired"   // 已退役
+)
+
+// SigningKey 令牌签名密钥（非对称密钥对，公钥通过 JWKS 对外发布）
+type SigningKey struct {
+	gorm.Model
+	KID        string     `gorm:"column:kid;uniqueIndex;size:64;not null" json:"kid"` // 密钥ID，写入令牌头部 kid
+	Algorithm  string     `gorm:"size:10;not null" json:"algorithm"`                  // 签名算法: RS256 / ES256
+	PrivateKey string     `gorm:"type:text;not null" json:"-"`                        // 私钥 (PKCS#8 PEM)，不对外返回
+	PublicKey  string     `gorm:"type:text;not null" json:"publicKey"`                // 公钥 (PKIX PEM)
+	Status     string     `gorm:"size:20;not null;index" json:"status"`               // 密钥状态
+	RotatedAt  *time.Time `json:"rotatedAt,omitempty"`                                // 被轮换下来（转为 verifying）的时间
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 批量吊销的主体类型
+const (
+	RevocationSubjectUser = "user" // 按用户吊销
+	RevocationSubjectApp  = "app"  // 按应用吊销
+)
+
+// RevokedToken 已吊销的单个令牌（按 jti 记录，过期后可清理）
+type RevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"uniqueIndex;size:36;not null" json:"jti"` // 令牌唯一标识
+	ExpiresAt time.Time `gorm:"index;not null" json:"expiresAt"`         // 令牌原本的过期时间
+}
+
+// TokenRevocation 批量吊销记录：在 RevokedAt 之前签发的该主体令牌全部失效
+type TokenRevocation struct {
+	gorm.Model
+	SubjectType string    `gorm:"uniqueIndex:idx_revocation_subject;size:20;not null" json:"subjectType"` // user / app
+	SubjectID   uint      `gorm:"uniqueIndex:idx_revocation_subject;not null" json:"subjectId"`           // 用户ID或应用ID
+	RevokedAt   time.Time `gorm:"not null" json:"revokedAt"`                                              // 吊销时间点
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -9,9 +11,29 @@ type User struct {
 	gorm.Model
 	Username string       `gorm:"uniqueIndex:idx_username_app;size:50;not null" json:"username"`
 	Password string       `gorm:"size:100;not null" json:"-"`
+	Email    string       `gorm:"size:100" json:"email"`                              // 邮箱（用于接收密码重置邮件）
 	Status   int          `gorm:"default:1" json:"status"`                            // 1=Enable, 0=Disable
 	AppID    uint         `gorm:"uniqueIndex:idx_username_app;not null" json:"appId"` // 所属应用ID
 	Roles    []*Role      `gorm:"many2many:user_roles;constraint:OnDelete:CASCADE" json:"roles,omitempty"`
 	RoleIDs  []uint       `gorm:"-" json:"roleIds,omitempty"` // 用于回显，不存储到数据库
 	App      *Application `gorm:"foreignKey:AppID" json:"app,omitempty"`
+
+	// Departments 所属部门（可属于多个部门），部门被授予的角色对成员生效
+	Departments   []*Department `gorm:"many2many:user_departments;constraint:OnDelete:CASCADE" json:"departments,omitempty"`
+	DepartmentIDs []uint        `gorm:"-" json:"departmentIds,omitempty"` // 用于回显，不存储到数据库
+
+	// Groups 所属用户组，用户组被授予的角色对成员生效
+	Groups   []*UserGroup `gorm:"many2many:user_group_members;constraint:OnDelete:CASCADE" json:"groups,omitempty"`
+	GroupIDs []uint       `gorm:"-" json:"groupIds,omitempty"` // 用于回显，不存储到数据库
+
+	// Attributes 用户属性（如 region），可在权限条件中以 user.<属性名> 引用
+	Attributes map[string]string `gorm:"serializer:json;type:text" json:"attributes,omitempty"`
+
+	// PasswordChangedAt 最近一次修改密码的时间，为空时以创建时间计算密码有效期
+	PasswordChangedAt *time.Time `json:"passwordChangedAt,omitempty"`
+
+	// 两步验证 (TOTP)
+	MFAEnabled   bool   `gorm:"column:mfa_enabled;default:false;not null" json:"mfaEnabled"` // 是否已启用 MFA
+	TOTPSecret   string `gorm:"column:totp_secret;size:255" json:"-"`                        // TOTP 密钥（Base32，服务端密钥加密保存），绑定确认前为待确认状态
+	TOTPLastStep int64  `gorm:"column:totp_last_step;default:0" json:"-"`                    // 最近一次通过校验的时间步，防止验证码重放
 }
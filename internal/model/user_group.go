@@ -0,0 +1,15 @@
+package model
+
+import "gorm.io/gorm"
+
+// UserGroup 用户组模型（按应用隔离），组内成员继承授予用户组的角色
+type UserGroup struct {
+	gorm.Model
+	Name        string  `gorm:"size:50;not null" json:"name"`
+	Description string  `gorm:"size:200" json:"description"`
+	AppID       uint    `gorm:"index;not null" json:"appId"` // 所属应用ID
+	Users       []*User `gorm:"many2many:user_group_members;constraint:OnDelete:CASCADE" json:"users,omitempty"`
+	Roles       []*Role `gorm:"many2many:user_group_roles;constraint:OnDelete:CASCADE" json:"roles,omitempty"`
+	RoleIDs     []uint  `gorm:"-" json:"roleIds,omitempty"` // 用于回显，不存储到数据库
+	MemberCount int64   `gorm:"-" json:"memberCount"`       // 成员数量，列表中返回
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+const (
+	// APIKeyPrefix API 密钥明文前缀，用于与 JWT 区分
+	APIKeyPrefix = "ak_"
+	// apiKeyMaxPerUser 每个用户最多持有的有效密钥数量
+	apiKeyMaxPerUser = 20
+	// apiKeyMaxLifetime 密钥最长有效期
+	apiKeyMaxLifetime = 365 * 24 * time.Hour
+	// apiKeyLastUsedInterval 最近使用时间的更新间隔，避免每次请求都写库
+	apiKeyLastUsedInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey API 密钥不存在、已过期、已吊销或所属用户不可用
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyNotFound 要吊销的密钥不存在或已吊销
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKeyService 用户个人 API 密钥服务
+type APIKeyService struct {
+	DB *gorm.DB
+}
+
+// NewAPIKeyService 创建 API 密钥服务实例
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{DB: db}
+}
+
+// IsAPIKey 判断凭证是否为 API 密钥（而非 JWT）
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// APIKeyAllows 判断密钥的权限范围是否包含指定的接口权限标识（未限定范围时全部允许）
+func APIKeyAllows(key *model.APIKey, permissionKey string) bool {
+	if len(key.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range key.Scopes {
+		if scope == permissionKey {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateKey 为用户创建 API 密钥，返回只展示一次的明文。
+// scopes 须为应用内已定义的接口权限标识，ttl 不能超过一年
+func (s *APIKeyService) CreateKey(user *model.User, name string, scopes []string, ttl time.Duration, createdBy uint) (string, *model.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return "", nil, errors.New("name is required and cannot exceed 100 characters")
+	}
+	if ttl <= 0 || ttl > apiKeyMaxLifetime {
+		return "", nil, errors.New("expiration must be between 1 and 365 days")
+	}
+
+	scopes = uniqueStrings(scopes)
+	if len(scopes) > 0 {
+		var found []string
+		if err := s.DB.Model(&model.ApiPermission{}).Where("key IN ? AND app_id = ?", scopes, user.AppID).
+			Distinct().Pluck("key", &found).Error; err != nil {
+			return "", nil, fmt.Errorf("failed to check scopes: %w", err)
+		}
+		if len(found) != len(scopes) {
+			return "", nil, fmt.Errorf("unknown permission key in scopes: %v", missingStrings(scopes, found))
+		}
+	}
+
+	var active int64
+	if err := s.DB.Model(&model.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Count(&active).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to count api keys: %w", err)
+	}
+	if active >= apiKeyMaxPerUser {
+		return "", nil, fmt.Errorf("a user can have at most %d active api keys", apiKeyMaxPerUser)
+	}
+
+	secret, err := generateRefreshToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	raw := APIKeyPrefix + secret
+
+	key := &model.APIKey{
+		Name:      name,
+		Prefix:    raw[:len(APIKeyPrefix)+8],
+		KeyHash:   hashRefreshToken(raw),
+		AppID:     user.AppID,
+		UserID:    user.ID,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedBy: createdBy,
+	}
+	if err := s.DB.Create(key).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+
+	return raw, key, nil
+}
+
+// ListKeys 列出用户的 API 密钥（包括已过期与已吊销的）
+func (s *APIKeyService) ListKeys(userID, appID uint) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	if err := s.DB.Where("user_id = ? AND app_id = ?", userID, appID).Order("id desc").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeKey 吊销用户的 API 密钥（按应用隔离）
+func (s *APIKeyService) RevokeKey(id, userID, appID uint) error {
+	result := s.DB.Model(&model.APIKey{}).
+		Where("id = ? AND user_id = ? AND app_id = ? AND revoked_at IS NULL", id, userID, appID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验 API 密钥，返回密钥及其所属用户，并记录最近使用时间与 IP
+func (s *APIKeyService) Authenticate(raw, ip string) (*model.APIKey, *model.User, error) {
+	var key model.APIKey
+	if err := s.DB.Where("key_hash = ?", hashRefreshToken(raw)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	if key.RevokedAt != nil || time.Now().After(key.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var user model.User
+	if err := s.DB.Where("id = ? AND app_id = ?", key.UserID, key.AppID).First(&user).Error; err != nil || user.Status == 0 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyLastUsedInterval || key.LastUsedIP != ip {
+		if err := s.DB.Model(&model.APIKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error; err != nil {
+			Log.Warnf("Failed to update api key last used time: %v, keyID=%d", err, key.ID)
+		}
+		key.LastUsedAt = &now
+		key.LastUsedIP = ip
+	}
+
+	return &key, &user, nil
+}
+
+// uniqueStrings 去除空白项与重复项（保持顺序）
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
+
+// missingStrings 返回 want 中不在 have 里的项
+func missingStrings(want, have []string) []string {
+	present := make(map[string]bool, len(have))
+	for _, v := range have {
+		present[v] = true
+	}
+	var missing []string
+	for _, v := range want {
+		if !present[v] {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	apiKeyService := NewAPIKeyService(db)
+
+	user := &model.User{Username: "script", Password: "hash", AppID: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := db.Create(&model.ApiPermission{Key: "order:read", Name: "读订单", Path: "/orders", Method: "GET", AppID: 1}).Error; err != nil {
+		t.Fatalf("failed to create permission: %v", err)
+	}
+
+	if _, _, err := apiKeyService.CreateKey(user, "ci", []string{"order:write"}, 24*time.Hour, user.ID); err == nil {
+		t.Fatal("expected unknown scope to be rejected")
+	}
+	if _, _, err := apiKeyService.CreateKey(user, "ci", nil, 400*24*time.Hour, user.ID); err == nil {
+		t.Fatal("expected lifetime over one year to be rejected")
+	}
+
+	raw, key, err := apiKeyService.CreateKey(user, "ci", []string{"order:read", "order:read"}, 24*time.Hour, user.ID)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if !IsAPIKey(raw) || !strings.HasPrefix(raw, key.Prefix) || len(key.Scopes) != 1 {
+		t.Fatalf("unexpected api key: raw=%s key=%+v", raw, key)
+	}
+
+	got, owner, err := apiKeyService.Authenticate(raw, "10.0.0.1")
+	if err != nil || owner.ID != user.ID || got.LastUsedAt == nil || got.LastUsedIP != "10.0.0.1" {
+		t.Fatalf("failed to authenticate api key: key=%+v owner=%+v err=%v", got, owner, err)
+	}
+	if !APIKeyAllows(got, "order:read") || APIKeyAllows(got, "order:delete") {
+		t.Fatal("unexpected api key scope check result")
+	}
+
+	// 所属用户被禁用后密钥不可用
+	db.Model(&model.User{}).Where("id = ?", user.ID).Update("status", 0)
+	if _, _, err := apiKeyService.Authenticate(raw, "10.0.0.1"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected api key of disabled user to be rejected, got %v", err)
+	}
+	db.Model(&model.User{}).Where("id = ?", user.ID).Update("status", 1)
+
+	// 其他应用不能吊销该密钥
+	if err := apiKeyService.RevokeKey(key.ID, user.ID, 2); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected cross-application revoke to fail, got %v", err)
+	}
+	if err := apiKeyService.RevokeKey(key.ID, user.ID, 1); err != nil {
+		t.Fatalf("failed to revoke api key: %v", err)
+	}
+	if _, _, err := apiKeyService.Authenticate(raw, "10.0.0.1"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected revoked api key to be rejected, got %v", err)
+	}
+
+	// 已过期的密钥不可用
+	expired, expiredKey, err := apiKeyService.CreateKey(user, "old", nil, time.Hour, user.ID)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	db.Model(&model.APIKey{}).Where("id = ?", expiredKey.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, _, err := apiKeyService.Authenticate(expired, "10.0.0.1"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected expired api key to be rejected, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"Authos/internal/model"
@@ -52,54 +53,64 @@ func (s *ApiPermissionService) GetApiPermissionByUUID(appID uint, uuid string) (
 	return &permission, nil
 }
 
-// GetApiPermissionByPathAndMethod 根据路径和方法获取接口权限（支持前缀匹配，优先具体方法，其次 *）（按应用隔离）
-func (s *ApiPermissionService) GetApiPermissionByPathAndMethod(appID uint, path, method string) (*model.ApiPermission, error) {
-	// 统一规范路径，避免尾部斜杠造成的不一致
-	reqPath := strings.TrimRight(path, "/")
-	if reqPath == "" {
-		reqPath = "/"
-	}
+// ApiPermissionCandidate 路径前缀匹配请求路径的接口权限，以及未被选中的原因
+type ApiPermissionCandidate struct {
+	Key      string `json:"key"`
+	Path     string `json:"path"`
+	Method   string `json:"method"`
+	Selected bool   `json:"selected"`
+	Reason   string `json:"reason"`
+}
 
-	var permissions []model.ApiPermission
+// ApiPermissionMatch 接口权限匹配过程的解释
+type ApiPermissionMatch struct {
+	Path       string                   `json:"path"`
+	Method     string                   `json:"method"`
+	Permission *model.ApiPermission     `json:"permission,omitempty"` // 最终匹配的接口权限
+	Reason     string                   `json:"reason"`               // 匹配（或未匹配）的原因
+	Candidates []ApiPermissionCandidate `json:"candidates"`           // 路径前缀匹配的全部接口权限
+}
 
-	// 一次性查出当前应用下当前方法和 * 方法的所有配置，减少多次 IO
-	if err := s.DB.Where("app_id = ? AND method IN ?",
-		appID,
-		[]string{method, model.HTTP_ALL},
-	).Find(&permissions).Error; err != nil {
-		return nil, fmt.Errorf("查询接口权限失败: %v", err)
+// normalizeApiPath 统一规范路径，避免尾部斜杠造成的不一致
+func normalizeApiPath(path string) string {
+	path = strings.TrimRight(path, "/")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// apiMethodScore method 匹配优先级：2 精确方法；1 HTTP_ALL（*）；0 不匹配
+func apiMethodScore(configured, method string) int {
+	if configured == method {
+		return 2
+	} else if configured == model.HTTP_ALL {
+		return 1
 	}
+	return 0
+}
 
+// selectApiPermission 选择匹配的接口权限：配置的 path 必须是请求 path 的前缀，选择更长的前缀（更具体的路径），
+// 相同前缀长度下优先方法更精确
+func selectApiPermission(permissions []model.ApiPermission, reqPath, method string) *model.ApiPermission {
 	var (
 		bestMatch       *model.ApiPermission
 		bestPathLength  int
-		bestMethodScore int // 2: 精确方法; 1: HTTP_ALL
+		bestMethodScore int
 	)
 
 	for i := range permissions {
 		p := &permissions[i]
-		cfgPath := strings.TrimRight(p.Path, "/")
-		if cfgPath == "" {
-			cfgPath = "/"
-		}
-
-		// 路径前缀匹配：配置的 path 必须是请求 path 的前缀
+		cfgPath := normalizeApiPath(p.Path)
 		if !strings.HasPrefix(reqPath, cfgPath) {
 			continue
 		}
 
-		// method 匹配优先级：先精确方法，再 HTTP_ALL（*）
-		methodScore := 0
-		if p.Method == method {
-			methodScore = 2
-		} else if p.Method == model.HTTP_ALL {
-			methodScore = 1
-		}
+		methodScore := apiMethodScore(p.Method, method)
 		if methodScore == 0 {
 			continue
 		}
 
-		// 选择更长的前缀（更具体的路径），并且在相同前缀长度下优先方法更精确
 		if len(cfgPath) > bestPathLength || (len(cfgPath) == bestPathLength && methodScore > bestMethodScore) {
 			bestMatch = p
 			bestPathLength = len(cfgPath)
@@ -107,6 +118,22 @@ func (s *ApiPermissionService) GetApiPermissionByPathAndMethod(appID uint, path,
 		}
 	}
 
+	return bestMatch
+}
+
+// GetApiPermissionByPathAndMethod 根据路径和方法获取接口权限（支持前缀匹配，优先具体方法，其次 *）（按应用隔离）
+func (s *ApiPermissionService) GetApiPermissionByPathAndMethod(appID uint, path, method string) (*model.ApiPermission, error) {
+	var permissions []model.ApiPermission
+
+	// 一次性查出当前应用下当前方法和 * 方法的所有配置，减少多次 IO
+	if err := s.DB.Where("app_id = ? AND method IN ?",
+		appID,
+		[]string{method, model.HTTP_ALL},
+	).Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("查询接口权限失败: %v", err)
+	}
+
+	bestMatch := selectApiPermission(permissions, normalizeApiPath(path), method)
 	if bestMatch == nil {
 		return nil, fmt.Errorf("接口权限未找到: path=%s method=%s", path, method)
 	}
@@ -114,6 +141,83 @@ func (s *ApiPermissionService) GetApiPermissionByPathAndMethod(appID uint, path,
 	return bestMatch, nil
 }
 
+// ExplainApiPermissionMatch 解释路径和方法匹配接口权限的过程：列出路径前缀匹配的全部接口权限，
+// 说明最终选中的权限以及其余权限落选的原因（按应用隔离）
+func (s *ApiPermissionService) ExplainApiPermissionMatch(appID uint, path, method string) (*ApiPermissionMatch, error) {
+	var permissions []model.ApiPermission
+	if err := s.DB.Where("app_id = ?", appID).Order("id asc").Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("查询接口权限失败: %v", err)
+	}
+
+	reqPath := normalizeApiPath(path)
+	match := &ApiPermissionMatch{
+		Path:       path,
+		Method:     method,
+		Permission: selectApiPermission(permissions, reqPath, method),
+		Candidates: []ApiPermissionCandidate{},
+	}
+
+	var bestPath string
+	if match.Permission != nil {
+		bestPath = normalizeApiPath(match.Permission.Path)
+		methodDesc := "exact method"
+		if apiMethodScore(match.Permission.Method, method) == 1 {
+			methodDesc = "wildcard method *"
+		}
+		match.Reason = fmt.Sprintf("longest matching path prefix %s (%d chars) with %s", bestPath, len(bestPath), methodDesc)
+	}
+
+	for _, p := range permissions {
+		cfgPath := normalizeApiPath(p.Path)
+		if !strings.HasPrefix(reqPath, cfgPath) {
+			continue
+		}
+
+		candidate := ApiPermissionCandidate{Key: p.Key, Path: p.Path, Method: p.Method}
+		switch {
+		case match.Permission != nil && p.ID == match.Permission.ID:
+			candidate.Selected = true
+			candidate.Reason = match.Reason
+		case apiMethodScore(p.Method, method) == 0:
+			candidate.Reason = fmt.Sprintf("method %s does not match %s", p.Method, method)
+		case len(cfgPath) < len(bestPath):
+			candidate.Reason = fmt.Sprintf("path prefix %s is shorter than %s", cfgPath, bestPath)
+		case apiMethodScore(p.Method, method) < apiMethodScore(match.Permission.Method, method):
+			candidate.Reason = "same path prefix, but the selected permission has a more specific method"
+		default:
+			candidate.Reason = "same path prefix and method as the selected permission, which was configured earlier"
+		}
+		match.Candidates = append(match.Candidates, candidate)
+	}
+
+	if match.Permission == nil {
+		if len(match.Candidates) == 0 {
+			match.Reason = "no api permission path is a prefix of the request path"
+		} else {
+			match.Reason = "api permissions match the path prefix, but none allows the method"
+		}
+	}
+
+	return match, nil
+}
+
+// GetExistingPermissionKeys 返回 keys 中在应用内已定义的权限标识
+func (s *ApiPermissionService) GetExistingPermissionKeys(appID uint, keys []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(keys) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	if err := s.DB.Model(&model.ApiPermission{}).Where("key IN ? AND app_id = ?", keys, appID).Pluck("key", &found).Error; err != nil {
+		return nil, fmt.Errorf("查询接口权限失败: %v", err)
+	}
+	for _, key := range found {
+		existing[key] = true
+	}
+	return existing, nil
+}
+
 // CreateApiPermission 创建接口权限（按应用隔离）
 func (s *ApiPermissionService) CreateApiPermission(appID uint, key, name, path, method, description string) (*model.ApiPermission, error) {
 	if key == "" || name == "" || path == "" || method == "" {
@@ -245,6 +349,10 @@ func (s *ApiPermissionService) DeleteApiPermission(id uint, appID uint) error {
 	if err := s.DB.Delete(permission).Error; err != nil {
 		return fmt.Errorf("删除接口权限失败: %v", err)
 	}
+	// 删除角色在该权限上的数据范围
+	if err := s.DB.Unscoped().Where("api_permission_id = ?", permission.ID).Delete(&model.RoleDataScope{}).Error; err != nil {
+		return fmt.Errorf("删除接口权限数据范围失败: %v", err)
+	}
 
 	// 重新加载策略
 	s.CasbinService.Enforcer.LoadPolicy()
@@ -265,6 +373,9 @@ func (s *ApiPermissionService) GetRolesForApiPermission(appID uint, permissionUU
 
 	var roleUUIDs []string
 	for _, policy := range policies {
+		if isDenyPolicy(policy) {
+			continue
+		}
 		if len(policy) >= 1 && len(policy[0]) > 5 && policy[0][:5] == "role:" {
 			// 提取角色UUID
 			roleUUID := policy[0][5:]
@@ -285,21 +396,40 @@ func (s *ApiPermissionService) GetRolesForApiPermission(appID uint, permissionUU
 
 // AddApiPermissionToRole 为角色添加接口权限（按应用隔离）
 func (s *ApiPermissionService) AddApiPermissionToRole(appID uint, roleUUID, permissionUUID string) error {
+	return s.AddApiPermissionRuleToRole(appID, roleUUID, permissionUUID, model.HTTP_ALL, model.EFFECT_ALLOW, "")
+}
+
+// AddApiPermissionRuleToRole 为角色添加接口权限的允许或拒绝规则（按应用隔离）
+// act 为 * 时对全部方法生效；拒绝规则优先于任何角色的允许规则；
+// condition 为空时无附加条件，否则规则只在条件成立时生效
+func (s *ApiPermissionService) AddApiPermissionRuleToRole(appID uint, roleUUID, permissionUUID, act, effect, condition string) error {
+	act, effect, err := normalizePermissionRule(act, effect)
+	if err != nil {
+		return err
+	}
+	condition, err = normalizeCondition(condition)
+	if err != nil {
+		return err
+	}
+
 	// 获取权限
 	permission, err := s.GetApiPermissionByUUID(appID, permissionUUID)
 	if err != nil {
 		return fmt.Errorf("接口权限不存在: %v", err)
 	}
 
-	// 检查权限是否已存在
+	// 检查权限是否已存在（同一角色、权限、方法与效果只能有一条规则）
 	rolePrefix := fmt.Sprintf("role:%s", roleUUID)
-	hasPolicy, _ := s.CasbinService.Enforcer.HasPolicy(rolePrefix, permission.Key, model.HTTP_ALL)
-	if hasPolicy {
+	existing, _ := s.CasbinService.Enforcer.GetFilteredPolicy(0, rolePrefix, permission.Key, act, effect)
+	if len(existing) > 0 {
+		if effect == model.EFFECT_DENY {
+			return fmt.Errorf("角色已拒绝此权限")
+		}
 		return fmt.Errorf("角色已拥有此权限")
 	}
 
 	// 添加权限策略
-	_, err = s.CasbinService.Enforcer.AddPolicy(rolePrefix, permission.Key, model.HTTP_ALL)
+	_, err = s.CasbinService.Enforcer.AddPolicy(rolePrefix, permission.Key, act, effect, condition)
 	if err != nil {
 		return fmt.Errorf("添加权限策略失败: %v", err)
 	}
@@ -310,6 +440,16 @@ func (s *ApiPermissionService) AddApiPermissionToRole(appID uint, roleUUID, perm
 
 // RemoveApiPermissionFromRole 移除角色的接口权限
 func (s *ApiPermissionService) RemoveApiPermissionFromRole(appID uint, roleUUID, permissionUUID string) error {
+	return s.RemoveApiPermissionRuleFromRole(appID, roleUUID, permissionUUID, model.HTTP_ALL, model.EFFECT_ALLOW)
+}
+
+// RemoveApiPermissionRuleFromRole 移除角色接口权限的允许或拒绝规则
+func (s *ApiPermissionService) RemoveApiPermissionRuleFromRole(appID uint, roleUUID, permissionUUID, act, effect string) error {
+	act, effect, err := normalizePermissionRule(act, effect)
+	if err != nil {
+		return err
+	}
+
 	// 获取权限
 	permission, err := s.GetApiPermissionByUUID(appID, permissionUUID)
 	if err != nil {
@@ -318,7 +458,7 @@ func (s *ApiPermissionService) RemoveApiPermissionFromRole(appID uint, roleUUID,
 
 	// 移除权限策略
 	rolePrefix := fmt.Sprintf("role:%s", roleUUID)
-	_, err = s.CasbinService.Enforcer.RemovePolicy(rolePrefix, permission.Key, model.HTTP_ALL)
+	_, err = s.CasbinService.Enforcer.RemoveFilteredPolicy(0, rolePrefix, permission.Key, act, effect)
 	if err != nil {
 		return fmt.Errorf("移除权限策略失败: %v", err)
 	}
@@ -327,6 +467,26 @@ func (s *ApiPermissionService) RemoveApiPermissionFromRole(appID uint, roleUUID,
 	return s.CasbinService.Enforcer.LoadPolicy()
 }
 
+// normalizePermissionRule 校验并补全规则的方法与效果，默认为全部方法、允许
+func normalizePermissionRule(act, effect string) (string, string, error) {
+	act = strings.ToUpper(strings.TrimSpace(act))
+	if act == "" {
+		act = model.HTTP_ALL
+	}
+	if !slices.Contains(model.GetAllHttpMethods(), act) {
+		return "", "", fmt.Errorf("不支持的HTTP方法: %s", act)
+	}
+
+	effect = strings.ToLower(strings.TrimSpace(effect))
+	if effect == "" {
+		effect = model.EFFECT_ALLOW
+	}
+	if effect != model.EFFECT_ALLOW && effect != model.EFFECT_DENY {
+		return "", "", fmt.Errorf("不支持的策略效果: %s", effect)
+	}
+	return act, effect, nil
+}
+
 // GetApiPermissionsForRole 获取角色的接口权限（按应用隔离）
 func (s *ApiPermissionService) GetApiPermissionsForRole(appID uint, roleUUID string) ([]model.ApiPermission, error) {
 	// 先查询角色信息，检查是否是超级管理员
@@ -352,7 +512,7 @@ func (s *ApiPermissionService) GetApiPermissionsForRole(appID uint, roleUUID str
 	var permissionKeys []string
 
 	for _, policy := range policies {
-		if len(policy) >= 3 {
+		if len(policy) >= 3 && !isDenyPolicy(policy) {
 			permissionKeys = append(permissionKeys, policy[1])
 		}
 	}
@@ -367,3 +527,59 @@ func (s *ApiPermissionService) GetApiPermissionsForRole(appID uint, roleUUID str
 
 	return permissions, nil
 }
+
+// ApiPermissionRule 角色对接口权限的规则
+type ApiPermissionRule struct {
+	PermissionUUID string `json:"permissionUUID"`
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	Path           string `json:"path"`
+	Act            string `json:"act"`                 // 方法，* 表示全部方法
+	Effect         string `json:"effect"`              // allow 或 deny
+	Condition      string `json:"condition,omitempty"` // 附加条件，为空表示无条件
+}
+
+// GetApiPermissionRulesForRole 获取角色直接设置的规则（按应用隔离），effect 为空时返回全部规则
+func (s *ApiPermissionService) GetApiPermissionRulesForRole(appID uint, roleUUID, effect string) ([]ApiPermissionRule, error) {
+	rolePrefix := fmt.Sprintf("role:%s", roleUUID)
+	policies, err := s.CasbinService.Enforcer.GetFilteredPolicy(0, rolePrefix, "", "", effect)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []ApiPermissionRule{}
+	if len(policies) == 0 {
+		return rules, nil
+	}
+	keys := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		keys = append(keys, policy[1])
+	}
+	var permissions []model.ApiPermission
+	if err := s.DB.Where("key IN ? AND app_id = ?", keys, appID).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]model.ApiPermission, len(permissions))
+	for _, permission := range permissions {
+		byKey[permission.Key] = permission
+	}
+	for _, policy := range policies {
+		permission, ok := byKey[policy[1]]
+		if !ok {
+			continue
+		}
+		rule := ApiPermissionRule{
+			PermissionUUID: permission.UUID,
+			Key:            permission.Key,
+			Name:           permission.Name,
+			Path:           permission.Path,
+			Act:            policy[2],
+			Effect:         policy[3],
+		}
+		if isConditionalPolicy(policy) {
+			rule.Condition = policy[4]
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
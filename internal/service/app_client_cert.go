@@ -0,0 +1,206 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Authos/internal/model"
+)
+
+// appClientCertMax 每个应用最多登记的受信任客户端证书数量
+const appClientCertMax = 10
+
+// ErrClientCertNotFound 受信任客户端证书不存在
+var ErrClientCertNotFound = errors.New("client certificate not found")
+
+// CertificateFingerprint 计算证书的 SHA-256 指纹（DER 编码，十六进制小写）
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint 规范化指纹：兼容 openssl 输出的冒号分隔大写格式
+func normalizeFingerprint(fingerprint string) (string, error) {
+	fingerprint = strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(fingerprint)))
+	if len(fingerprint) != sha256.Size*2 {
+		return "", errors.New("fingerprint must be a SHA-256 hex digest")
+	}
+	if _, err := hex.DecodeString(fingerprint); err != nil {
+		return "", errors.New("fingerprint must be a SHA-256 hex digest")
+	}
+	return fingerprint, nil
+}
+
+// parsePEMCertificate 解析 PEM 编码的证书（只取第一个证书）
+func parsePEMCertificate(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(data)))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("certificate must be PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// AddClientCert 为应用登记受信任的客户端证书：
+// fingerprint 类型可直接提供指纹或提供证书（由证书计算指纹）；ca 类型必须提供 CA 证书，可选限定客户端证书 CN
+func (s *ApplicationService) AddClientCert(appID uint, entry *model.AppClientCert, createdBy uint) (*model.AppClientCert, error) {
+	entry.Label = strings.TrimSpace(entry.Label)
+	if entry.Label == "" || len([]rune(entry.Label)) > 100 {
+		return nil, errors.New("label is required and cannot exceed 100 characters")
+	}
+
+	var cert *x509.Certificate
+	if strings.TrimSpace(entry.Certificate) != "" {
+		parsed, err := parsePEMCertificate(entry.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		cert = parsed
+	}
+
+	switch entry.Type {
+	case model.ClientCertTypeFingerprint:
+		entry.SubjectCN = ""
+		if cert == nil {
+			fingerprint, err := normalizeFingerprint(entry.Fingerprint)
+			if err != nil {
+				return nil, err
+			}
+			entry.Fingerprint = fingerprint
+			entry.Certificate = ""
+		}
+	case model.ClientCertTypeCA:
+		if cert == nil {
+			return nil, errors.New("certificate is required for ca type")
+		}
+		if !cert.IsCA {
+			return nil, errors.New("certificate is not a CA certificate")
+		}
+		entry.SubjectCN = strings.TrimSpace(entry.SubjectCN)
+	default:
+		return nil, errors.New("type must be fingerprint or ca")
+	}
+
+	if cert != nil {
+		entry.Fingerprint = CertificateFingerprint(cert)
+		entry.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+		entry.Subject = cert.Subject.String()
+		notAfter := cert.NotAfter
+		entry.NotAfter = &notAfter
+	}
+
+	var count int64
+	if err := s.DB.Model(&model.AppClientCert{}).Where("app_id = ?", appID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count >= appClientCertMax {
+		return nil, fmt.Errorf("an application can trust at most %d client certificates", appClientCertMax)
+	}
+	if err := s.DB.Model(&model.AppClientCert{}).Where("app_id = ? AND type = ? AND fingerprint = ?", appID, entry.Type, entry.Fingerprint).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("client certificate is already trusted by this application")
+	}
+
+	entry.ID = 0
+	entry.AppID = appID
+	entry.CreatedBy = createdBy
+	entry.LastUsedAt = nil
+	entry.LastUsedIP = ""
+	if err := s.DB.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to save client certificate: %w", err)
+	}
+	return entry, nil
+}
+
+// ListClientCerts 列出应用受信任的客户端证书
+func (s *ApplicationService) ListClientCerts(appID uint) ([]*model.AppClientCert, error) {
+	var certs []*model.AppClientCert
+	if err := s.DB.Where("app_id = ?", appID).Order("id desc").Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// DeleteClientCert 移除应用受信任的客户端证书
+func (s *ApplicationService) DeleteClientCert(appID, certID uint) error {
+	result := s.DB.Where("id = ? AND app_id = ?", certID, appID).Delete(&model.AppClientCert{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrClientCertNotFound
+	}
+	return nil
+}
+
+// VerifyClientCert 校验 TLS 握手中的客户端证书是否受应用信任。
+// 握手只要求客户端证明持有私钥，证书链与有效期在这里按应用登记的指纹或 CA 校验
+func (s *ApplicationService) VerifyClientCert(app *model.Application, peerCerts []*x509.Certificate, ip string) bool {
+	if len(peerCerts) == 0 {
+		return false
+	}
+	leaf := peerCerts[0]
+	now := time.Now()
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return false
+	}
+
+	var entries []*model.AppClientCert
+	if err := s.DB.Where("app_id = ?", app.ID).Find(&entries).Error; err != nil {
+		return false
+	}
+
+	fingerprint := CertificateFingerprint(leaf)
+	for _, entry := range entries {
+		if !clientCertMatches(entry, leaf, peerCerts[1:], fingerprint) {
+			continue
+		}
+		if entry.LastUsedAt == nil || now.Sub(*entry.LastUsedAt) > appSecretLastUsedInterval || entry.LastUsedIP != ip {
+			s.DB.Model(&model.AppClientCert{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+				"last_used_at": now,
+				"last_used_ip": ip,
+			})
+		}
+		return true
+	}
+	return false
+}
+
+// clientCertMatches 判断客户端证书是否与一条受信任记录匹配
+func clientCertMatches(entry *model.AppClientCert, leaf *x509.Certificate, intermediates []*x509.Certificate, fingerprint string) bool {
+	switch entry.Type {
+	case model.ClientCertTypeFingerprint:
+		return entry.Fingerprint == fingerprint
+	case model.ClientCertTypeCA:
+		if entry.SubjectCN != "" && leaf.Subject.CommonName != entry.SubjectCN {
+			return false
+		}
+		ca, err := parsePEMCertificate(entry.Certificate)
+		if err != nil {
+			return false
+		}
+		opts := x509.VerifyOptions{
+			Roots:         x509.NewCertPool(),
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		opts.Roots.AddCert(ca)
+		for _, cert := range intermediates {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err = leaf.Verify(opts)
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+// issueTestCert 签发测试证书，parent 为空时生成自签名证书
+func issueTestCert(t *testing.T, cn string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func certPEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func TestVerifyClientCert(t *testing.T) {
+	db := newTestDB(t)
+	applicationService := NewApplicationService(db)
+
+	app := &model.Application{ID: 1}
+	other := &model.Application{ID: 2}
+
+	ca, caKey := issueTestCert(t, "tenant-ca", true, nil, nil)
+	backend, _ := issueTestCert(t, "orders-backend", false, ca, caKey)
+	pinned, _ := issueTestCert(t, "pinned-backend", false, nil, nil)
+
+	// 未登记任何证书时不信任
+	if applicationService.VerifyClientCert(app, []*x509.Certificate{pinned}, "") {
+		t.Fatal("expected untrusted certificate to be rejected")
+	}
+
+	// 按指纹登记（兼容 openssl 冒号分隔的大写格式）
+	fingerprint := CertificateFingerprint(pinned)
+	colon := ""
+	for i := 0; i < len(fingerprint); i += 2 {
+		if i > 0 {
+			colon += ":"
+		}
+		colon += fingerprint[i : i+2]
+	}
+	if _, err := applicationService.AddClientCert(app.ID, &model.AppClientCert{Label: "pinned", Type: model.ClientCertTypeFingerprint, Fingerprint: colon}, 1); err != nil {
+		t.Fatalf("failed to trust fingerprint: %v", err)
+	}
+	if !applicationService.VerifyClientCert(app, []*x509.Certificate{pinned}, "") {
+		t.Fatal("expected pinned certificate to be trusted")
+	}
+	if applicationService.VerifyClientCert(other, []*x509.Certificate{pinned}, "") {
+		t.Fatal("expected certificate not to be trusted by other application")
+	}
+
+	// 非 CA 证书不能按 CA 登记
+	if _, err := applicationService.AddClientCert(app.ID, &model.AppClientCert{Label: "bad", Type: model.ClientCertTypeCA, Certificate: certPEM(pinned)}, 1); err == nil {
+		t.Fatal("expected non-CA certificate to be rejected as ca")
+	}
+
+	// 按 CA 登记并限定 CN
+	if _, err := applicationService.AddClientCert(app.ID, &model.AppClientCert{Label: "ca", Type: model.ClientCertTypeCA, Certificate: certPEM(ca), SubjectCN: "orders-backend"}, 1); err != nil {
+		t.Fatalf("failed to trust ca: %v", err)
+	}
+	if !applicationService.VerifyClientCert(app, []*x509.Certificate{backend}, "") {
+		t.Fatal("expected certificate issued by trusted ca to be trusted")
+	}
+	stranger, _ := issueTestCert(t, "billing-backend", false, ca, caKey)
+	if applicationService.VerifyClientCert(app, []*x509.Certificate{stranger}, "") {
+		t.Fatal("expected certificate with other CN to be rejected")
+	}
+	forged, _ := issueTestCert(t, "orders-backend", false, nil, nil)
+	if applicationService.VerifyClientCert(app, []*x509.Certificate{forged}, "") {
+		t.Fatal("expected self-signed certificate with the same CN to be rejected")
+	}
+}
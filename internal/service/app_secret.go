@@ -0,0 +1,183 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+const (
+	// appSecretMaxActive 每个应用最多同时持有的有效密钥数量
+	appSecretMaxActive = 5
+	// appSecretLastUsedInterval 最近使用时间的更新间隔，避免每次请求都写库
+	appSecretLastUsedInterval = time.Minute
+)
+
+var (
+	// ErrAppSecretNotFound 密钥不存在或已吊销
+	ErrAppSecretNotFound = errors.New("application secret not found")
+	// ErrLastAppSecret 不能吊销应用最后一个有效密钥
+	ErrLastAppSecret = errors.New("cannot revoke the last active secret, create a new one first")
+)
+
+// activeSecrets 有效（未吊销且未过期）密钥的查询条件
+func activeSecrets(db *gorm.DB, appID uint) *gorm.DB {
+	return db.Model(&model.AppSecret{}).
+		Where("app_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", appID, time.Now())
+}
+
+// sealSigningKey 由密钥明文推导请求签名密钥并加密保存，未配置 SecretBox 时返回空（该密钥不能用于请求签名）
+func (s *ApplicationService) sealSigningKey(raw string) (string, error) {
+	if s.SecretBox == nil {
+		return "", nil
+	}
+	signingKey, err := s.SecretBox.Seal(SignatureSigningKey(raw))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+	return signingKey, nil
+}
+
+// createSecret 生成并保存新密钥，返回只展示一次的明文
+func (s *ApplicationService) createSecret(tx *gorm.DB, appID uint, label string, ttl time.Duration, createdBy uint) (string, *model.AppSecret, error) {
+	raw := s.generateSecretKey()
+	secret := &model.AppSecret{
+		AppID:      appID,
+		Label:      label,
+		Prefix:     raw[:8],
+		SecretHash: hashRefreshToken(raw),
+		CreatedBy:  createdBy,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		secret.ExpiresAt = &expiresAt
+	}
+	signingKey, err := s.sealSigningKey(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	secret.SigningKey = signingKey
+	if err := tx.Create(secret).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to save application secret: %w", err)
+	}
+	return raw, secret, nil
+}
+
+// CreateSecret 为应用新增一个密钥（ttl 为 0 表示长期有效），已有密钥继续有效
+func (s *ApplicationService) CreateSecret(appID uint, label string, ttl time.Duration, createdBy uint) (string, *model.AppSecret, error) {
+	label = strings.TrimSpace(label)
+	if label == "" || len([]rune(label)) > 100 {
+		return "", nil, errors.New("label is required and cannot exceed 100 characters")
+	}
+	if ttl < 0 {
+		return "", nil, errors.New("expiration must not be negative")
+	}
+
+	var active int64
+	if err := activeSecrets(s.DB, appID).Count(&active).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to count application secrets: %w", err)
+	}
+	if active >= appSecretMaxActive {
+		return "", nil, fmt.Errorf("an application can have at most %d active secrets, revoke an old one first", appSecretMaxActive)
+	}
+
+	return s.createSecret(s.DB, appID, label, ttl, createdBy)
+}
+
+// ListSecrets 列出应用的密钥（只包含前缀，不包含明文与哈希）
+func (s *ApplicationService) ListSecrets(appID uint) ([]*model.AppSecret, error) {
+	var secrets []*model.AppSecret
+	if err := s.DB.Where("app_id = ?", appID).Order("id desc").Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// RevokeSecret 吊销应用的一个密钥（不能吊销最后一个有效密钥）
+func (s *ApplicationService) RevokeSecret(appID, secretID uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var secret model.AppSecret
+		if err := tx.Where("id = ? AND app_id = ? AND revoked_at IS NULL", secretID, appID).First(&secret).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAppSecretNotFound
+			}
+			return err
+		}
+
+		var active int64
+		if err := activeSecrets(tx, appID).Where("id <> ?", secretID).Count(&active).Error; err != nil {
+			return err
+		}
+		if active == 0 {
+			return ErrLastAppSecret
+		}
+
+		return tx.Model(&secret).Update("revoked_at", time.Now()).Error
+	})
+}
+
+// VerifySecret 校验应用密钥（任一有效密钥匹配即通过），并记录该密钥的最近使用时间与 IP
+func (s *ApplicationService) VerifySecret(app *model.Application, raw, ip string) bool {
+	if raw == "" {
+		return false
+	}
+
+	var secret model.AppSecret
+	if err := activeSecrets(s.DB, app.ID).Where("secret_hash = ?", hashRefreshToken(raw)).First(&secret).Error; err != nil {
+		return false
+	}
+
+	s.touchSecret(&secret, ip)
+	return true
+}
+
+// touchSecret 记录密钥的最近使用时间与 IP（按间隔节流）
+func (s *ApplicationService) touchSecret(secret *model.AppSecret, ip string) {
+	now := time.Now()
+	if secret.LastUsedAt != nil && now.Sub(*secret.LastUsedAt) <= appSecretLastUsedInterval && secret.LastUsedIP == ip {
+		return
+	}
+	if err := s.DB.Model(&model.AppSecret{}).Where("id = ?", secret.ID).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ip,
+	}).Error; err != nil && Log != nil {
+		Log.Warnf("Failed to update application secret last used time: %v, secretID=%d", err, secret.ID)
+	}
+}
+
+// MigrateLegacySecrets 将旧版保存在 Application.SecretKey 中的明文密钥迁移为哈希密钥（同时保存加密的请求签名密钥），并清空明文
+func (s *ApplicationService) MigrateLegacySecrets() (int, error) {
+	var apps []*model.Application
+	if err := s.DB.Unscoped().Where("secret_key <> ''").Find(&apps).Error; err != nil {
+		return 0, err
+	}
+
+	for _, app := range apps {
+		signingKey, err := s.sealSigningKey(app.SecretKey)
+		if err != nil {
+			return 0, err
+		}
+		err = s.DB.Transaction(func(tx *gorm.DB) error {
+			secret := &model.AppSecret{
+				AppID:      app.ID,
+				Label:      "default",
+				Prefix:     app.SecretKey[:min(8, len(app.SecretKey))],
+				SecretHash: hashRefreshToken(app.SecretKey),
+				SigningKey: signingKey,
+			}
+			if err := tx.Create(secret).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&model.Application{}).Where("id = ?", app.ID).Update("secret_key", "").Error
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to migrate secret of application %s: %w", app.Code, err)
+		}
+	}
+	return len(apps), nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestAppSecretRotation(t *testing.T) {
+	db := newTestDB(t)
+	applicationService := NewApplicationService(db)
+
+	app, err := applicationService.CreateApplication("galaxy", "galaxy", "")
+	if err != nil {
+		t.Fatalf("failed to create application: %v", err)
+	}
+	first := app.SecretKey
+	if !applicationService.VerifySecret(app, first, "10.0.0.1") {
+		t.Fatal("expected initial secret to be valid")
+	}
+
+	// 明文不落库
+	var stored model.Application
+	db.Where("id = ?", app.ID).First(&stored)
+	if stored.SecretKey != "" {
+		t.Fatal("expected plaintext secret not to be stored")
+	}
+
+	// 新增密钥后新旧密钥同时有效
+	second, secret, err := applicationService.CreateSecret(app.ID, "v2", 0, 1)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	if !applicationService.VerifySecret(app, first, "") || !applicationService.VerifySecret(app, second, "") {
+		t.Fatal("expected both secrets to be valid during rotation")
+	}
+
+	// 吊销旧密钥后只有新密钥有效；最后一个有效密钥不能吊销
+	secrets, _ := applicationService.ListSecrets(app.ID)
+	if len(secrets) != 2 || secrets[0].LastUsedAt == nil && secrets[1].LastUsedAt == nil {
+		t.Fatalf("unexpected secrets: %+v", secrets)
+	}
+	oldID := secrets[1].ID
+	if err := applicationService.RevokeSecret(app.ID+1, oldID); !errors.Is(err, ErrAppSecretNotFound) {
+		t.Fatalf("expected cross-application revoke to fail, got %v", err)
+	}
+	if err := applicationService.RevokeSecret(app.ID, oldID); err != nil {
+		t.Fatalf("failed to revoke secret: %v", err)
+	}
+	if applicationService.VerifySecret(app, first, "") || !applicationService.VerifySecret(app, second, "") {
+		t.Fatal("expected only the new secret to be valid")
+	}
+	if err := applicationService.RevokeSecret(app.ID, secret.ID); !errors.Is(err, ErrLastAppSecret) {
+		t.Fatalf("expected revoking the last secret to fail, got %v", err)
+	}
+
+	// 已过期的密钥无效
+	expiring, expiringSecret, err := applicationService.CreateSecret(app.ID, "temp", time.Hour, 1)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	db.Model(&model.AppSecret{}).Where("id = ?", expiringSecret.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if applicationService.VerifySecret(app, expiring, "") {
+		t.Fatal("expected expired secret to be rejected")
+	}
+
+	// 重置后全部旧密钥失效
+	reset, _, err := applicationService.ResetSecretKey(app.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to reset secret: %v", err)
+	}
+	if applicationService.VerifySecret(app, second, "") || !applicationService.VerifySecret(app, reset, "") {
+		t.Fatal("expected only the reset secret to be valid")
+	}
+}
+
+func TestMigrateLegacySecrets(t *testing.T) {
+	db := newTestDB(t)
+	applicationService := NewApplicationService(db)
+	secretBox, err := NewSecretBox(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create secret box: %v", err)
+	}
+	applicationService.SecretBox = secretBox
+
+	app := &model.Application{Name: "legacy", Code: "legacy", SecretKey: "legacy-plaintext-secret"}
+	if err := db.Create(app).Error; err != nil {
+		t.Fatalf("failed to create application: %v", err)
+	}
+
+	migrated, err := applicationService.MigrateLegacySecrets()
+	if err != nil || migrated != 1 {
+		t.Fatalf("unexpected migration result: %d, %v", migrated, err)
+	}
+	if !applicationService.VerifySecret(app, "legacy-plaintext-secret", "") {
+		t.Fatal("expected migrated secret to remain valid")
+	}
+
+	// 迁移时由明文推导并保存签名密钥，迁移后的密钥可直接用于请求签名
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := SignRequest(SignatureSigningKey("legacy-plaintext-secret"), CanonicalRequest("GET", "/api/public/check-access", now, "nonce-legacy", nil))
+	if err := applicationService.VerifyRequestSignature(app, "GET", "/api/public/check-access", now, "nonce-legacy", signature, nil, ""); err != nil {
+		t.Fatalf("expected migrated secret to sign requests, got %v", err)
+	}
+
+	// 再次迁移不会重复创建
+	if migrated, err := applicationService.MigrateLegacySecrets(); err != nil || migrated != 0 {
+		t.Fatalf("expected migration to be idempotent, got %d, %v", migrated, err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -12,7 +14,8 @@ import (
 
 // ApplicationService 应用服务
 type ApplicationService struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	SecretBox *SecretBox // 加密保存请求签名密钥，未配置时新密钥不能用于请求签名
 }
 
 // NewApplicationService 创建应用服务实例
@@ -31,15 +34,12 @@ func (s *ApplicationService) generateSecretKey() string {
 
 // CreateApplication 创建应用
 func (s *ApplicationService) CreateApplication(name, code, description string) (*model.Application, error) {
-	fmt.Printf("CreateApplication called with name=%s, code=%s, description=%s\n", name, code, description)
-
 	// 检查应用代码是否已存在（包括软删除的记录）
 	var count int64
 	if err := s.DB.Unscoped().Model(&model.Application{}).Where("code = ?", code).Count(&count).Error; err != nil {
 		return nil, fmt.Errorf("failed to check application existence: %w", err)
 	}
 
-	fmt.Printf("Found %d applications with code '%s'\n", count, code)
 	if count > 0 {
 		return nil, fmt.Errorf("application with code '%s' already exists", code)
 	}
@@ -52,12 +52,20 @@ func (s *ApplicationService) CreateApplication(name, code, description string) (
 		Status:      1, // 默认启用
 	}
 
-	// 生成密钥和UUID
-	app.SecretKey = s.generateSecretKey()
-
-	if err := s.DB.Create(app).Error; err != nil {
-		return nil, fmt.Errorf("failed to create application: %w", err)
+	// 创建应用及其首个密钥，明文只通过返回值的 SecretKey 展示一次（不落库）
+	var raw string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(app).Error; err != nil {
+			return fmt.Errorf("failed to create application: %w", err)
+		}
+		var err error
+		raw, _, err = s.createSecret(tx, app.ID, "default", 0, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	app.SecretKey = raw
 	return app, nil
 }
 
@@ -97,9 +105,7 @@ func (s *ApplicationService) UpdateApplication(id, name, code, description strin
 
 // DeleteApplication 删除应用
 func (s *ApplicationService) DeleteApplication(id string) error {
-	fmt.Printf("DeleteApplication called with ID: %s\n", id)
 	appID := s.parseID(id)
-	fmt.Printf("Parsed ID: %d\n", appID)
 	if appID == 0 {
 		return fmt.Errorf("invalid application ID: %s", id)
 	}
@@ -113,8 +119,6 @@ func (s *ApplicationService) DeleteApplication(id string) error {
 		return err
 	}
 
-	fmt.Printf("Application found: %+v\n", app)
-
 	// 开启事务进行级联删除
 	return s.DB.Transaction(func(tx *gorm.DB) error {
 		// 1. 删除关联表数据 (User-Role, Role-Menu)
@@ -159,13 +163,16 @@ func (s *ApplicationService) DeleteApplication(id string) error {
 			return fmt.Errorf("failed to delete audit logs: %w", err)
 		}
 
+		// 删除刷新令牌
+		if err := tx.Unscoped().Where("app_id = ?", appID).Delete(&model.RefreshToken{}).Error; err != nil {
+			return fmt.Errorf("failed to delete refresh tokens: %w", err)
+		}
+
 		// 3. 最后删除应用本身
 		result := tx.Unscoped().Delete(&model.Application{}, appID)
 		if result.Error != nil {
 			return fmt.Errorf("failed to delete application: %w", result.Error)
 		}
-		
-		fmt.Printf("Delete result: %d rows affected\n", result.RowsAffected)
 		return nil
 	})
 }
@@ -253,19 +260,112 @@ func (s *ApplicationService) ListApplications() ([]*model.Application, error) {
 	return apps, nil
 }
 
-// ResetSecretKey 重置应用密钥
-func (s *ApplicationService) ResetSecretKey(id uint) (string, error) {
-	app, err := s.GetApplicationByID(fmt.Sprintf("%d", id))
+// ResetSecretKey 重置应用密钥：立即吊销全部现有密钥并生成一个新密钥（用于密钥泄露等紧急情况，平滑轮换请使用 CreateSecret）
+func (s *ApplicationService) ResetSecretKey(id uint, createdBy uint) (string, *model.AppSecret, error) {
+	if _, err := s.GetApplicationByID(fmt.Sprintf("%d", id)); err != nil {
+		return "", nil, fmt.Errorf("failed to get application: %w", err)
+	}
+
+	var raw string
+	var secret *model.AppSecret
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.AppSecret{}).Where("app_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to revoke secrets: %w", err)
+		}
+		var err error
+		raw, secret, err = s.createSecret(tx, id, "reset", 0, createdBy)
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get application: %w", err)
+		return "", nil, err
+	}
+
+	return raw, secret, nil
+}
+
+// UpdateRedirectURIs 更新应用登记的授权回调地址
+func (s *ApplicationService) UpdateRedirectURIs(id uint, redirectURIs []string) (*model.Application, error) {
+	uris := make([]string, 0, len(redirectURIs))
+	seen := make(map[string]bool)
+	for _, uri := range redirectURIs {
+		uri = strings.TrimSpace(uri)
+		if uri == "" || seen[uri] {
+			continue
+		}
+		if err := ValidateRedirectURI(uri); err != nil {
+			return nil, err
+		}
+		seen[uri] = true
+		uris = append(uris, uri)
 	}
 
-	newSecretKey := s.generateSecretKey()
-	app.SecretKey = newSecretKey
+	if err := s.DB.Model(&model.Application{}).Where("id = ?", id).Select("redirect_uris").Updates(&model.Application{RedirectURIs: uris}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update redirect uris: %w", err)
+	}
+
+	return s.GetApplicationByIDWithoutSecret(fmt.Sprintf("%d", id))
+}
+
+// UpdateMFAPolicy 更新应用的两步验证策略
+func (s *ApplicationService) UpdateMFAPolicy(id uint, policy string) (*model.Application, error) {
+	switch policy {
+	case model.MFAPolicyOff, model.MFAPolicyAll, model.MFAPolicySuperAdmin:
+	default:
+		return nil, fmt.Errorf("invalid mfa policy: %s", policy)
+	}
+
+	if err := s.DB.Model(&model.Application{}).Where("id = ?", id).Update("mfa_policy", policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to update mfa policy: %w", err)
+	}
+
+	return s.GetApplicationByIDWithoutSecret(fmt.Sprintf("%d", id))
+}
+
+// UpdateLoginPolicy 更新应用的登录防暴力破解策略（失败次数为 0 表示该维度不限制）
+func (s *ApplicationService) UpdateLoginPolicy(id uint, maxFailures, ipMaxFailures, lockoutSeconds int) (*model.Application, error) {
+	if maxFailures < 0 || ipMaxFailures < 0 {
+		return nil, fmt.Errorf("max failures must not be negative")
+	}
+	if lockoutSeconds <= 0 {
+		return nil, fmt.Errorf("lockout seconds must be positive")
+	}
+
+	if err := s.DB.Model(&model.Application{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"login_max_failures":    maxFailures,
+		"login_ip_max_failures": ipMaxFailures,
+		"login_lockout_seconds": lockoutSeconds,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update login policy: %w", err)
+	}
+
+	return s.GetApplicationByIDWithoutSecret(fmt.Sprintf("%d", id))
+}
+
+// UpdatePasswordPolicy 更新应用的密码策略
+func (s *ApplicationService) UpdatePasswordPolicy(id uint, policy *model.Application) (*model.Application, error) {
+	if policy.PasswordMinLength < 1 || policy.PasswordMinLength > 72 {
+		return nil, fmt.Errorf("password min length must be between 1 and 72")
+	}
+	if policy.PasswordMinClasses < 0 || policy.PasswordMinClasses > 4 {
+		return nil, fmt.Errorf("password min classes must be between 0 and 4")
+	}
+	if policy.PasswordHistory < 0 || policy.PasswordHistory > passwordHistoryLimit {
+		return nil, fmt.Errorf("password history must be between 0 and %d", passwordHistoryLimit)
+	}
+	if policy.PasswordMaxAgeDays < 0 {
+		return nil, fmt.Errorf("password max age must not be negative")
+	}
 
-	if err := s.DB.Save(app).Error; err != nil {
-		return "", fmt.Errorf("failed to update secret key: %w", err)
+	if err := s.DB.Model(&model.Application{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password_min_length":   policy.PasswordMinLength,
+		"password_min_classes":  policy.PasswordMinClasses,
+		"password_check_common": policy.PasswordCheckCommon,
+		"password_history":      policy.PasswordHistory,
+		"password_max_age_days": policy.PasswordMaxAgeDays,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update password policy: %w", err)
 	}
 
-	return newSecretKey, nil
+	return s.GetApplicationByIDWithoutSecret(fmt.Sprintf("%d", id))
 }
@@ -18,7 +18,14 @@ func NewAuditLogService(db *gorm.DB) *AuditLogService {
 
 // Record 记录审计日志
 func (s *AuditLogService) Record(log *model.AuditLog) {
-	s.DB.Create(log)
+	// Status 列默认值为 1，GORM 创建时会以默认值替换零值，失败日志需要在创建后显式写回
+	failed := log.Status == 0
+	if err := s.DB.Create(log).Error; err != nil {
+		return
+	}
+	if failed {
+		s.DB.Model(log).Update("status", 0)
+	}
 }
 
 // ListAuditLogs 列出审计日志
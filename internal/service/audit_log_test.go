@@ -0,0 +1,23 @@
+package service
+
+import (
+	"testing"
+
+	"Authos/internal/model"
+)
+
+func TestRecordKeepsFailedStatus(t *testing.T) {
+	db := newTestDB(t)
+	auditLogService := NewAuditLogService(db)
+
+	auditLogService.Record(&model.AuditLog{Action: "LOGIN", Resource: "USER", Status: 0, ErrorMsg: "password mismatch"})
+	auditLogService.Record(&model.AuditLog{Action: "LOGIN", Resource: "USER", Status: 1})
+
+	var logs []model.AuditLog
+	if err := db.Order("id").Find(&logs).Error; err != nil {
+		t.Fatalf("failed to list audit logs: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Status != 0 || logs[1].Status != 1 {
+		t.Fatalf("unexpected audit log statuses: %+v", logs)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+var (
+	// ErrInvalidAuthorizationCode 授权码不存在、已过期、已使用或与请求参数不匹配
+	ErrInvalidAuthorizationCode = errors.New("invalid authorization code")
+	// ErrInvalidCodeVerifier PKCE code_verifier 校验失败
+	ErrInvalidCodeVerifier = errors.New("invalid code verifier")
+)
+
+// AuthorizationCodeService 授权码服务
+type AuthorizationCodeService struct {
+	DB         *gorm.DB
+	ExpireTime time.Duration
+}
+
+// NewAuthorizationCodeService 创建授权码服务实例
+func NewAuthorizationCodeService(db *gorm.DB, expireTime time.Duration) *AuthorizationCodeService {
+	return &AuthorizationCodeService{
+		DB:         db,
+		ExpireTime: expireTime,
+	}
+}
+
+// IsRedirectURIRegistered 判断回调地址是否已在应用中登记（精确匹配）
+func IsRedirectURIRegistered(app *model.Application, redirectURI string) bool {
+	for _, registered := range app.RedirectURIs {
+		if registered == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRedirectURI 校验回调地址格式：必须是不带 fragment 的绝对地址
+func ValidateRedirectURI(redirectURI string) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid redirect uri: %s", redirectURI)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect uri must not contain a fragment: %s", redirectURI)
+	}
+	return nil
+}
+
+// CreateCode 为通过登录校验的用户签发一次性授权码
+func (s *AuthorizationCodeService) CreateCode(appID, userID uint, redirectURI, scope, nonce, codeChallenge, codeChallengeMethod string) (string, error) {
+	if codeChallengeMethod != model.CodeChallengeMethodS256 {
+		return "", fmt.Errorf("unsupported code_challenge_method: %s", codeChallengeMethod)
+	}
+	if codeChallenge == "" {
+		return "", errors.New("code_challenge is required")
+	}
+
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate authorization code: %w", err)
+	}
+
+	code := &model.AuthorizationCode{
+		CodeHash:            hashRefreshToken(raw),
+		AppID:               appID,
+		UserID:              userID,
+		RedirectURI:         redirectURI,
+		Scope:               scope,
+		Nonce:               nonce,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		ExpiresAt:           time.Now().Add(s.ExpireTime),
+	}
+	if err := s.DB.Create(code).Error; err != nil {
+		return "", fmt.Errorf("failed to save authorization code: %w", err)
+	}
+
+	return raw, nil
+}
+
+// ExchangeCode 兑换授权码：校验所属应用、回调地址、有效期与 PKCE，成功后授权码立即失效
+func (s *AuthorizationCodeService) ExchangeCode(raw string, appID uint, redirectURI, codeVerifier string) (*model.AuthorizationCode, error) {
+	var code model.AuthorizationCode
+	if err := s.DB.Where("code_hash = ?", hashRefreshToken(raw)).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAuthorizationCode
+		}
+		return nil, err
+	}
+
+	if code.AppID != appID || code.RedirectURI != redirectURI || code.UsedAt != nil {
+		return nil, ErrInvalidAuthorizationCode
+	}
+
+	if time.Now().After(code.ExpiresAt) {
+		return nil, ErrInvalidAuthorizationCode
+	}
+
+	if !VerifyCodeChallenge(code.CodeChallenge, codeVerifier) {
+		return nil, ErrInvalidCodeVerifier
+	}
+
+	// 条件更新，保证授权码只能兑换一次
+	now := time.Now()
+	result := s.DB.Model(&model.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", code.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidAuthorizationCode
+	}
+	code.UsedAt = &now
+
+	return &code, nil
+}
+
+// VerifyCodeChallenge 校验 PKCE：BASE64URL(SHA256(code_verifier)) == code_challenge
+func VerifyCodeChallenge(codeChallenge, codeVerifier string) bool {
+	// RFC 7636 4.1: code_verifier 长度 43-128
+	if len(codeVerifier) < 43 || len(codeVerifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(codeVerifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
+
+// CleanupExpired 清理过期的授权码
+func (s *AuthorizationCodeService) CleanupExpired() error {
+	return s.DB.Where("expires_at < ?", time.Now()).Delete(&model.AuthorizationCode{}).Error
+}
+
+// HasScope 判断以空格分隔的 scope 中是否包含指定值
+func HasScope(scope, value string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
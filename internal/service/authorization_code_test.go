@@ -0,0 +1,74 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestAuthorizationCodeExchangeWithPKCE(t *testing.T) {
+	db := newTestDB(t)
+	applicationService := NewApplicationService(db)
+	authorizationCodeService := NewAuthorizationCodeService(db, time.Minute)
+
+	app, err := applicationService.CreateApplication("Galaxy", "galaxy", "")
+	if err != nil {
+		t.Fatalf("failed to create application: %v", err)
+	}
+
+	redirectURI := "https://galaxy.example.com/callback"
+	if _, err := applicationService.UpdateRedirectURIs(app.ID, []string{"https://galaxy.example.com/#frag"}); err == nil {
+		t.Fatalf("expected redirect uri with fragment to be rejected")
+	}
+	app, err = applicationService.UpdateRedirectURIs(app.ID, []string{redirectURI, redirectURI})
+	if err != nil {
+		t.Fatalf("failed to update redirect uris: %v", err)
+	}
+	if !IsRedirectURIRegistered(app, redirectURI) || len(app.RedirectURIs) != 1 {
+		t.Fatalf("unexpected redirect uris: %v", app.RedirectURIs)
+	}
+	if IsRedirectURIRegistered(app, redirectURI+"/evil") {
+		t.Fatalf("redirect uri must match exactly")
+	}
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if _, err := authorizationCodeService.CreateCode(app.ID, 1, redirectURI, "", "", challenge, "plain"); err == nil {
+		t.Fatalf("expected plain code_challenge_method to be rejected")
+	}
+
+	code, err := authorizationCodeService.CreateCode(app.ID, 1, redirectURI, "openid", "n-0S6_WzA2Mj", challenge, model.CodeChallengeMethodS256)
+	if err != nil {
+		t.Fatalf("failed to create authorization code: %v", err)
+	}
+
+	// 错误的 code_verifier、回调地址或应用都无法兑换
+	if _, err := authorizationCodeService.ExchangeCode(code, app.ID, redirectURI, verifier[:43-1]+"x"); !errors.Is(err, ErrInvalidCodeVerifier) {
+		t.Fatalf("expected ErrInvalidCodeVerifier, got %v", err)
+	}
+	if _, err := authorizationCodeService.ExchangeCode(code, app.ID, "https://evil.example.com/callback", verifier); !errors.Is(err, ErrInvalidAuthorizationCode) {
+		t.Fatalf("expected ErrInvalidAuthorizationCode for redirect mismatch, got %v", err)
+	}
+	if _, err := authorizationCodeService.ExchangeCode(code, app.ID+1, redirectURI, verifier); !errors.Is(err, ErrInvalidAuthorizationCode) {
+		t.Fatalf("expected ErrInvalidAuthorizationCode for foreign app, got %v", err)
+	}
+
+	authCode, err := authorizationCodeService.ExchangeCode(code, app.ID, redirectURI, verifier)
+	if err != nil {
+		t.Fatalf("failed to exchange authorization code: %v", err)
+	}
+	if authCode.UserID != 1 || authCode.Scope != "openid" || authCode.Nonce != "n-0S6_WzA2Mj" {
+		t.Fatalf("unexpected authorization code: %+v", authCode)
+	}
+
+	// 授权码只能兑换一次
+	if _, err := authorizationCodeService.ExchangeCode(code, app.ID, redirectURI, verifier); !errors.Is(err, ErrInvalidAuthorizationCode) {
+		t.Fatalf("expected ErrInvalidAuthorizationCode on reuse, got %v", err)
+	}
+}
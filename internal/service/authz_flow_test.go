@@ -48,6 +48,23 @@ func newTestDB(t *testing.T) *gorm.DB {
 		&model.Menu{},
 		&model.ApiPermission{},
 		&model.AuditLog{},
+		&model.RefreshToken{},
+		&model.AuthorizationCode{},
+		&model.MFARecoveryCode{},
+		&model.MFAChallenge{},
+		&model.LoginFailure{},
+		&model.PasswordHistory{},
+		&model.PasswordResetToken{},
+		&model.APIKey{},
+		&model.AppSecret{},
+		&model.RequestNonce{},
+		&model.AppClientCert{},
+		&model.RevokedToken{},
+		&model.TokenRevocation{},
+		&model.SigningKey{},
+		&model.RoleDataScope{},
+		&model.Department{},
+		&model.UserGroup{},
 	); err != nil {
 		t.Fatalf("failed to migrate test db: %v", err)
 	}
@@ -113,7 +130,7 @@ func TestPermissionBindingAndCheckByKey(t *testing.T) {
 		t.Fatalf("failed to add permission to role: %v", err)
 	}
 
-	allowed, err := casbinService.CheckPermission(user.ID, "user:create", model.HTTP_ALL)
+	allowed, err := casbinService.CheckPermission(user.ID, "user:create", model.HTTP_ALL, nil)
 	if err != nil {
 		t.Fatalf("check permission error: %v", err)
 	}
@@ -125,7 +142,7 @@ func TestPermissionBindingAndCheckByKey(t *testing.T) {
 		t.Fatalf("failed to remove permission from role: %v", err)
 	}
 
-	allowed, err = casbinService.CheckPermission(user.ID, "user:create", model.HTTP_ALL)
+	allowed, err = casbinService.CheckPermission(user.ID, "user:create", model.HTTP_ALL, nil)
 	if err != nil {
 		t.Fatalf("check permission error after remove: %v", err)
 	}
@@ -133,3 +150,60 @@ func TestPermissionBindingAndCheckByKey(t *testing.T) {
 		t.Fatalf("expected permission denied for user:create after remove")
 	}
 }
+
+func TestCheckPermissionsBatch(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+
+	app := &model.Application{Name: "batch-app", Code: "batch-app", Status: 1}
+	db.Create(app)
+	reader := &model.Role{Name: "reader", AppID: app.ID}
+	writer := &model.Role{Name: "writer", AppID: app.ID}
+	db.Create(reader)
+	db.Create(writer)
+	user := &model.User{Username: "batch-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append([]*model.Role{reader, writer})
+
+	for _, binding := range []struct {
+		key  string
+		role *model.Role
+	}{
+		{"order.read", reader},
+		{"order.write", writer},
+		{"order.delete", nil},
+	} {
+		permission, err := apiPermissionService.CreateApiPermission(app.ID, binding.key, binding.key, "/api/"+binding.key, model.HTTP_ALL, "")
+		if err != nil {
+			t.Fatalf("failed to create api permission: %v", err)
+		}
+		if binding.role != nil {
+			if err := apiPermissionService.AddApiPermissionToRole(app.ID, binding.role.UUID, permission.UUID); err != nil {
+				t.Fatalf("failed to add permission to role: %v", err)
+			}
+		}
+	}
+
+	// 结果顺序与请求一致，任一角色拥有即放行
+	results, err := casbinService.CheckPermissions(user.ID, []PermissionRequest{
+		{Obj: "order.delete", Act: model.HTTP_ALL},
+		{Obj: "order.read", Act: "GET"},
+		{Obj: "order.write", Act: model.HTTP_ALL},
+	}, nil)
+	if err != nil {
+		t.Fatalf("check permissions error: %v", err)
+	}
+	if results[0] || !results[1] || !results[2] {
+		t.Fatalf("unexpected batch results: %v", results)
+	}
+
+	existing, err := apiPermissionService.GetExistingPermissionKeys(app.ID, []string{"order.read", "order.unknown"})
+	if err != nil || !existing["order.read"] || existing["order.unknown"] {
+		t.Fatalf("unexpected existing keys: %v, err=%v", existing, err)
+	}
+}
@@ -24,12 +24,23 @@ func NewCasbinService(db *gorm.DB) (*CasbinService, error) {
 		return nil, fmt.Errorf("failed to create casbin adapter: %w", err)
 	}
 
+	// 兼容旧版本写入的策略：补全策略效果为 allow、条件为 true
+	if err := db.Table("casbin_rule").Where("ptype = ? AND (v3 = '' OR v3 IS NULL)", "p").
+		Update("v3", model.EFFECT_ALLOW).Error; err != nil {
+		return nil, fmt.Errorf("failed to migrate casbin policy effect: %w", err)
+	}
+	if err := db.Table("casbin_rule").Where("ptype = ? AND (v4 = '' OR v4 IS NULL)", "p").
+		Update("v4", model.CONDITION_ALWAYS).Error; err != nil {
+		return nil, fmt.Errorf("failed to migrate casbin policy condition: %w", err)
+	}
+
 	// 优先在根目录查找 model.conf
 	modelPath := "model.conf"
 	enforcer, err := casbin.NewEnforcer(modelPath, adapter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create casbin enforcer:%s %w", modelPath, err)
 	}
+	enforcer.AddFunction("conditionMatch", conditionMatch)
 
 	// 加载策略
 	if err := enforcer.LoadPolicy(); err != nil {
@@ -50,30 +61,242 @@ type CheckPermissionReq struct {
 }
 
 // CheckPermission 检查用户是否具有指定资源的操作权限。
-// 若用户持有超级管理员角色则直接放行，否则交由 Casbin 策略判断。
-func (s *CasbinService) CheckPermission(userId uint, obj, act string) (bool, error) {
-	var user model.User
-	if err := s.DB.Preload("Roles").First(&user, userId).Error; err != nil {
+// 若用户持有超级管理员角色则直接放行（拒绝策略对超级管理员不生效），否则交由 Casbin 策略判断：
+// 任一角色命中拒绝策略即拒绝，否则任一角色命中允许策略即放行。
+// 附加了条件的策略只在条件对 ctx 成立时生效，ctx 可为 nil。
+// 用户的角色包括直接持有的角色与所在用户组、部门被授予的角色。
+func (s *CasbinService) CheckPermission(userId uint, obj, act string, ctx *PermissionContext) (bool, error) {
+	results, err := s.CheckPermissions(userId, []PermissionRequest{{Obj: obj, Act: act}}, ctx)
+	if err != nil {
 		return false, err
 	}
+	return results[0], nil
+}
 
-	for _, role := range user.Roles {
-		// 超级管理员角色直接放行，无需经过 Casbin 策略
+// PermissionRequest 一次权限检查的对象（权限标识）与操作
+type PermissionRequest struct {
+	Obj string
+	Act string
+}
+
+// CheckPermissions 批量检查用户权限，用户角色只加载一次，结果顺序与 requests 一致
+func (s *CasbinService) CheckPermissions(userId uint, requests []PermissionRequest, ctx *PermissionContext) ([]bool, error) {
+	results, _, _, err := s.checkPermissions(userId, requests, ctx)
+	return results, err
+}
+
+// checkPermissions 批量检查用户权限，同时返回每项请求中命中允许策略的直接角色；
+// superAdmin 为 true 时全部放行，grantedBy 为空
+func (s *CasbinService) checkPermissions(userId uint, requests []PermissionRequest, ctx *PermissionContext) (results []bool, grantedBy [][]*model.Role, superAdmin bool, err error) {
+	var user model.User
+	if err := s.DB.First(&user, userId).Error; err != nil {
+		return nil, nil, false, err
+	}
+	if err := loadEffectiveRoles(s.DB, &user); err != nil {
+		return nil, nil, false, err
+	}
+
+	// 超级管理员角色（包括沿继承链继承的）直接放行，无需经过 Casbin 策略
+	roles, err := s.expandRoles(user.Roles)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	results = make([]bool, len(requests))
+	for _, role := range roles {
 		if role.IsSuperAdmin {
-			return true, nil
+			for i := range results {
+				results[i] = true
+			}
+			return results, nil, true, nil
 		}
+	}
 
-		roleKey := fmt.Sprintf("role:%s", role.UUID)
-		allowed, err := s.Enforcer.Enforce(roleKey, obj, act)
+	// 匹配器中的 g(r.sub, p.sub) 沿继承链解析父角色的策略；拒绝优先，需检查全部角色
+	denied := make([]bool, len(requests))
+	grantedBy = make([][]*model.Role, len(requests))
+	env := newConditionEnv(&user, ctx)
+	for _, role := range user.Roles {
+		roleKey := roleSubject(role.UUID)
+		for i, req := range requests {
+			if denied[i] {
+				continue
+			}
+			allowed, policy, err := s.Enforcer.EnforceEx(roleKey, req.Obj, req.Act, env)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if isDenyPolicy(policy) {
+				denied[i], results[i], grantedBy[i] = true, false, nil
+				continue
+			}
+			if allowed {
+				results[i] = true
+				grantedBy[i] = append(grantedBy[i], role)
+			}
+		}
+	}
+
+	return results, grantedBy, false, nil
+}
+
+// RoleDecision 单个角色的权限检查结果
+type RoleDecision struct {
+	RoleID     uint     `json:"roleId"`
+	RoleUUID   string   `json:"roleUuid"`
+	RoleName   string   `json:"roleName"`
+	SuperAdmin bool     `json:"superAdmin"`
+	Allowed    bool     `json:"allowed"`
+	Denied     bool     `json:"denied,omitempty"` // 命中拒绝策略
+	Policy     []string `json:"policy,omitempty"` // 命中的策略 [sub, obj, act, eft, cond]
+	Reason     string   `json:"reason"`
+}
+
+// PermissionExplanation 权限检查过程的解释
+type PermissionExplanation struct {
+	UserID  uint           `json:"userId"`
+	Obj     string         `json:"obj"`
+	Act     string         `json:"act"`
+	Allowed bool           `json:"allowed"`
+	Roles   []RoleDecision `json:"roles"`  // 逐个检查的用户角色
+	Reason  string         `json:"reason"` // 放行或拒绝的原因
+}
+
+// ExplainPermission 与 CheckPermission 判定一致，同时返回逐个角色的检查结果、放行的策略或拒绝原因
+func (s *CasbinService) ExplainPermission(userId uint, obj, act string, ctx *PermissionContext) (*PermissionExplanation, error) {
+	var user model.User
+	if err := s.DB.First(&user, userId).Error; err != nil {
+		return nil, err
+	}
+	if err := loadEffectiveRoles(s.DB, &user); err != nil {
+		return nil, err
+	}
+
+	explanation := &PermissionExplanation{UserID: userId, Obj: obj, Act: act, Roles: []RoleDecision{}}
+	if len(user.Roles) == 0 {
+		explanation.Reason = "user has no roles"
+		return explanation, nil
+	}
+
+	// 角色名称（含祖先角色），用于说明继承来的策略
+	expanded, err := s.expandRoles(user.Roles)
+	if err != nil {
+		return nil, err
+	}
+	env := newConditionEnv(&user, ctx)
+	roleNames := make(map[string]string, len(expanded))
+	for _, role := range expanded {
+		roleNames[roleSubject(role.UUID)] = role.Name
+	}
+
+	// 超级管理员 > 拒绝策略 > 允许策略
+	var superReason, denyReason, allowReason string
+	for _, role := range user.Roles {
+		roleKey := roleSubject(role.UUID)
+		decision := RoleDecision{RoleID: role.ID, RoleUUID: role.UUID, RoleName: role.Name, SuperAdmin: role.IsSuperAdmin}
+
+		ancestors, err := s.GetRoleAncestors(role)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
-		if allowed {
-			return true, nil
+		var superAncestor *model.Role
+		for _, ancestor := range ancestors {
+			if ancestor.IsSuperAdmin {
+				superAncestor = ancestor
+				break
+			}
+		}
+
+		switch {
+		case role.IsSuperAdmin:
+			decision.Allowed = true
+			decision.Reason = "super admin role bypasses policies"
+		case superAncestor != nil:
+			decision.Allowed = true
+			decision.Reason = fmt.Sprintf("inherits super admin role %s", superAncestor.Name)
+		default:
+			allowed, policy, err := s.Enforcer.EnforceEx(roleKey, obj, act, env)
+			if err != nil {
+				return nil, err
+			}
+			decision.Allowed, decision.Policy = allowed, policy
+			if isDenyPolicy(policy) {
+				decision.Denied = true
+				decision.Reason = "denied by policy"
+				if policy[0] != roleKey {
+					decision.Reason = fmt.Sprintf("denied by policy inherited from role %s", roleNames[policy[0]])
+				}
+				if isConditionalPolicy(policy) {
+					decision.Reason += fmt.Sprintf(" with condition %s", policy[4])
+				}
+			} else if allowed {
+				decision.Reason = "granted by policy"
+				if len(policy) > 0 && policy[0] != roleKey {
+					decision.Reason = fmt.Sprintf("granted by policy inherited from role %s", roleNames[policy[0]])
+				}
+				if isConditionalPolicy(policy) {
+					decision.Reason += fmt.Sprintf(" with condition %s", policy[4])
+				}
+			} else {
+				// 角色自身及继承的全部策略
+				policies, err := s.Enforcer.GetImplicitPermissionsForUser(roleKey)
+				if err != nil {
+					return nil, err
+				}
+				var acts, conditions []string
+				for _, policy := range policies {
+					if len(policy) < 3 || policy[1] != obj || isDenyPolicy(policy) {
+						continue
+					}
+					if policy[2] == act || policy[2] == model.HTTP_ALL {
+						if isConditionalPolicy(policy) {
+							conditions = append(conditions, policy[4])
+						}
+						continue
+					}
+					acts = append(acts, policy[2])
+				}
+				if len(conditions) > 0 {
+					decision.Reason = fmt.Sprintf("role is granted %s only when %v", obj, conditions)
+				} else if len(acts) > 0 {
+					decision.Reason = fmt.Sprintf("role is granted %s only for methods %v", obj, acts)
+				} else {
+					decision.Reason = fmt.Sprintf("role has no policy for %s, including inherited roles", obj)
+				}
+			}
+		}
+
+		explanation.Roles = append(explanation.Roles, decision)
+		reason := fmt.Sprintf("allowed by role %s: %s", role.Name, decision.Reason)
+		switch {
+		case decision.Allowed && (role.IsSuperAdmin || superAncestor != nil) && superReason == "":
+			superReason = reason
+		case decision.Denied && denyReason == "":
+			denyReason = fmt.Sprintf("denied by role %s: %s", role.Name, decision.Reason)
+		case decision.Allowed && allowReason == "":
+			allowReason = reason
 		}
 	}
 
-	return false, nil
+	switch {
+	case superReason != "":
+		explanation.Allowed, explanation.Reason = true, superReason
+	case denyReason != "":
+		explanation.Reason = denyReason
+	case allowReason != "":
+		explanation.Allowed, explanation.Reason = true, allowReason
+	default:
+		policies, err := s.Enforcer.GetFilteredPolicy(1, obj, "", model.EFFECT_ALLOW)
+		if err != nil {
+			return nil, err
+		}
+		if len(policies) == 0 {
+			explanation.Reason = fmt.Sprintf("no role has any policy for %s", obj)
+		} else {
+			explanation.Reason = fmt.Sprintf("none of the user's roles is granted %s %s", obj, act)
+		}
+	}
+
+	return explanation, nil
 }
 
 // LoadPolicy 重新加载策略
@@ -81,18 +304,28 @@ func (s *CasbinService) LoadPolicy() error {
 	return s.Enforcer.LoadPolicy()
 }
 
-// AddPolicy 添加策略
+// AddPolicy 添加无条件的允许策略
 func (s *CasbinService) AddPolicy(sub, obj, act string) error {
-	_, err := s.Enforcer.AddPolicy(sub, obj, act)
+	return s.AddPolicyRule(sub, obj, act, model.EFFECT_ALLOW, model.CONDITION_ALWAYS)
+}
+
+// AddPolicyRule 添加指定效果（allow/deny）与条件的策略
+func (s *CasbinService) AddPolicyRule(sub, obj, act, eft, cond string) error {
+	_, err := s.Enforcer.AddPolicy(sub, obj, act, eft, cond)
 	return err
 }
 
-// RemovePolicy 删除策略
+// RemovePolicy 删除策略（包括允许与拒绝）
 func (s *CasbinService) RemovePolicy(sub, obj, act string) error {
-	_, err := s.Enforcer.RemovePolicy(sub, obj, act)
+	_, err := s.Enforcer.RemoveFilteredPolicy(0, sub, obj, act)
 	return err
 }
 
+// isDenyPolicy 判断命中的策略是否为拒绝策略
+func isDenyPolicy(policy []string) bool {
+	return len(policy) > 3 && policy[3] == model.EFFECT_DENY
+}
+
 // RemoveFilteredPolicy 根据过滤条件删除策略
 func (s *CasbinService) RemoveFilteredPolicy(fieldIndex int, fieldValues ...string) error {
 	_, err := s.Enforcer.RemoveFilteredPolicy(fieldIndex, fieldValues...)
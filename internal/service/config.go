@@ -1,6 +1,7 @@
 package service
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
 
@@ -8,13 +9,47 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig `yaml:"server"`
-	Log    LogConfig    `yaml:"log"`
-	System SystemConfig `yaml:"system"`
+	Server   ServerConfig   `yaml:"server"`
+	Log      LogConfig      `yaml:"log"`
+	System   SystemConfig   `yaml:"system"`
+	Token    TokenConfig    `yaml:"token"`
+	Password PasswordConfig `yaml:"password"`
+	SMTP     SMTPConfig     `yaml:"smtp"`
+	Security SecurityConfig `yaml:"security"`
 }
 
 type ServerConfig struct {
-	Port string `yaml:"port"`
+	Port string    `yaml:"port"`
+	TLS  TLSConfig `yaml:"tls"`
+}
+
+type TLSConfig struct {
+	CertFile   string `yaml:"certFile"`   // 服务端证书（PEM），与 keyFile 同时配置时启用 HTTPS
+	KeyFile    string `yaml:"keyFile"`    // 服务端私钥（PEM）
+	ClientAuth bool   `yaml:"clientAuth"` // 是否请求客户端证书（mTLS），证书按各应用登记的指纹或 CA 校验
+}
+
+// Enabled 是否启用 HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// ServerTLSConfig 构造服务端 TLS 配置。
+// 客户端证书只在握手时要求证明持有私钥，不做链校验：受信任的证书因应用而异，由 ApplicationService.VerifyClientCert 校验
+func (c TLSConfig) ServerTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientAuth {
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	}
+	return tlsConfig, nil
 }
 
 type LogConfig struct {
@@ -27,6 +62,29 @@ type SystemConfig struct {
 	AdminPassword string `yaml:"adminPassword"`
 }
 
+type TokenConfig struct {
+	Algorithm string `yaml:"algorithm"` // 签名算法: RS256 / ES256 / HS256
+	Secret    string `yaml:"secret"`    // HS256 共享密钥（仅 algorithm 为 HS256 时使用）
+	Issuer    string `yaml:"issuer"`    // OIDC 签发者地址（对外访问地址），为空时按请求地址推导
+}
+
+type PasswordConfig struct {
+	DictionaryFile string `yaml:"dictionaryFile"` // 追加的弱密码 / 泄露密码字典文件（每行一个），为空时只使用内置字典
+	ResetURL       string `yaml:"resetUrl"`       // 重置密码页面地址，重置令牌以 token 参数附加；为空时只返回令牌
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`     // SMTP 服务器地址，为空时不发送邮件
+	Port     int    `yaml:"port"`     // SMTP 端口
+	Username string `yaml:"username"` // 认证用户名，为空时不认证（如本地测试邮件服务）
+	Password string `yaml:"password"` // 认证密码
+	From     string `yaml:"from"`     // 发件人地址
+}
+
+type SecurityConfig struct {
+	EncryptionKeyFile string `yaml:"encryptionKeyFile"` // 服务端加密密钥文件（请求签名密钥、TOTP 密钥），不存在时自动生成；多实例部署须使用同一文件
+}
+
 // LoadConfig 加载配置文件
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -43,6 +101,12 @@ func LoadConfig(path string) (*Config, error) {
 	if config.Server.Port == "" {
 		config.Server.Port = "8080"
 	}
+	if (config.Server.TLS.CertFile == "") != (config.Server.TLS.KeyFile == "") {
+		return nil, fmt.Errorf("server.tls.certFile and server.tls.keyFile must be configured together")
+	}
+	if config.Server.TLS.ClientAuth && !config.Server.TLS.Enabled() {
+		return nil, fmt.Errorf("server.tls.clientAuth requires server.tls.certFile and server.tls.keyFile")
+	}
 	if config.Log.Dir == "" {
 		config.Log.Dir = "logs"
 	}
@@ -50,6 +114,19 @@ func LoadConfig(path string) (*Config, error) {
 		config.Log.Filename = "authos.log"
 	}
 
+	if config.Security.EncryptionKeyFile == "" {
+		config.Security.EncryptionKeyFile = "authos.key"
+	}
+
+	if config.SMTP.Host != "" && config.SMTP.Port == 0 {
+		config.SMTP.Port = 25
+	}
+
+	// 默认使用非对称签名，便于租户后端通过 JWKS 离线验证令牌
+	if config.Token.Algorithm == "" {
+		config.Token.Algorithm = AlgorithmRS256
+	}
+
 	// 设置系统管理员默认值
 	if config.System.AdminUsername == "" {
 		config.System.AdminUsername = "admin"
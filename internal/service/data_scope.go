@@ -0,0 +1,239 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+// ErrInvalidDataScope 数据范围不合法
+var ErrInvalidDataScope = errors.New("invalid data scope")
+
+// DataScope 鉴权放行时返回的数据范围，用户多个角色的范围取并集：
+// All 为 true 时不限制；否则租户后端按 Scopes 中任一范围可见的数据过滤，CUSTOM 对应 CustomDeptIDs。
+// DeptIDs 为 DEPT、DEPT_AND_CHILDREN 与 CUSTOM 解析出的全部可见部门ID
+type DataScope struct {
+	All           bool     `json:"all"`
+	Scopes        []string `json:"scopes"`
+	CustomDeptIDs []uint   `json:"customDeptIds,omitempty"`
+	DeptIDs       []uint   `json:"deptIds,omitempty"`
+}
+
+// normalizeDataScope 校验数据范围，CUSTOM 必须指定部门，其他范围忽略部门
+func normalizeDataScope(scope string, deptIDs []uint) (string, []uint, error) {
+	scope = strings.ToUpper(strings.TrimSpace(scope))
+	if !slices.Contains(model.GetAllDataScopes(), scope) {
+		return "", nil, fmt.Errorf("%w: %s", ErrInvalidDataScope, scope)
+	}
+	if scope != model.DATA_SCOPE_CUSTOM {
+		return scope, nil, nil
+	}
+	deptIDs = uniqueUints(deptIDs)
+	if len(deptIDs) == 0 {
+		return "", nil, fmt.Errorf("%w: CUSTOM requires deptIds", ErrInvalidDataScope)
+	}
+	return scope, deptIDs, nil
+}
+
+// MigrateRoleDataScopes 将升级前创建、未设置数据范围的角色回填为 ALL（与升级前不限制数据的行为一致）
+func MigrateRoleDataScopes(db *gorm.DB) (int64, error) {
+	result := db.Unscoped().Model(&model.Role{}).
+		Where("data_scope = '' OR data_scope IS NULL").
+		Update("data_scope", model.DATA_SCOPE_ALL)
+	return result.RowsAffected, result.Error
+}
+
+// checkDataScopeDepartments 校验 CUSTOM 数据范围的部门属于同一应用
+func (s *RoleService) checkDataScopeDepartments(appID uint, deptIDs []uint) error {
+	if len(deptIDs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := s.DB.Model(&model.Department{}).Where("id IN ? AND app_id = ?", deptIDs, appID).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(deptIDs) {
+		return fmt.Errorf("%w: department not found in this application", ErrInvalidDataScope)
+	}
+	return nil
+}
+
+// SetRoleDataScope 设置角色默认的数据范围（按应用隔离）
+func (s *RoleService) SetRoleDataScope(roleID uint, appID uint, scope string, deptIDs []uint) error {
+	role, err := s.GetRoleByID(roleID, appID)
+	if err != nil {
+		return err
+	}
+	scope, deptIDs, err = normalizeDataScope(scope, deptIDs)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDataScopeDepartments(appID, deptIDs); err != nil {
+		return err
+	}
+	role.DataScope, role.DataScopeDeptIDs = scope, deptIDs
+	return s.DB.Model(role).Select("DataScope", "DataScopeDeptIDs").Updates(role).Error
+}
+
+// SetRolePermissionDataScope 设置角色在指定接口权限上的数据范围，覆盖角色默认的数据范围；
+// scope 为空时删除覆盖，恢复使用角色默认的数据范围
+func (s *RoleService) SetRolePermissionDataScope(roleID uint, appID uint, permissionUUID, scope string, deptIDs []uint) error {
+	if _, err := s.GetRoleByID(roleID, appID); err != nil {
+		return err
+	}
+	var permission model.ApiPermission
+	if err := s.DB.Where("uuid = ? AND app_id = ?", permissionUUID, appID).First(&permission).Error; err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(scope) == "" {
+		return s.DB.Unscoped().Where("role_id = ? AND api_permission_id = ?", roleID, permission.ID).
+			Delete(&model.RoleDataScope{}).Error
+	}
+	scope, deptIDs, err := normalizeDataScope(scope, deptIDs)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDataScopeDepartments(appID, deptIDs); err != nil {
+		return err
+	}
+
+	var entry model.RoleDataScope
+	err = s.DB.Where("role_id = ? AND api_permission_id = ?", roleID, permission.ID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.DB.Create(&model.RoleDataScope{
+			AppID:           appID,
+			RoleID:          roleID,
+			ApiPermissionID: permission.ID,
+			Scope:           scope,
+			DeptIDs:         deptIDs,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	entry.Scope, entry.DeptIDs = scope, deptIDs
+	return s.DB.Model(&entry).Select("Scope", "DeptIDs").Updates(&entry).Error
+}
+
+// ListRolePermissionDataScopes 获取角色在各接口权限上单独设置的数据范围（按应用隔离）
+func (s *RoleService) ListRolePermissionDataScopes(roleID uint, appID uint) ([]model.RoleDataScope, error) {
+	if _, err := s.GetRoleByID(roleID, appID); err != nil {
+		return nil, err
+	}
+	var entries []model.RoleDataScope
+	err := s.DB.Preload("ApiPermission").Where("role_id = ? AND app_id = ?", roleID, appID).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// CheckPermissionWithDataScope 与 CheckPermission 判定一致，放行时同时返回数据范围
+func (s *CasbinService) CheckPermissionWithDataScope(userId uint, obj, act string, ctx *PermissionContext) (bool, *DataScope, error) {
+	results, scopes, err := s.CheckPermissionsWithDataScope(userId, []PermissionRequest{{Obj: obj, Act: act}}, ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	return results[0], scopes[0], nil
+}
+
+// CheckPermissionsWithDataScope 与 CheckPermissions 判定一致，同时返回放行项的数据范围（拒绝项为 nil）。
+// 数据范围取放行该请求的角色（直接持有或经用户组、部门获得）在该权限上的范围（未单独设置时取角色默认范围）的并集，超级管理员不限制
+func (s *CasbinService) CheckPermissionsWithDataScope(userId uint, requests []PermissionRequest, ctx *PermissionContext) ([]bool, []*DataScope, error) {
+	results, grantedBy, superAdmin, err := s.checkPermissions(userId, requests, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	scopes := make([]*DataScope, len(requests))
+	for i, req := range requests {
+		if !results[i] {
+			continue
+		}
+		if superAdmin {
+			scopes[i] = &DataScope{All: true, Scopes: []string{model.DATA_SCOPE_ALL}}
+			continue
+		}
+		if scopes[i], err = s.resolveDataScope(userId, grantedBy[i], req.Obj); err != nil {
+			return nil, nil, err
+		}
+	}
+	return results, scopes, nil
+}
+
+// resolveDataScope 合并角色在权限 obj 上的数据范围，并按用户所在部门解析可见部门
+func (s *CasbinService) resolveDataScope(userID uint, roles []*model.Role, obj string) (*DataScope, error) {
+	roleIDs := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	var overrides []model.RoleDataScope
+	if len(roleIDs) > 0 {
+		if err := s.DB.Joins("JOIN api_permissions ON api_permissions.id = role_data_scopes.api_permission_id AND api_permissions.app_id = role_data_scopes.app_id").
+			Where("role_data_scopes.role_id IN ? AND api_permissions.key = ? AND api_permissions.deleted_at IS NULL", roleIDs, obj).
+			Find(&overrides).Error; err != nil {
+			return nil, err
+		}
+	}
+	overrideByRole := make(map[uint]model.RoleDataScope, len(overrides))
+	for _, override := range overrides {
+		overrideByRole[override.RoleID] = override
+	}
+
+	granted := make(map[string]bool)
+	var customDeptIDs []uint
+	for _, role := range roles {
+		scope, deptIDs := role.DataScope, role.DataScopeDeptIDs
+		if override, ok := overrideByRole[role.ID]; ok {
+			scope, deptIDs = override.Scope, override.DeptIDs
+		}
+		// 未识别的数据范围不授予任何数据（升级前的角色由 MigrateRoleDataScopes 回填为 ALL）
+		if scope == model.DATA_SCOPE_ALL {
+			return &DataScope{All: true, Scopes: []string{model.DATA_SCOPE_ALL}}, nil
+		}
+		granted[scope] = true
+		if scope == model.DATA_SCOPE_CUSTOM {
+			customDeptIDs = append(customDeptIDs, deptIDs...)
+		}
+	}
+
+	result := &DataScope{Scopes: []string{}}
+	for _, scope := range model.GetAllDataScopes() {
+		if granted[scope] {
+			result.Scopes = append(result.Scopes, scope)
+		}
+	}
+	result.CustomDeptIDs = sortedUints(customDeptIDs)
+
+	// 本部门（及下级部门）按用户直接所属的部门解析
+	deptIDs := customDeptIDs
+	if granted[model.DATA_SCOPE_DEPT] || granted[model.DATA_SCOPE_DEPT_AND_CHILDREN] {
+		ownDeptIDs, err := userDepartmentIDs(s.DB, userID)
+		if err != nil {
+			return nil, err
+		}
+		deptIDs = append(deptIDs, ownDeptIDs...)
+		if granted[model.DATA_SCOPE_DEPT_AND_CHILDREN] && len(ownDeptIDs) > 0 {
+			var departments []*model.Department
+			if err := s.DB.Where("app_id = ?", roles[0].AppID).Find(&departments).Error; err != nil {
+				return nil, err
+			}
+			deptIDs = append(deptIDs, descendantDepartmentIDs(departments, ownDeptIDs)...)
+		}
+	}
+	result.DeptIDs = sortedUints(deptIDs)
+	return result, nil
+}
+
+// sortedUints 去重并排序，为空时返回 nil
+func sortedUints(values []uint) []uint {
+	values = uniqueUints(values)
+	if len(values) == 0 {
+		return nil
+	}
+	slices.Sort(values)
+	return values
+}
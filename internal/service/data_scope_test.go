@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"Authos/internal/model"
+)
+
+func TestDataScopeResolution(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+	roleService := NewRoleService(db, casbinService)
+
+	app := &model.Application{Name: "scope-app", Code: "scope-app", Status: 1}
+	db.Create(app)
+	sales := &model.Role{Name: "sales", AppID: app.ID}
+	auditor := &model.Role{Name: "auditor", AppID: app.ID}
+	db.Create(sales)
+	db.Create(auditor)
+	if sales.DataScope != model.DATA_SCOPE_ALL {
+		t.Fatalf("expected default data scope ALL, got %q", sales.DataScope)
+	}
+	user := &model.User{Username: "scope-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append(sales)
+
+	orders, err := apiPermissionService.CreateApiPermission(app.ID, "order.list", "订单列表", "/api/orders", model.HTTP_ALL, "")
+	if err != nil {
+		t.Fatalf("failed to create api permission: %v", err)
+	}
+	customers, err := apiPermissionService.CreateApiPermission(app.ID, "customer.list", "客户列表", "/api/customers", model.HTTP_ALL, "")
+	if err != nil {
+		t.Fatalf("failed to create api permission: %v", err)
+	}
+	for _, role := range []*model.Role{sales, auditor} {
+		for _, permission := range []*model.ApiPermission{orders, customers} {
+			if err := apiPermissionService.AddApiPermissionToRole(app.ID, role.UUID, permission.UUID); err != nil {
+				t.Fatalf("failed to add permission to role: %v", err)
+			}
+		}
+	}
+
+	// 角色默认仅本人数据，客户列表单独放宽到本部门及下级
+	if err := roleService.SetRoleDataScope(sales.ID, app.ID, "self", nil); err != nil {
+		t.Fatalf("failed to set role data scope: %v", err)
+	}
+	if err := roleService.SetRolePermissionDataScope(sales.ID, app.ID, customers.UUID, model.DATA_SCOPE_DEPT_AND_CHILDREN, nil); err != nil {
+		t.Fatalf("failed to set permission data scope: %v", err)
+	}
+	if err := roleService.SetRoleDataScope(sales.ID, app.ID, model.DATA_SCOPE_CUSTOM, nil); !errors.Is(err, ErrInvalidDataScope) {
+		t.Fatalf("expected CUSTOM without deptIds rejected, got %v", err)
+	}
+	if err := roleService.SetRoleDataScope(sales.ID, app.ID, "EVERYTHING", nil); !errors.Is(err, ErrInvalidDataScope) {
+		t.Fatalf("expected unknown scope rejected, got %v", err)
+	}
+
+	check := func(obj string, want *DataScope) {
+		t.Helper()
+		allowed, scope, err := casbinService.CheckPermissionWithDataScope(user.ID, obj, "GET", nil)
+		if err != nil || !allowed {
+			t.Fatalf("%s: expected allowed, got %v, err=%v", obj, allowed, err)
+		}
+		if !reflect.DeepEqual(scope, want) {
+			t.Fatalf("%s: expected data scope %+v, got %+v", obj, want, scope)
+		}
+	}
+	check("order.list", &DataScope{Scopes: []string{model.DATA_SCOPE_SELF}})
+	check("customer.list", &DataScope{Scopes: []string{model.DATA_SCOPE_DEPT_AND_CHILDREN}})
+
+	// 多个角色的数据范围取并集
+	east := &model.Department{Name: "east", AppID: app.ID}
+	west := &model.Department{Name: "west", AppID: app.ID}
+	db.Create(east)
+	db.Create(west)
+	if err := roleService.SetRoleDataScope(auditor.ID, app.ID, model.DATA_SCOPE_CUSTOM, []uint{west.ID, east.ID, west.ID}); err != nil {
+		t.Fatalf("failed to set role data scope: %v", err)
+	}
+	if err := roleService.SetRoleDataScope(auditor.ID, app.ID, model.DATA_SCOPE_CUSTOM, []uint{west.ID + 100}); !errors.Is(err, ErrInvalidDataScope) {
+		t.Fatalf("expected unknown department rejected, got %v", err)
+	}
+	db.Model(user).Association("Roles").Append(auditor)
+	deptIDs := []uint{east.ID, west.ID}
+	check("order.list", &DataScope{Scopes: []string{model.DATA_SCOPE_SELF, model.DATA_SCOPE_CUSTOM}, CustomDeptIDs: deptIDs, DeptIDs: deptIDs})
+
+	// 任一角色为全部数据时不限制
+	if err := roleService.SetRolePermissionDataScope(auditor.ID, app.ID, orders.UUID, model.DATA_SCOPE_ALL, nil); err != nil {
+		t.Fatalf("failed to set permission data scope: %v", err)
+	}
+	check("order.list", &DataScope{All: true, Scopes: []string{model.DATA_SCOPE_ALL}})
+
+	// 删除覆盖后恢复角色默认范围
+	if err := roleService.SetRolePermissionDataScope(auditor.ID, app.ID, orders.UUID, "", nil); err != nil {
+		t.Fatalf("failed to delete permission data scope: %v", err)
+	}
+	entries, err := roleService.ListRolePermissionDataScopes(sales.ID, app.ID)
+	if err != nil || len(entries) != 1 || entries[0].ApiPermission == nil || entries[0].ApiPermission.Key != "customer.list" {
+		t.Fatalf("unexpected data scope entries: %+v, err=%v", entries, err)
+	}
+
+	// 拒绝时不返回数据范围
+	allowed, scope, err := casbinService.CheckPermissionWithDataScope(user.ID, "invoice.list", "GET", nil)
+	if err != nil || allowed || scope != nil {
+		t.Fatalf("expected denied without data scope, got %v %+v, err=%v", allowed, scope, err)
+	}
+
+	// 删除接口权限时一并删除其数据范围
+	if err := apiPermissionService.DeleteApiPermission(customers.ID, app.ID); err != nil {
+		t.Fatalf("failed to delete api permission: %v", err)
+	}
+	if entries, _ := roleService.ListRolePermissionDataScopes(sales.ID, app.ID); len(entries) != 0 {
+		t.Fatalf("expected data scopes removed with permission, got %+v", entries)
+	}
+}
+
+func TestMigrateRoleDataScopes(t *testing.T) {
+	db := newTestDB(t)
+
+	legacy := &model.Role{Name: "legacy", AppID: 1}
+	scoped := &model.Role{Name: "scoped", AppID: 1, DataScope: model.DATA_SCOPE_SELF}
+	db.Create(legacy)
+	db.Create(scoped)
+	// 模拟升级前没有数据范围的角色
+	db.Model(legacy).UpdateColumn("data_scope", "")
+
+	backfilled, err := MigrateRoleDataScopes(db)
+	if err != nil || backfilled != 1 {
+		t.Fatalf("unexpected migration result: %d, %v", backfilled, err)
+	}
+	var roles []model.Role
+	db.Order("id").Find(&roles)
+	if roles[0].DataScope != model.DATA_SCOPE_ALL || roles[1].DataScope != model.DATA_SCOPE_SELF {
+		t.Fatalf("expected only the legacy role to be backfilled, got %q and %q", roles[0].DataScope, roles[1].DataScope)
+	}
+
+	if backfilled, err := MigrateRoleDataScopes(db); err != nil || backfilled != 0 {
+		t.Fatalf("expected migration to be idempotent, got %d, %v", backfilled, err)
+	}
+}
@@ -61,6 +61,18 @@ func NewDBService(config *Config) (*DBService, error) {
 		return nil, fmt.Errorf("failed to seed data: %w", err)
 	}
 
+	// 升级前创建的角色没有数据范围，回填为全部数据
+	backfilled, err := MigrateRoleDataScopes(db)
+	if err != nil {
+		if Log != nil {
+			Log.Errorf("failed to migrate role data scopes: %v", err)
+		}
+		return nil, fmt.Errorf("failed to migrate role data scopes: %w", err)
+	}
+	if backfilled > 0 && Log != nil {
+		Log.Infof("Backfilled data scope ALL for %d roles", backfilled)
+	}
+
 	return &DBService{
 		DB: db,
 	}, nil
@@ -76,10 +88,30 @@ func autoMigrate(db *gorm.DB) error {
 		&model.ApiPermission{},
 		&model.ConfigDictionary{},
 		&model.AuditLog{},
+		&model.RefreshToken{},
+		&model.AuthorizationCode{},
+		&model.MFARecoveryCode{},
+		&model.MFAChallenge{},
+		&model.LoginFailure{},
+		&model.PasswordHistory{},
+		&model.PasswordResetToken{},
+		&model.APIKey{},
+		&model.AppSecret{},
+		&model.RequestNonce{},
+		&model.AppClientCert{},
+		&model.RevokedToken{},
+		&model.TokenRevocation{},
+		&model.SigningKey{},
+		&model.RoleDataScope{},
+		&model.Department{},
+		&model.UserGroup{},
 		// CasbinRule 会被 Gorm Adapter 自动迁移
 	)
 }
 
+// SystemAppID 系统默认应用的ID，该应用中拥有超级管理员身份的用户即系统管理员
+const SystemAppID uint = 1
+
 // seedData 初始化种子数据
 func seedData(db *gorm.DB, config *Config) error {
 	// 检查是否已经有数据
@@ -88,16 +120,44 @@ func seedData(db *gorm.DB, config *Config) error {
 	if appCount > 0 {
 		return nil // 已有数据，跳过种子初始化
 	}
+	// 获取管理员账户配置
+	var adminUsername, adminPassword string
+
+	// 优先使用配置文件中的设置
+	if config != nil && config.System.AdminUsername != "" {
+		adminUsername = config.System.AdminUsername
+	} else {
+		adminUsername = "admin"
+	}
+
+	if config != nil && config.System.AdminPassword != "" {
+		adminPassword = config.System.AdminPassword
+	} else {
+		// 如果配置文件未设置密码，则自动生成
+		adminPassword = generateSecurePassword()
+	}
+
 	uuid := uuid.New().String()
-	// 创建默认应用
+	// 创建默认应用（密码策略与模型默认值一致，用于校验初始管理员密码）
 	defaultApp := &model.Application{
-		UUID:        uuid,
-		Name:        "默认应用",
-		Code:        "default",
-		SecretKey:   uuid,
-		Status:      1,
-		Description: "系统默认应用",
+		UUID:                uuid,
+		Name:                "默认应用",
+		Code:                "default",
+		SecretKey:           uuid,
+		Status:              1,
+		Description:         "系统默认应用",
+		PasswordMinLength:   8,
+		PasswordMinClasses:  2,
+		PasswordCheckCommon: true,
+	}
+
+	// 配置文件中的管理员密码必须符合默认应用的密码策略，在写入任何数据前校验，避免留下不完整的种子数据
+	if config != nil && config.System.AdminPassword != "" {
+		if err := CheckPasswordStrength(defaultApp, adminUsername, adminPassword); err != nil {
+			return fmt.Errorf("system.adminPassword does not satisfy the password policy of the default application (%v), change it or leave it empty to generate one", err)
+		}
 	}
+
 	if err := db.Create(defaultApp).Error; err != nil {
 		return err
 	}
@@ -121,23 +181,6 @@ func seedData(db *gorm.DB, config *Config) error {
 		return err
 	}
 
-	// 获取管理员账户配置
-	var adminUsername, adminPassword string
-
-	// 优先使用配置文件中的设置
-	if config != nil && config.System.AdminUsername != "" {
-		adminUsername = config.System.AdminUsername
-	} else {
-		adminUsername = "admin"
-	}
-
-	if config != nil && config.System.AdminPassword != "" {
-		adminPassword = config.System.AdminPassword
-	} else {
-		// 如果配置文件未设置密码，则自动生成
-		adminPassword = generateSecurePassword()
-	}
-
 	// 创建管理员用户
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.DefaultCost)
 	if err != nil {
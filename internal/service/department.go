@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+var (
+	// ErrDepartmentCycle 部门被移动到自身或其下级部门之下
+	ErrDepartmentCycle = errors.New("department cannot be moved under itself or its descendants")
+	// ErrDepartmentHasChildren 部门下仍有子部门
+	ErrDepartmentHasChildren = errors.New("department has child departments")
+)
+
+// DepartmentService 部门服务
+type DepartmentService struct {
+	DB *gorm.DB
+}
+
+// NewDepartmentService 创建部门服务实例
+func NewDepartmentService(db *gorm.DB) *DepartmentService {
+	return &DepartmentService{DB: db}
+}
+
+// validateDepartment 校验父部门与负责人属于同一应用，且父部门不是自身或其下级部门
+func (s *DepartmentService) validateDepartment(dept *model.Department) error {
+	if dept.ParentID != 0 {
+		departments, err := s.ListDepartmentsByApp(dept.AppID)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(departments, func(d *model.Department) bool { return d.ID == dept.ParentID }) {
+			return errors.New("parent department not found in this application")
+		}
+		if dept.ID != 0 && slices.Contains(descendantDepartmentIDs(departments, []uint{dept.ID}), dept.ParentID) {
+			return ErrDepartmentCycle
+		}
+	}
+	if dept.LeaderID != 0 {
+		var count int64
+		if err := s.DB.Model(&model.User{}).Where("id = ? AND app_id = ?", dept.LeaderID, dept.AppID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("leader not found in this application")
+		}
+	}
+	return nil
+}
+
+// CreateDepartment 创建部门（按应用隔离）
+func (s *DepartmentService) CreateDepartment(dept *model.Department) error {
+	dept.ID = 0
+	if err := s.validateDepartment(dept); err != nil {
+		return err
+	}
+	return s.DB.Omit("Users", "Roles").Create(dept).Error
+}
+
+// UpdateDepartment 更新部门（按应用隔离），可移动到其他父部门之下
+func (s *DepartmentService) UpdateDepartment(dept *model.Department) error {
+	if _, err := s.GetDepartmentByID(dept.ID, dept.AppID); err != nil {
+		return err
+	}
+	if err := s.validateDepartment(dept); err != nil {
+		return err
+	}
+	return s.DB.Model(dept).Where("id = ? AND app_id = ?", dept.ID, dept.AppID).
+		Select("ParentID", "Name", "Sort", "LeaderID").Updates(dept).Error
+}
+
+// DeleteDepartment 删除部门（按应用隔离），存在子部门时不允许删除
+func (s *DepartmentService) DeleteDepartment(id uint, appID uint) error {
+	dept, err := s.GetDepartmentByID(id, appID)
+	if err != nil {
+		return err
+	}
+	var children int64
+	if err := s.DB.Model(&model.Department{}).Where("parent_id = ? AND app_id = ?", id, appID).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrDepartmentHasChildren
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		// 移除成员与角色关联
+		if err := tx.Model(dept).Association("Users").Clear(); err != nil {
+			return fmt.Errorf("failed to remove members of department %d: %w", id, err)
+		}
+		if err := tx.Model(dept).Association("Roles").Clear(); err != nil {
+			return fmt.Errorf("failed to remove roles of department %d: %w", id, err)
+		}
+		return tx.Delete(dept).Error
+	})
+}
+
+// GetDepartmentByID 根据ID获取部门（按应用隔离），包含授予部门的角色
+func (s *DepartmentService) GetDepartmentByID(id uint, appID uint) (*model.Department, error) {
+	var dept model.Department
+	if err := s.DB.Preload("Roles").Where("id = ? AND app_id = ?", id, appID).First(&dept).Error; err != nil {
+		return nil, err
+	}
+	for _, role := range dept.Roles {
+		dept.RoleIDs = append(dept.RoleIDs, role.ID)
+	}
+	return &dept, nil
+}
+
+// ListDepartmentsByApp 列出指定应用的所有部门（扁平结构）
+func (s *DepartmentService) ListDepartmentsByApp(appID uint) ([]*model.Department, error) {
+	var departments []*model.Department
+	if err := s.DB.Where("app_id = ?", appID).Order("sort asc, id asc").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	return departments, nil
+}
+
+// GetDepartmentTreeByApp 获取指定应用的部门树
+func (s *DepartmentService) GetDepartmentTreeByApp(appID uint) ([]*model.Department, error) {
+	// 获取所有部门
+	departments, err := s.ListDepartmentsByApp(appID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 构建部门树
+	return buildDepartmentTree(departments, 0), nil
+}
+
+// buildDepartmentTree 构建部门树
+func buildDepartmentTree(departments []*model.Department, parentID uint) []*model.Department {
+	var tree []*model.Department
+
+	for _, dept := range departments {
+		if dept.ParentID == parentID {
+			children := buildDepartmentTree(departments, dept.ID)
+			if len(children) > 0 {
+				dept.Children = children
+			}
+			tree = append(tree, dept)
+		}
+	}
+
+	return tree
+}
+
+// descendantDepartmentIDs 返回 rootIDs 的全部下级部门ID（不含 rootIDs 自身）
+func descendantDepartmentIDs(departments []*model.Department, rootIDs []uint) []uint {
+	children := make(map[uint][]uint)
+	for _, dept := range departments {
+		children[dept.ParentID] = append(children[dept.ParentID], dept.ID)
+	}
+
+	var result []uint
+	visited := make(map[uint]bool)
+	queue := slices.Clone(rootIDs)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if !visited[child] {
+				visited[child] = true
+				result = append(result, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	return result
+}
+
+// ListDepartmentUsers 获取部门的成员（按应用隔离）
+func (s *DepartmentService) ListDepartmentUsers(id uint, appID uint) ([]*model.User, error) {
+	dept, err := s.GetDepartmentByID(id, appID)
+	if err != nil {
+		return nil, err
+	}
+	var users []*model.User
+	if err := s.DB.Model(dept).Order("id").Association("Users").Find(&users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// SetDepartmentUsers 替换部门的成员，成员必须属于同一应用
+func (s *DepartmentService) SetDepartmentUsers(id uint, appID uint, userIDs []uint) error {
+	dept, err := s.GetDepartmentByID(id, appID)
+	if err != nil {
+		return err
+	}
+	userIDs = uniqueUints(userIDs)
+	var users []*model.User
+	if len(userIDs) > 0 {
+		if err := s.DB.Where("id IN ? AND app_id = ?", userIDs, appID).Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) != len(userIDs) {
+			return errors.New("user not found in this application")
+		}
+	}
+	return s.DB.Model(dept).Association("Users").Replace(users)
+}
+
+// SetDepartmentRoles 替换授予部门的角色，部门成员继承这些角色；角色必须属于同一应用
+func (s *DepartmentService) SetDepartmentRoles(id uint, appID uint, roleIDs []uint) error {
+	dept, err := s.GetDepartmentByID(id, appID)
+	if err != nil {
+		return err
+	}
+	roleIDs = uniqueUints(roleIDs)
+	var roles []*model.Role
+	if len(roleIDs) > 0 {
+		if err := s.DB.Where("id IN ? AND app_id = ?", roleIDs, appID).Find(&roles).Error; err != nil {
+			return err
+		}
+		if len(roles) != len(roleIDs) {
+			return errors.New("role not found in this application")
+		}
+	}
+	return s.DB.Model(dept).Association("Roles").Replace(roles)
+}
+
+// SetUserDepartments 替换用户所属的部门，部门必须属于用户所在应用
+func (s *DepartmentService) SetUserDepartments(userID uint, appID uint, departmentIDs []uint) error {
+	var user model.User
+	if err := s.DB.Where("id = ? AND app_id = ?", userID, appID).First(&user).Error; err != nil {
+		return err
+	}
+	departmentIDs = uniqueUints(departmentIDs)
+	var departments []*model.Department
+	if len(departmentIDs) > 0 {
+		if err := s.DB.Where("id IN ? AND app_id = ?", departmentIDs, appID).Find(&departments).Error; err != nil {
+			return err
+		}
+		if len(departments) != len(departmentIDs) {
+			return errors.New("department not found in this application")
+		}
+	}
+	return s.DB.Model(&user).Association("Departments").Replace(departments)
+}
+
+// userDepartmentIDs 获取用户直接所属的部门ID
+func userDepartmentIDs(db *gorm.DB, userID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Table("user_departments").
+		Joins("JOIN departments ON departments.id = user_departments.department_id AND departments.deleted_at IS NULL").
+		Where("user_departments.user_id = ?", userID).
+		Order("departments.id").Pluck("departments.id", &ids).Error
+	return ids, err
+}
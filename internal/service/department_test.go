@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"Authos/internal/model"
+)
+
+func TestDepartmentTreeAndRoles(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+	roleService := NewRoleService(db, casbinService)
+	departmentService := NewDepartmentService(db)
+	menuService := NewMenuService(db, casbinService)
+
+	app := &model.Application{Name: "org-app", Code: "org-app", Status: 1}
+	other := &model.Application{Name: "org-other", Code: "org-other", Status: 1}
+	db.Create(app)
+	db.Create(other)
+	leader := &model.User{Username: "org-leader", Password: "password", Status: 1, AppID: app.ID}
+	member := &model.User{Username: "org-member", Password: "password", Status: 1, AppID: app.ID}
+	outsider := &model.User{Username: "org-outsider", Password: "password", Status: 1, AppID: other.ID}
+	for _, user := range []*model.User{leader, member, outsider} {
+		db.Create(user)
+	}
+
+	create := func(name string, parentID uint, sort int) *model.Department {
+		dept := &model.Department{Name: name, ParentID: parentID, Sort: sort, AppID: app.ID}
+		if err := departmentService.CreateDepartment(dept); err != nil {
+			t.Fatalf("failed to create department %s: %v", name, err)
+		}
+		return dept
+	}
+	head := create("head office", 0, 0)
+	sales := create("sales", head.ID, 2)
+	rd := create("r&d", head.ID, 1)
+	east := create("east sales", sales.ID, 0)
+
+	tree, err := departmentService.GetDepartmentTreeByApp(app.ID)
+	if err != nil || len(tree) != 1 || len(tree[0].Children) != 2 {
+		t.Fatalf("unexpected tree: %+v, err=%v", tree, err)
+	}
+	if tree[0].Children[0].ID != rd.ID || tree[0].Children[1].Children[0].ID != east.ID {
+		t.Fatalf("unexpected tree order: %+v", tree[0].Children)
+	}
+
+	// 负责人与父部门必须属于同一应用，且不能移动到自身的下级部门之下
+	if err := departmentService.CreateDepartment(&model.Department{Name: "x", LeaderID: outsider.ID, AppID: app.ID}); err == nil {
+		t.Fatal("expected leader from another application rejected")
+	}
+	head.ParentID = east.ID
+	if err := departmentService.UpdateDepartment(head); !errors.Is(err, ErrDepartmentCycle) {
+		t.Fatalf("expected cycle rejected, got %v", err)
+	}
+	sales.LeaderID = leader.ID
+	if err := departmentService.UpdateDepartment(sales); err != nil {
+		t.Fatalf("failed to update department: %v", err)
+	}
+	if err := departmentService.DeleteDepartment(sales.ID, app.ID); !errors.Is(err, ErrDepartmentHasChildren) {
+		t.Fatalf("expected delete with children rejected, got %v", err)
+	}
+
+	// 部门被授予的角色对成员生效
+	role := &model.Role{Name: "seller", AppID: app.ID}
+	db.Create(role)
+	menu := &model.Menu{Name: "orders", Path: "/orders", Type: 1, AppID: app.ID}
+	db.Create(menu)
+	if err := roleService.AssignMenus(role.ID, app.ID, []uint{menu.ID}); err != nil {
+		t.Fatalf("failed to assign menus: %v", err)
+	}
+	permission, err := apiPermissionService.CreateApiPermission(app.ID, "order.list", "订单列表", "/api/orders", model.HTTP_ALL, "")
+	if err != nil {
+		t.Fatalf("failed to create api permission: %v", err)
+	}
+	if err := apiPermissionService.AddApiPermissionToRole(app.ID, role.UUID, permission.UUID); err != nil {
+		t.Fatalf("failed to add permission to role: %v", err)
+	}
+
+	if allowed, _ := casbinService.CheckPermission(member.ID, "order.list", "GET", nil); allowed {
+		t.Fatal("expected member without department denied")
+	}
+	if err := departmentService.SetDepartmentRoles(sales.ID, app.ID, []uint{role.ID}); err != nil {
+		t.Fatalf("failed to set department roles: %v", err)
+	}
+	if err := departmentService.SetUserDepartments(member.ID, app.ID, []uint{sales.ID, rd.ID}); err != nil {
+		t.Fatalf("failed to set user departments: %v", err)
+	}
+	if err := departmentService.SetUserDepartments(outsider.ID, app.ID, []uint{sales.ID}); err == nil {
+		t.Fatal("expected user from another application rejected")
+	}
+	if allowed, err := casbinService.CheckPermission(member.ID, "order.list", "GET", nil); err != nil || !allowed {
+		t.Fatalf("expected department role allowed, got %v, err=%v", allowed, err)
+	}
+	menus, err := menuService.GetUserMenuTree(member.ID)
+	if err != nil || len(menus) != 1 || menus[0].ID != menu.ID {
+		t.Fatalf("unexpected user menus: %+v, err=%v", menus, err)
+	}
+	users, err := departmentService.ListDepartmentUsers(sales.ID, app.ID)
+	if err != nil || len(users) != 1 || users[0].ID != member.ID {
+		t.Fatalf("unexpected department users: %+v, err=%v", users, err)
+	}
+
+	// 本部门及下级部门的数据范围按用户所在部门解析
+	if err := roleService.SetRoleDataScope(role.ID, app.ID, model.DATA_SCOPE_DEPT_AND_CHILDREN, nil); err != nil {
+		t.Fatalf("failed to set role data scope: %v", err)
+	}
+	_, scope, err := casbinService.CheckPermissionWithDataScope(member.ID, "order.list", "GET", nil)
+	want := &DataScope{Scopes: []string{model.DATA_SCOPE_DEPT_AND_CHILDREN}, DeptIDs: []uint{sales.ID, rd.ID, east.ID}}
+	if err != nil || !reflect.DeepEqual(scope, want) {
+		t.Fatalf("expected data scope %+v, got %+v, err=%v", want, scope, err)
+	}
+
+	// 移出部门后不再继承部门角色
+	if err := departmentService.SetDepartmentUsers(sales.ID, app.ID, nil); err != nil {
+		t.Fatalf("failed to clear department users: %v", err)
+	}
+	if allowed, _ := casbinService.CheckPermission(member.ID, "order.list", "GET", nil); allowed {
+		t.Fatal("expected permission removed after leaving department")
+	}
+	if err := departmentService.DeleteDepartment(east.ID, app.ID); err != nil {
+		t.Fatalf("failed to delete department: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+// effectiveRolesQuery 用户有效角色的查询：直接持有的角色 ∪ 所在用户组被授予的角色 ∪ 所在部门被授予的角色
+func effectiveRolesQuery(db *gorm.DB, userID uint) *gorm.DB {
+	direct := db.Table("user_roles").Select("role_id").Where("user_id = ?", userID)
+	viaGroups := db.Table("user_group_roles").Select("user_group_roles.role_id").
+		Joins("JOIN user_group_members ON user_group_members.user_group_id = user_group_roles.user_group_id").
+		Joins("JOIN user_groups ON user_groups.id = user_group_roles.user_group_id AND user_groups.deleted_at IS NULL").
+		Where("user_group_members.user_id = ?", userID)
+	viaDepartments := db.Table("department_roles").Select("department_roles.role_id").
+		Joins("JOIN user_departments ON user_departments.department_id = department_roles.department_id").
+		Joins("JOIN departments ON departments.id = department_roles.department_id AND departments.deleted_at IS NULL").
+		Where("user_departments.user_id = ?", userID)
+	return db.Model(&model.Role{}).Where("id IN (?) OR id IN (?) OR id IN (?)", direct, viaGroups, viaDepartments)
+}
+
+// roleHoldersQuery 持有任一指定角色的用户ID的查询（与 effectiveRolesQuery 相对）：直接持有 ∪ 经所在用户组 ∪ 经所在部门
+func roleHoldersQuery(db *gorm.DB, roleIDs []uint) *gorm.DB {
+	direct := db.Table("user_roles").Select("user_id").Where("role_id IN ?", roleIDs)
+	viaGroups := db.Table("user_group_members").Select("user_group_members.user_id").
+		Joins("JOIN user_group_roles ON user_group_roles.user_group_id = user_group_members.user_group_id").
+		Joins("JOIN user_groups ON user_groups.id = user_group_members.user_group_id AND user_groups.deleted_at IS NULL").
+		Where("user_group_roles.role_id IN ?", roleIDs)
+	viaDepartments := db.Table("user_departments").Select("user_departments.user_id").
+		Joins("JOIN department_roles ON department_roles.department_id = user_departments.department_id").
+		Joins("JOIN departments ON departments.id = user_departments.department_id AND departments.deleted_at IS NULL").
+		Where("department_roles.role_id IN ?", roleIDs)
+	return db.Model(&model.User{}).Where("id IN (?) OR id IN (?) OR id IN (?)", direct, viaGroups, viaDepartments)
+}
+
+// loadEffectiveRoles 将 user.Roles 替换为用户的有效角色（见 effectiveRolesQuery），preloads 为角色需要预加载的关联
+func loadEffectiveRoles(db *gorm.DB, user *model.User, preloads ...string) error {
+	query := effectiveRolesQuery(db, user.ID).Order("id")
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+	var roles []*model.Role
+	if err := query.Find(&roles).Error; err != nil {
+		return err
+	}
+	user.Roles = roles
+	return nil
+}
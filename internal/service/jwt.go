@@ -2,15 +2,21 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // JWTConfig JWT配置
+// 密钥库为空时使用 HS256 共享密钥签名；否则使用密钥库中的 active 非对称密钥 (RS256/ES256) 签名，
+// 令牌头部携带 kid，校验时按 kid 从密钥库选择公钥，租户后端也可通过 JWKS 离线验证
 type JWTConfig struct {
 	SecretKey  string
 	ExpireTime time.Duration
+	Keys       *KeyStore // 签名密钥库
+	Issuer     string    // OIDC 签发者地址，为空时按请求地址推导
 }
 
 // JWTClaims JWT声明
@@ -40,12 +46,94 @@ type AppJWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+// IDTokenClaims OpenID Connect ID Token 声明
+type IDTokenClaims struct {
+	Nonce             string           `json:"nonce,omitempty"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	PreferredUsername string           `json:"preferred_username"`
+	Roles             []string         `json:"roles"`
+	Type              string           `json:"type"` // 令牌类型: id，只用于向客户端证明身份，不可作为访问令牌
+	jwt.RegisteredClaims
+}
+
 // NewJWTConfig 创建JWT配置实例
 func NewJWTConfig(secretKey string, expireTime time.Duration) *JWTConfig {
 	return &JWTConfig{
 		SecretKey:  secretKey,
 		ExpireTime: expireTime,
+		Keys:       NewKeyStore(),
+	}
+}
+
+// signClaims 使用当前 active 密钥签名令牌
+func (j *JWTConfig) signClaims(claims jwt.Claims) (string, error) {
+	active := j.Keys.Active()
+	if active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.SecretKey))
+	}
+
+	method := jwt.GetSigningMethod(active.Algorithm)
+	if method == nil {
+		return "", fmt.Errorf("unsupported signing algorithm: %s", active.Algorithm)
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = active.KID
+	return token.SignedString(active.PrivateKey)
+}
+
+// SigningAlgorithm 返回当前签名算法
+func (j *JWTConfig) SigningAlgorithm() string {
+	if active := j.Keys.Active(); active != nil {
+		return active.Algorithm
+	}
+	return AlgorithmHS256
+}
+
+// keyFunc 根据令牌头部的 kid 从密钥库选择验证密钥
+func (j *JWTConfig) keyFunc(token *jwt.Token) (interface{}, error) {
+	if j.Keys.Empty() {
+		return []byte(j.SecretKey), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	entry, ok := j.Keys.VerificationKey(kid)
+	if !ok && !j.Keys.Known(kid) && j.Keys.reloadForUnknownKID() {
+		// 其他实例可能已轮换密钥，刷新后重试
+		entry, ok = j.Keys.VerificationKey(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	// 算法必须与密钥登记的算法一致，防止算法混淆攻击
+	if token.Method.Alg() != entry.Algorithm {
+		return nil, fmt.Errorf("signing method %s does not match key %s", token.Method.Alg(), kid)
 	}
+	return entry.PrivateKey.Public(), nil
+}
+
+// parse 校验签名算法并解析令牌
+func (j *JWTConfig) parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	validMethods := []string{AlgorithmRS256, AlgorithmES256}
+	if j.Keys.Empty() {
+		validMethods = []string{AlgorithmHS256}
+	}
+	return jwt.ParseWithClaims(tokenString, claims, j.keyFunc, jwt.WithValidMethods(validMethods))
+}
+
+// JWKS 返回用于离线验证令牌的公钥集合 (RFC 7517)，包含 active 与 verifying 密钥
+func (j *JWTConfig) JWKS() (map[string]interface{}, error) {
+	entries := j.Keys.PublishedKeys()
+	keys := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		jwk, err := PublicJWK(entry.KID, entry.Algorithm, entry.PrivateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwk)
+	}
+
+	return map[string]interface{}{"keys": keys}, nil
 }
 
 // GenerateToken 生成JWT令牌
@@ -57,14 +145,14 @@ func (j *JWTConfig) GenerateToken(userID uint, username string, appID uint, appU
 		AppUUID:  appUUID,
 		Type:     "user",
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpireTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.SecretKey))
+	return j.signClaims(claims)
 }
 
 // GenerateSystemToken 生成系统管理员JWT令牌
@@ -74,14 +162,14 @@ func (j *JWTConfig) GenerateSystemToken(username string) (string, error) {
 		IsAdmin:  true,
 		Type:     "system",
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpireTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.SecretKey))
+	return j.signClaims(claims)
 }
 
 // GenerateAppToken 生成应用JWT令牌
@@ -92,21 +180,59 @@ func (j *JWTConfig) GenerateAppToken(appID uint, appUUID, appCode string) (strin
 		AppCode: appCode,
 		Type:    "app",
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpireTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.SecretKey))
+	return j.signClaims(claims)
+}
+
+// GenerateIDToken 生成 OIDC ID Token，aud 为客户端 (appCode)，sub 为用户ID
+func (j *JWTConfig) GenerateIDToken(issuer, clientID string, userID uint, username string, roles []string, nonce string, authTime time.Time) (string, error) {
+	if roles == nil {
+		roles = []string{}
+	}
+
+	claims := IDTokenClaims{
+		Nonce:             nonce,
+		AuthTime:          jwt.NewNumericDate(authTime),
+		PreferredUsername: username,
+		Roles:             roles,
+		Type:              "id",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    issuer,
+			Subject:   fmt.Sprintf("%d", userID),
+			Audience:  jwt.ClaimStrings{clientID},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpireTime)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return j.signClaims(claims)
+}
+
+// ParseMapClaims 解析任意类型的JWT令牌（user/system/app），返回通用声明
+func (j *JWTConfig) ParseMapClaims(tokenString string) (jwt.MapClaims, error) {
+	token, err := j.parse(tokenString, jwt.MapClaims{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
 }
 
 // ParseToken 解析JWT令牌
 func (j *JWTConfig) ParseToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.SecretKey), nil
-	})
+	token, err := j.parse(tokenString, &JWTClaims{})
 
 	if err != nil {
 		return nil, err
@@ -121,9 +247,7 @@ func (j *JWTConfig) ParseToken(tokenString string) (*JWTClaims, error) {
 
 // ParseSystemToken 解析系统管理员JWT令牌
 func (j *JWTConfig) ParseSystemToken(tokenString string) (*SystemJWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &SystemJWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.SecretKey), nil
-	})
+	token, err := j.parse(tokenString, &SystemJWTClaims{})
 
 	if err != nil {
 		return nil, err
@@ -138,9 +262,7 @@ func (j *JWTConfig) ParseSystemToken(tokenString string) (*SystemJWTClaims, erro
 
 // ParseAppToken 解析应用JWT令牌
 func (j *JWTConfig) ParseAppToken(tokenString string) (*AppJWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &AppJWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.SecretKey), nil
-	})
+	token, err := j.parse(tokenString, &AppJWTClaims{})
 
 	if err != nil {
 		return nil, err
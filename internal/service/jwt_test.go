@@ -0,0 +1,211 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"Authos/internal/model"
+)
+
+// tokenHeader 解码令牌头部（不校验签名）
+func tokenHeader(t *testing.T, token string) map[string]interface{} {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	return parsed.Header
+}
+
+func TestAsymmetricSigningAndJWKS(t *testing.T) {
+	db := newTestDB(t)
+	signingKeyService := NewSigningKeyService(db)
+	jwtConfig := NewJWTConfig("secret", time.Hour)
+
+	key, err := signingKeyService.LoadOrCreateActiveKey(AlgorithmRS256)
+	if err != nil {
+		t.Fatalf("failed to create signing key: %v", err)
+	}
+
+	// 再次加载应返回同一把密钥
+	again, err := signingKeyService.LoadOrCreateActiveKey(AlgorithmRS256)
+	if err != nil || again.KID != key.KID {
+		t.Fatalf("expected existing key %s, got %v (err=%v)", key.KID, again, err)
+	}
+
+	if err := signingKeyService.LoadKeyStore(jwtConfig.Keys); err != nil {
+		t.Fatalf("failed to load key store: %v", err)
+	}
+
+	token, err := jwtConfig.GenerateToken(1, "alice", 2, "app-uuid")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	if header := tokenHeader(t, token); header["kid"] != key.KID || header["alg"] != AlgorithmRS256 {
+		t.Fatalf("unexpected header %v", header)
+	}
+
+	claims, err := jwtConfig.ParseToken(token)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	if claims.UserID != 1 || claims.ID == "" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	// 使用共享密钥签发的 HS256 令牌不能通过校验
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{UserID: 1, Type: "user"})
+	forged.Header["kid"] = key.KID
+	forgedString, _ := forged.SignedString([]byte("secret"))
+	if _, err := jwtConfig.ParseToken(forgedString); err == nil || !strings.Contains(err.Error(), "signing method") {
+		t.Fatalf("expected HS256 token to be rejected, got %v", err)
+	}
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	db := newTestDB(t)
+	signingKeyService := NewSigningKeyService(db)
+	jwtConfig := NewJWTConfig("secret", time.Hour)
+
+	oldKey, err := signingKeyService.LoadOrCreateActiveKey(AlgorithmRS256)
+	if err != nil {
+		t.Fatalf("failed to create signing key: %v", err)
+	}
+	if err := signingKeyService.LoadKeyStore(jwtConfig.Keys); err != nil {
+		t.Fatalf("failed to load key store: %v", err)
+	}
+	oldToken, _ := jwtConfig.GenerateToken(1, "alice", 2, "app-uuid")
+
+	// 轮换到 ES256：新令牌使用新密钥，旧令牌在重叠期内仍然有效
+	newKey, err := signingKeyService.RotateKey(AlgorithmES256)
+	if err != nil {
+		t.Fatalf("failed to rotate signing key: %v", err)
+	}
+	if err := signingKeyService.RetireExpiredKeys(jwtConfig.ExpireTime); err != nil {
+		t.Fatalf("failed to retire expired keys: %v", err)
+	}
+	if err := signingKeyService.LoadKeyStore(jwtConfig.Keys); err != nil {
+		t.Fatalf("failed to reload key store: %v", err)
+	}
+
+	newToken, _ := jwtConfig.GenerateAppToken(2, "app-uuid", "app")
+	if header := tokenHeader(t, newToken); header["kid"] != newKey.KID || header["alg"] != AlgorithmES256 {
+		t.Fatalf("unexpected header after rotation %v", header)
+	}
+	if _, err := jwtConfig.ParseAppToken(newToken); err != nil {
+		t.Fatalf("failed to parse token signed by new key: %v", err)
+	}
+	if _, err := jwtConfig.ParseToken(oldToken); err != nil {
+		t.Fatalf("expected token signed by verifying key to remain valid: %v", err)
+	}
+
+	jwks, err := jwtConfig.JWKS()
+	if err != nil {
+		t.Fatalf("failed to build jwks: %v", err)
+	}
+	keys := jwks["keys"].([]map[string]interface{})
+	if len(keys) != 2 || keys[0]["kid"] != newKey.KID || keys[1]["kid"] != oldKey.KID {
+		t.Fatalf("expected active and verifying keys in jwks, got %v", keys)
+	}
+
+	// active 密钥不可退役；退役旧密钥后其签发的令牌失效
+	if err := signingKeyService.RetireKey(newKey.KID); err == nil {
+		t.Fatalf("expected retiring the active key to fail")
+	}
+	if err := signingKeyService.RetireKey(oldKey.KID); err != nil {
+		t.Fatalf("failed to retire old key: %v", err)
+	}
+	if err := signingKeyService.LoadKeyStore(jwtConfig.Keys); err != nil {
+		t.Fatalf("failed to reload key store: %v", err)
+	}
+	if _, err := jwtConfig.ParseToken(oldToken); err == nil {
+		t.Fatalf("expected token signed by retired key to be rejected")
+	}
+
+	var active int64
+	db.Model(&model.SigningKey{}).Where("status = ?", model.SigningKeyStatusActive).Count(&active)
+	if active != 1 {
+		t.Fatalf("expected exactly one active key, got %d", active)
+	}
+}
+
+func TestGenerateIDToken(t *testing.T) {
+	db := newTestDB(t)
+	signingKeyService := NewSigningKeyService(db)
+	jwtConfig := NewJWTConfig("secret", time.Hour)
+
+	if _, err := signingKeyService.LoadOrCreateActiveKey(AlgorithmES256); err != nil {
+		t.Fatalf("failed to create signing key: %v", err)
+	}
+	if err := signingKeyService.LoadKeyStore(jwtConfig.Keys); err != nil {
+		t.Fatalf("failed to load key store: %v", err)
+	}
+
+	authTime := time.Now().Add(-time.Minute)
+	idToken, err := jwtConfig.GenerateIDToken("https://authos.example.com", "galaxy", 7, "alice", []string{"editor"}, "n-0S6", authTime)
+	if err != nil {
+		t.Fatalf("failed to generate id token: %v", err)
+	}
+
+	claims := &IDTokenClaims{}
+	if _, err := jwtConfig.parse(idToken, claims); err != nil {
+		t.Fatalf("failed to verify id token: %v", err)
+	}
+	if claims.Type != "id" || claims.Issuer != "https://authos.example.com" || claims.Subject != "7" || claims.Nonce != "n-0S6" {
+		t.Fatalf("unexpected id token claims %+v", claims)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "galaxy" {
+		t.Fatalf("unexpected audience %v", claims.Audience)
+	}
+	if claims.PreferredUsername != "alice" || len(claims.Roles) != 1 || claims.Roles[0] != "editor" {
+		t.Fatalf("unexpected profile claims %+v", claims)
+	}
+	if claims.AuthTime == nil || claims.AuthTime.Unix() != authTime.Unix() {
+		t.Fatalf("unexpected auth_time %v", claims.AuthTime)
+	}
+	if jwtConfig.SigningAlgorithm() != AlgorithmES256 {
+		t.Fatalf("unexpected signing algorithm %s", jwtConfig.SigningAlgorithm())
+	}
+}
+
+func TestKeyStoreReloadsKeysRotatedByOtherInstance(t *testing.T) {
+	db := newTestDB(t)
+	signingKeyService := NewSigningKeyService(db)
+	instanceA := NewJWTConfig("secret", time.Hour)
+	instanceB := NewJWTConfig("secret", time.Hour)
+
+	if _, err := signingKeyService.LoadOrCreateActiveKey(AlgorithmRS256); err != nil {
+		t.Fatalf("failed to create signing key: %v", err)
+	}
+	for _, config := range []*JWTConfig{instanceA, instanceB} {
+		if err := signingKeyService.LoadKeyStore(config.Keys); err != nil {
+			t.Fatalf("failed to load key store: %v", err)
+		}
+	}
+
+	// 实例 A 轮换密钥，实例 B 遇到未知 kid 时从数据库刷新
+	if _, err := signingKeyService.RotateKey(""); err != nil {
+		t.Fatalf("failed to rotate signing key: %v", err)
+	}
+	if err := signingKeyService.RefreshKeyStore(instanceA.Keys, time.Hour); err != nil {
+		t.Fatalf("failed to refresh key store: %v", err)
+	}
+	token, _ := instanceA.GenerateToken(1, "alice", 2, "app-uuid")
+	instanceB.Keys.lastReload = time.Time{}
+	if _, err := instanceB.ParseToken(token); err != nil {
+		t.Fatalf("expected other instance to pick up rotated key: %v", err)
+	}
+
+	// 刷新限频：伪造的 kid 不会在间隔内反复触发数据库查询
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"type": "user"})
+	forged.Header["kid"] = "forged"
+	forgedString, _ := forged.SignedString(instanceA.Keys.Active().PrivateKey)
+	if _, err := instanceB.ParseToken(forgedString); err == nil {
+		t.Fatal("expected token with unknown kid rejected")
+	}
+	if time.Since(instanceB.Keys.lastReload) > time.Second || instanceB.Keys.reloadForUnknownKID() {
+		t.Fatal("expected unknown kid reload to be rate limited")
+	}
+}
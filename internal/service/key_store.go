@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto"
+	"sync"
+	"time"
+
+	"Authos/internal/model"
+)
+
+// KeyStoreEntry 密钥库中的单个密钥
+type KeyStoreEntry struct {
+	KID        string
+	Algorithm  string
+	Status     string
+	PrivateKey crypto.Signer
+}
+
+// KeyStore 签名密钥库，按 kid 保存多把密钥（active / verifying / retired）
+// 签发时使用唯一的 active 密钥，校验时按令牌头部的 kid 选择密钥
+type KeyStore struct {
+	mu        sync.RWMutex
+	keys      map[string]*KeyStoreEntry
+	activeKID string
+
+	// loader 从数据库读取未退役的密钥记录，用于发现其他实例轮换的密钥
+	loader     func() ([]*model.SigningKey, error)
+	reloadMu   sync.Mutex
+	lastReload time.Time
+}
+
+// keyStoreUnknownKIDReloadInterval 未知 kid 触发重新加载的最小间隔，避免伪造 kid 的令牌反复查询数据库
+const keyStoreUnknownKIDReloadInterval = 10 * time.Second
+
+// NewKeyStore 创建空的密钥库
+func NewKeyStore() *KeyStore {
+	return &KeyStore{keys: make(map[string]*KeyStoreEntry)}
+}
+
+// Load 用数据库中的密钥记录整体替换密钥库内容
+func (s *KeyStore) Load(records []*model.SigningKey) error {
+	keys := make(map[string]*KeyStoreEntry, len(records))
+	activeKID := ""
+
+	for _, record := range records {
+		privateKey, err := ParseSigningKey(record)
+		if err != nil {
+			return err
+		}
+		keys[record.KID] = &KeyStoreEntry{
+			KID:        record.KID,
+			Algorithm:  record.Algorithm,
+			Status:     record.Status,
+			PrivateKey: privateKey,
+		}
+		if record.Status == model.SigningKeyStatusActive {
+			activeKID = record.KID
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.activeKID = activeKID
+	return nil
+}
+
+// SetLoader 设置密钥记录的加载函数，设置后可通过 Reload 从数据库刷新密钥库
+func (s *KeyStore) SetLoader(loader func() ([]*model.SigningKey, error)) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.loader = loader
+}
+
+// Reload 通过加载函数从数据库刷新密钥库，未设置加载函数时不做任何操作
+func (s *KeyStore) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	return s.reloadLocked()
+}
+
+// reloadLocked 刷新密钥库，调用方须持有 reloadMu
+func (s *KeyStore) reloadLocked() error {
+	if s.loader == nil {
+		return nil
+	}
+	records, err := s.loader()
+	if err != nil {
+		return err
+	}
+	if err := s.Load(records); err != nil {
+		return err
+	}
+	s.lastReload = time.Now()
+	return nil
+}
+
+// reloadForUnknownKID 遇到本实例未加载的 kid 时刷新密钥库（限频），返回是否已刷新
+func (s *KeyStore) reloadForUnknownKID() bool {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.loader == nil || time.Since(s.lastReload) < keyStoreUnknownKIDReloadInterval {
+		return false
+	}
+	if err := s.reloadLocked(); err != nil {
+		Log.Warnf("KeyStore: Failed to reload signing keys: %v", err)
+		return false
+	}
+	return true
+}
+
+// Active 返回当前用于签发的密钥，未配置时返回 nil
+func (s *KeyStore) Active() *KeyStoreEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[s.activeKID]
+}
+
+// VerificationKey 按 kid 查找可用于校验的密钥（active 或 verifying）
+func (s *KeyStore) VerificationKey(kid string) (*KeyStoreEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.keys[kid]
+	if !ok || entry.Status == model.SigningKeyStatusRetired {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Known 本实例是否已加载过该 kid（包括已退役的密钥）
+func (s *KeyStore) Known(kid string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.keys[kid]
+	return ok
+}
+
+// PublishedKeys 返回需要通过 JWKS 发布的密钥（active 与 verifying）
+func (s *KeyStore) PublishedKeys() []*KeyStoreEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]*KeyStoreEntry, 0, len(s.keys))
+	if active, ok := s.keys[s.activeKID]; ok {
+		entries = append(entries, active)
+	}
+	for kid, entry := range s.keys {
+		if kid != s.activeKID && entry.Status == model.SigningKeyStatusVerifying {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Empty 密钥库是否为空（为空时回退到 HS256 共享密钥）
+func (s *KeyStore) Empty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.activeKID == ""
+}
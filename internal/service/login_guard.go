@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+const (
+	loginFailureWindow = 24 * time.Hour // 超过该时间没有新的失败则重新计数
+	loginMaxLockout    = 24 * time.Hour // 单次锁定时长上限
+)
+
+// LoginGuardService 登录防暴力破解服务：按用户与客户端 IP 统计连续失败次数并指数退避锁定
+type LoginGuardService struct {
+	DB *gorm.DB
+}
+
+// NewLoginGuardService 创建登录防暴力破解服务实例
+func NewLoginGuardService(db *gorm.DB) *LoginGuardService {
+	return &LoginGuardService{DB: db}
+}
+
+// loginSubject 失败计数的维度与阈值
+type loginSubject struct {
+	scope       string
+	subject     string
+	maxFailures int
+}
+
+// subjects 按应用配置返回需要计数的维度（阈值为 0 的维度不计数）
+func (s *LoginGuardService) subjects(app *model.Application, userID uint, ip string) []loginSubject {
+	var subjects []loginSubject
+	if app.LoginMaxFailures > 0 && userID > 0 {
+		subjects = append(subjects, loginSubject{model.LoginFailureScopeUser, fmt.Sprintf("%d", userID), app.LoginMaxFailures})
+	}
+	if app.LoginIPMaxFailures > 0 && ip != "" {
+		subjects = append(subjects, loginSubject{model.LoginFailureScopeIP, ip, app.LoginIPMaxFailures})
+	}
+	return subjects
+}
+
+// LockedFor 返回用户或 IP 剩余的锁定时长，0 表示未锁定；userID 为 0 时只检查 IP
+func (s *LoginGuardService) LockedFor(app *model.Application, userID uint, ip string) time.Duration {
+	now := time.Now()
+	var remaining time.Duration
+	for _, subject := range s.subjects(app, userID, ip) {
+		var failure model.LoginFailure
+		if err := s.DB.Where("app_id = ? AND scope = ? AND subject = ?", app.ID, subject.scope, subject.subject).
+			First(&failure).Error; err != nil {
+			continue
+		}
+		if failure.LockedUntil != nil && failure.LockedUntil.After(now) {
+			if d := failure.LockedUntil.Sub(now); d > remaining {
+				remaining = d
+			}
+		}
+	}
+	return remaining
+}
+
+// RecordFailure 记录一次登录失败，返回因此触发的锁定时长（0 表示未锁定）。
+// userID 为 0（用户不存在）时只按 IP 计数
+func (s *LoginGuardService) RecordFailure(app *model.Application, userID uint, ip string) (time.Duration, error) {
+	now := time.Now()
+	var lockout time.Duration
+	for _, subject := range s.subjects(app, userID, ip) {
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			var failure model.LoginFailure
+			err := tx.Where("app_id = ? AND scope = ? AND subject = ?", app.ID, subject.scope, subject.subject).
+				First(&failure).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err != nil {
+				failure = model.LoginFailure{AppID: app.ID, Scope: subject.scope, Subject: subject.subject}
+			}
+
+			if now.Sub(failure.LastFailedAt) > loginFailureWindow {
+				failure.FailedCount = 0
+				failure.LockedUntil = nil
+			}
+			failure.FailedCount++
+			failure.LastFailedAt = now
+
+			if failure.FailedCount >= subject.maxFailures {
+				d := lockoutDuration(app.LoginLockoutSeconds, failure.FailedCount-subject.maxFailures)
+				until := now.Add(d)
+				failure.LockedUntil = &until
+				if d > lockout {
+					lockout = d
+				}
+			}
+
+			return tx.Save(&failure).Error
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to record login failure: %w", err)
+		}
+	}
+	return lockout, nil
+}
+
+// lockoutDuration 计算锁定时长：首次锁定 baseSeconds 秒，此后每次失败翻倍，不超过上限
+func lockoutDuration(baseSeconds, exceeded int) time.Duration {
+	if baseSeconds <= 0 {
+		baseSeconds = 300
+	}
+	d := time.Duration(baseSeconds) * time.Second
+	for i := 0; i < exceeded && d < loginMaxLockout; i++ {
+		d *= 2
+	}
+	if d > loginMaxLockout {
+		d = loginMaxLockout
+	}
+	return d
+}
+
+// ResetUser 清除用户的失败计数与锁定（登录成功或管理员解锁时调用）
+func (s *LoginGuardService) ResetUser(appID, userID uint) error {
+	return s.DB.Unscoped().
+		Where("app_id = ? AND scope = ? AND subject = ?", appID, model.LoginFailureScopeUser, fmt.Sprintf("%d", userID)).
+		Delete(&model.LoginFailure{}).Error
+}
+
+// GetUserFailure 查询用户当前的失败计数，没有记录时返回 nil
+func (s *LoginGuardService) GetUserFailure(appID, userID uint) (*model.LoginFailure, error) {
+	var failure model.LoginFailure
+	err := s.DB.Where("app_id = ? AND scope = ? AND subject = ?", appID, model.LoginFailureScopeUser, fmt.Sprintf("%d", userID)).
+		First(&failure).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &failure, nil
+}
+
+// CleanupExpired 清理已过计数窗口且不在锁定期的失败记录
+func (s *LoginGuardService) CleanupExpired() error {
+	now := time.Now()
+	return s.DB.Unscoped().
+		Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-loginFailureWindow), now).
+		Delete(&model.LoginFailure{}).Error
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestLoginGuardLockoutAndBackoff(t *testing.T) {
+	db := newTestDB(t)
+	loginGuardService := NewLoginGuardService(db)
+
+	app := &model.Application{ID: 1, LoginMaxFailures: 3, LoginIPMaxFailures: 5, LoginLockoutSeconds: 60}
+
+	// 未达到阈值前不锁定
+	for i := 0; i < 2; i++ {
+		lockout, err := loginGuardService.RecordFailure(app, 7, "10.0.0.1")
+		if err != nil || lockout != 0 {
+			t.Fatalf("unexpected lockout after %d failures: %s, err=%v", i+1, lockout, err)
+		}
+	}
+	if d := loginGuardService.LockedFor(app, 7, "10.0.0.1"); d != 0 {
+		t.Fatalf("expected no lockout yet, got %s", d)
+	}
+
+	// 达到阈值后锁定，此后每次失败锁定时长翻倍
+	lockout, _ := loginGuardService.RecordFailure(app, 7, "10.0.0.1")
+	if lockout != time.Minute {
+		t.Fatalf("expected first lockout of 1m, got %s", lockout)
+	}
+	lockout, _ = loginGuardService.RecordFailure(app, 7, "10.0.0.2")
+	if lockout != 2*time.Minute {
+		t.Fatalf("expected doubled lockout of 2m, got %s", lockout)
+	}
+	if d := loginGuardService.LockedFor(app, 7, "10.0.0.3"); d <= time.Minute {
+		t.Fatalf("expected user to be locked from any ip, got %s", d)
+	}
+
+	// 其他用户不受影响；IP 计数独立（10.0.0.1 失败 3 次，未达到 IP 阈值）
+	if d := loginGuardService.LockedFor(app, 8, "10.0.0.1"); d != 0 {
+		t.Fatalf("expected other user to be unaffected, got %s", d)
+	}
+
+	// 管理员解锁
+	if err := loginGuardService.ResetUser(app.ID, 7); err != nil {
+		t.Fatalf("failed to reset user: %v", err)
+	}
+	if d := loginGuardService.LockedFor(app, 7, "10.0.0.3"); d != 0 {
+		t.Fatalf("expected user to be unlocked, got %s", d)
+	}
+
+	// 不存在的用户名只按 IP 计数，累计达到 IP 阈值后锁定该 IP
+	loginGuardService.RecordFailure(app, 0, "10.0.0.1")
+	if lockout, _ := loginGuardService.RecordFailure(app, 0, "10.0.0.1"); lockout != time.Minute {
+		t.Fatalf("expected ip lockout of 1m, got %s", lockout)
+	}
+	if d := loginGuardService.LockedFor(app, 9, "10.0.0.1"); d == 0 {
+		t.Fatal("expected locked ip to block other users")
+	}
+
+	// 阈值为 0 表示不限制
+	unlimited := &model.Application{ID: 2, LoginLockoutSeconds: 60}
+	for i := 0; i < 10; i++ {
+		if lockout, _ := loginGuardService.RecordFailure(unlimited, 7, "10.0.0.9"); lockout != 0 {
+			t.Fatalf("expected no lockout when disabled, got %s", lockout)
+		}
+	}
+}
+
+func TestLockoutDurationIsCapped(t *testing.T) {
+	if d := lockoutDuration(300, 0); d != 5*time.Minute {
+		t.Fatalf("unexpected base lockout: %s", d)
+	}
+	if d := lockoutDuration(300, 3); d != 40*time.Minute {
+		t.Fatalf("unexpected backoff lockout: %s", d)
+	}
+	if d := lockoutDuration(300, 1000); d != loginMaxLockout {
+		t.Fatalf("expected lockout to be capped, got %s", d)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer 通过 SMTP 发送通知邮件（未配置 SMTP 服务器时不可用）
+type Mailer struct {
+	Config SMTPConfig
+}
+
+// NewMailer 创建邮件发送实例
+func NewMailer(config SMTPConfig) *Mailer {
+	return &Mailer{Config: config}
+}
+
+// Enabled 是否已配置 SMTP 服务器
+func (m *Mailer) Enabled() bool {
+	return m != nil && m.Config.Host != ""
+}
+
+// Send 发送纯文本邮件
+func (m *Mailer) Send(to, subject, body string) error {
+	if !m.Enabled() {
+		return errors.New("smtp is not configured")
+	}
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient: %q", to)
+	}
+
+	from := m.Config.From
+	if from == "" {
+		from = m.Config.Username
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + from + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	// 未配置用户名时不认证（本地测试邮件服务）
+	var auth smtp.Auth
+	if m.Config.Username != "" {
+		auth = smtp.PlainAuth("", m.Config.Username, m.Config.Password, m.Config.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", m.Config.Host, m.Config.Port)
+	if err := smtp.SendMail(addr, auth, from, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
@@ -8,12 +8,13 @@ import (
 
 // MenuService 菜单服务
 type MenuService struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	CasbinService *CasbinService
 }
 
 // NewMenuService 创建菜单服务实例
-func NewMenuService(db *gorm.DB) *MenuService {
-	return &MenuService{DB: db}
+func NewMenuService(db *gorm.DB, casbinService *CasbinService) *MenuService {
+	return &MenuService{DB: db, CasbinService: casbinService}
 }
 
 // CreateMenu 创建菜单（按应用隔离）
@@ -106,18 +107,23 @@ func (s *MenuService) GetNonSystemMenuTreeByApp(appID uint) ([]*model.Menu, erro
 
 // GetUserMenuTree 根据用户ID获取用户有权访问的菜单树
 func (s *MenuService) GetUserMenuTree(userID uint) ([]*model.Menu, error) {
-	// 获取用户关联的角色
+	// 获取用户的有效角色（直接角色与所在用户组、部门的角色）
 	var user model.User
-	if err := s.DB.Preload("Roles.Menus").First(&user, userID).Error; err != nil {
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := loadEffectiveRoles(s.DB, &user); err != nil {
 		return nil, err
 	}
 
-	// 提取用户有权访问的菜单ID
+	// 提取用户有权访问的菜单ID（含沿继承链从祖先角色继承的菜单）
+	menus, err := s.CasbinService.roleMenus(user.Roles)
+	if err != nil {
+		return nil, err
+	}
 	menuIDMap := make(map[uint]bool)
-	for _, role := range user.Roles {
-		for _, menu := range role.Menus {
-			menuIDMap[menu.ID] = true
-		}
+	for _, menu := range menus {
+		menuIDMap[menu.ID] = true
 	}
 
 	// 获取所有菜单
@@ -0,0 +1,432 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+const (
+	totpPeriod = 30 // TOTP 时间步长（秒）
+	totpDigits = 6  // 验证码位数
+	totpSkew   = 1  // 允许前后各偏移的时间步数，容忍时钟误差
+
+	mfaRecoveryCodeCount    = 10 // 每次生成的恢复码数量
+	mfaMaxChallengeAttempts = 5  // 单个挑战令牌允许的验证失败次数
+)
+
+var (
+	// ErrInvalidMFACode 验证码或恢复码错误
+	ErrInvalidMFACode = errors.New("invalid mfa code")
+	// ErrMFANotEnrolled 用户尚未开始绑定 MFA
+	ErrMFANotEnrolled = errors.New("mfa is not enrolled")
+	// ErrMFAAlreadyEnabled 用户已启用 MFA
+	ErrMFAAlreadyEnabled = errors.New("mfa is already enabled")
+	// ErrInvalidMFAChallenge 挑战令牌不存在、已过期、已使用或失败次数过多
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa challenge")
+)
+
+// MFAService 两步验证服务 (TOTP, RFC 6238)
+type MFAService struct {
+	DB                  *gorm.DB
+	CasbinService       *CasbinService // 解析用户的有效角色（super_admin 策略）
+	SecretBox           *SecretBox     // 加密保存 TOTP 密钥，未配置时明文保存
+	ChallengeExpireTime time.Duration
+}
+
+// NewMFAService 创建两步验证服务实例
+func NewMFAService(db *gorm.DB, casbinService *CasbinService, challengeExpireTime time.Duration) *MFAService {
+	return &MFAService{
+		DB:                  db,
+		CasbinService:       casbinService,
+		ChallengeExpireTime: challengeExpireTime,
+	}
+}
+
+// IsRequired 判断用户登录时是否需要两步验证，以及是否需要先完成绑定
+func (s *MFAService) IsRequired(user *model.User, app *model.Application) (required bool, enrollmentRequired bool) {
+	if user.MFAEnabled {
+		return true, false
+	}
+
+	switch app.MFAPolicy {
+	case model.MFAPolicyAll:
+		return true, true
+	case model.MFAPolicySuperAdmin:
+		// 与鉴权一致：超级管理员身份可来自直接角色、用户组、部门以及沿继承链继承的角色
+		superAdmin, err := s.CasbinService.IsSuperAdmin(user.ID)
+		if err != nil {
+			// 无法确定身份时按需要两步验证处理
+			Log.Errorf("MFA: Failed to resolve roles: %v, userID=%d", err, user.ID)
+			return true, true
+		}
+		if superAdmin {
+			return true, true
+		}
+	}
+
+	return false, false
+}
+
+// BeginEnrollment 开始绑定：生成新的 TOTP 密钥（待确认），返回密钥与 otpauth URI
+func (s *MFAService) BeginEnrollment(user *model.User, issuer string) (string, string, error) {
+	if user.MFAEnabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	stored, err := s.sealTOTPSecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.DB.Model(&model.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": stored, "totp_last_step": 0}).Error; err != nil {
+		return "", "", fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	user.TOTPSecret = stored
+
+	return secret, TOTPURI(issuer, user.Username, secret), nil
+}
+
+// TOTPSecret 返回用户 TOTP 密钥的明文（Base32），数据库中保存的是服务端密钥加密后的密文
+func (s *MFAService) TOTPSecret(user *model.User) (string, error) {
+	if s.SecretBox == nil || user.TOTPSecret == "" {
+		return user.TOTPSecret, nil
+	}
+	secret, err := s.SecretBox.Open(user.TOTPSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	return secret, nil
+}
+
+// sealTOTPSecret 加密待保存的 TOTP 密钥
+func (s *MFAService) sealTOTPSecret(secret string) (string, error) {
+	if s.SecretBox == nil {
+		return secret, nil
+	}
+	stored, err := s.SecretBox.Seal(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+	return stored, nil
+}
+
+// MigrateTOTPSecrets 将明文保存的 TOTP 密钥（升级前绑定）加密保存，已加密的密钥保持不变
+func MigrateTOTPSecrets(db *gorm.DB, box *SecretBox) (int, error) {
+	var users []*model.User
+	if err := db.Unscoped().Select("id", "totp_secret").Where("totp_secret <> ''").Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, user := range users {
+		if _, err := box.Open(user.TOTPSecret); err == nil {
+			continue
+		}
+		stored, err := box.Seal(user.TOTPSecret)
+		if err != nil {
+			return migrated, err
+		}
+		if err := db.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).Update("totp_secret", stored).Error; err != nil {
+			return migrated, fmt.Errorf("failed to encrypt totp secret of user %d: %w", user.ID, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// ConfirmEnrollment 确认绑定：校验首个验证码后启用 MFA，并返回一组新的恢复码
+func (s *MFAService) ConfirmEnrollment(user *model.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	secret, err := s.TOTPSecret(user)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"mfa_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	user.MFAEnabled = true
+	user.TOTPLastStep = step
+	return codes, nil
+}
+
+// Disable 关闭用户的 MFA，同时删除全部恢复码
+func (s *MFAService) Disable(userID uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"mfa_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func (s *MFAService) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的一组
+func (s *MFAService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+		code := encoded[:4] + "-" + encoded[4:]
+		if err := tx.Create(&model.MFARecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRefreshToken(normalizeRecoveryCode(code)),
+		}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode 统一恢复码格式（忽略大小写、空格与连字符）
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// Verify 校验已启用 MFA 用户的 TOTP 验证码或恢复码
+func (s *MFAService) Verify(user *model.User, code string) error {
+	if !user.MFAEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		secret, err := s.TOTPSecret(user)
+		if err != nil {
+			return err
+		}
+		step, ok := ValidateTOTP(secret, code, time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		// 条件更新：同一时间步内的验证码只能使用一次
+		result := s.DB.Model(&model.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	result := s.DB.Model(&model.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRefreshToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// CountRecoveryCodes 返回未使用的恢复码数量
+func (s *MFAService) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := s.DB.Model(&model.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// CreateChallenge 密码校验通过后签发 MFA 挑战令牌
+func (s *MFAService) CreateChallenge(userID, appID uint, loginAction string) (string, error) {
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate mfa challenge: %w", err)
+	}
+
+	challenge := &model.MFAChallenge{
+		TokenHash:   hashRefreshToken(raw),
+		UserID:      userID,
+		AppID:       appID,
+		LoginAction: loginAction,
+		ExpiresAt:   time.Now().Add(s.ChallengeExpireTime),
+	}
+	if err := s.DB.Create(challenge).Error; err != nil {
+		return "", fmt.Errorf("failed to save mfa challenge: %w", err)
+	}
+
+	return raw, nil
+}
+
+// GetChallenge 查询仍然有效的挑战令牌
+func (s *MFAService) GetChallenge(raw string) (*model.MFAChallenge, error) {
+	var challenge model.MFAChallenge
+	if err := s.DB.Where("token_hash = ?", hashRefreshToken(raw)).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAChallenge
+		}
+		return nil, err
+	}
+
+	if challenge.UsedAt != nil || challenge.Attempts >= mfaMaxChallengeAttempts || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	return &challenge, nil
+}
+
+// CompleteChallenge 使用验证码完成挑战。
+// 已启用 MFA 的用户校验验证码或恢复码；处于绑定流程中的用户同时确认绑定并返回恢复码。
+// 验证失败会累计失败次数，超过上限后挑战令牌作废
+func (s *MFAService) CompleteChallenge(challenge *model.MFAChallenge, user *model.User, code string) ([]string, error) {
+	var (
+		recoveryCodes []string
+		err           error
+	)
+	if user.MFAEnabled {
+		err = s.Verify(user, code)
+	} else {
+		recoveryCodes, err = s.ConfirmEnrollment(user, code)
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.DB.Model(&model.MFAChallenge{}).Where("id = ?", challenge.ID).
+				Update("attempts", gorm.Expr("attempts + ?", 1))
+		}
+		return nil, err
+	}
+
+	// 条件更新，保证挑战令牌只能使用一次
+	result := s.DB.Model(&model.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	return recoveryCodes, nil
+}
+
+// CleanupExpired 清理过期的挑战令牌
+func (s *MFAService) CleanupExpired() error {
+	return s.DB.Where("expires_at < ?", time.Now()).Delete(&model.MFAChallenge{}).Error
+}
+
+// TOTPURI 生成 otpauth URI，可转换为二维码供 Google Authenticator 等应用扫描
+func TOTPURI(issuer, account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprintf("%d", totpDigits))
+	values.Set("period", fmt.Sprintf("%d", totpPeriod))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// GenerateTOTP 计算指定时间的 TOTP 验证码
+func GenerateTOTP(secret string, at time.Time) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return totpCode(key, at.Unix()/totpPeriod), nil
+}
+
+// ValidateTOTP 校验 TOTP 验证码，成功时返回匹配的时间步
+func ValidateTOTP(secret, code string, at time.Time) (int64, bool) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := at.Unix() / totpPeriod
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		step := current + offset
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode HOTP 算法 (RFC 4226)
+func totpCode(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestGenerateTOTPMatchesRFC6238(t *testing.T) {
+	// RFC 6238 附录 B 的 SHA1 测试向量（取 8 位结果的后 6 位）
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // base32("12345678901234567890")
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range cases {
+		code, err := GenerateTOTP(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("failed to generate totp: %v", err)
+		}
+		if code != expected {
+			t.Fatalf("unexpected totp at %d: got %s, want %s", unix, code, expected)
+		}
+	}
+}
+
+func TestMFAEnrollmentVerifyAndRecoveryCodes(t *testing.T) {
+	db := newTestDB(t)
+	mfaService := NewMFAService(db, nil, time.Minute)
+	secretBox, err := NewSecretBox(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create secret box: %v", err)
+	}
+	mfaService.SecretBox = secretBox
+
+	user := &model.User{Username: "u", Password: "p", Status: 1, AppID: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	secret, uri, err := mfaService.BeginEnrollment(user, "Authos")
+	if err != nil || secret == "" || uri == "" {
+		t.Fatalf("failed to begin enrollment: secret=%q uri=%q err=%v", secret, uri, err)
+	}
+
+	// 数据库中只保存加密后的密钥
+	var stored model.User
+	db.First(&stored, user.ID)
+	if stored.TOTPSecret == "" || stored.TOTPSecret == secret {
+		t.Fatalf("expected totp secret to be stored encrypted, got %q", stored.TOTPSecret)
+	}
+
+	if _, err := mfaService.ConfirmEnrollment(user, "abcdef"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+	}
+
+	code, _ := GenerateTOTP(secret, time.Now())
+	recoveryCodes, err := mfaService.ConfirmEnrollment(user, code)
+	if err != nil {
+		t.Fatalf("failed to confirm enrollment: %v", err)
+	}
+	if !user.MFAEnabled || len(recoveryCodes) != mfaRecoveryCodeCount {
+		t.Fatalf("unexpected enrollment result: enabled=%v codes=%d", user.MFAEnabled, len(recoveryCodes))
+	}
+
+	// 同一时间步内的验证码不能重复使用
+	if err := mfaService.Verify(user, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("expected replayed code to be rejected, got %v", err)
+	}
+	next, _ := GenerateTOTP(secret, time.Now().Add(totpPeriod*time.Second))
+	if err := mfaService.Verify(user, next); err != nil {
+		t.Fatalf("expected next step code to be accepted, got %v", err)
+	}
+
+	// 恢复码只能使用一次，且忽略大小写
+	if err := mfaService.Verify(user, " "+recoveryCodes[0]+" "); err != nil {
+		t.Fatalf("expected recovery code to be accepted, got %v", err)
+	}
+	if err := mfaService.Verify(user, recoveryCodes[0]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("expected used recovery code to be rejected, got %v", err)
+	}
+	if remaining, _ := mfaService.CountRecoveryCodes(user.ID); remaining != mfaRecoveryCodeCount-1 {
+		t.Fatalf("unexpected remaining recovery codes: %d", remaining)
+	}
+
+	if err := mfaService.Disable(user.ID); err != nil {
+		t.Fatalf("failed to disable mfa: %v", err)
+	}
+	if remaining, _ := mfaService.CountRecoveryCodes(user.ID); remaining != 0 {
+		t.Fatalf("expected recovery codes to be removed, got %d", remaining)
+	}
+}
+
+func TestMFAPolicyAndChallengeAttempts(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	mfaService := NewMFAService(db, casbinService, time.Minute)
+
+	app := &model.Application{Name: "mfa-app", Code: "mfa-app", Status: 1, MFAPolicy: model.MFAPolicySuperAdmin}
+	db.Create(app)
+	adminRole := &model.Role{Name: "admin", AppID: app.ID, IsSuperAdmin: true}
+	db.Create(adminRole)
+	superAdmin := &model.User{Username: "admin", Password: "p", Status: 1, AppID: app.ID}
+	normal := &model.User{Username: "normal", Password: "p", Status: 1, AppID: app.ID}
+	db.Create(superAdmin)
+	db.Create(normal)
+	db.Model(superAdmin).Association("Roles").Append(adminRole)
+
+	if required, _ := mfaService.IsRequired(normal, app); required {
+		t.Fatal("normal user should not require mfa under super_admin policy")
+	}
+	if required, enroll := mfaService.IsRequired(superAdmin, app); !required || !enroll {
+		t.Fatalf("super admin should require enrollment: required=%v enroll=%v", required, enroll)
+	}
+	app.MFAPolicy = model.MFAPolicyOff
+	normal.MFAEnabled = true
+	if required, enroll := mfaService.IsRequired(normal, app); !required || enroll {
+		t.Fatalf("enabled user should always require mfa: required=%v enroll=%v", required, enroll)
+	}
+
+	user := &model.User{Username: "u", Password: "p", Status: 1, AppID: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	secret, _, err := mfaService.BeginEnrollment(user, "Authos")
+	if err != nil {
+		t.Fatalf("failed to begin enrollment: %v", err)
+	}
+
+	token, err := mfaService.CreateChallenge(user.ID, 1, "LOGIN")
+	if err != nil {
+		t.Fatalf("failed to create challenge: %v", err)
+	}
+
+	// 连续失败达到上限后挑战令牌作废
+	for i := 0; i < mfaMaxChallengeAttempts; i++ {
+		challenge, err := mfaService.GetChallenge(token)
+		if err != nil {
+			t.Fatalf("challenge should still be valid after %d attempts: %v", i, err)
+		}
+		if _, err := mfaService.CompleteChallenge(challenge, user, "abcdef"); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+		}
+	}
+	if _, err := mfaService.GetChallenge(token); !errors.Is(err, ErrInvalidMFAChallenge) {
+		t.Fatalf("expected challenge to be exhausted, got %v", err)
+	}
+
+	// 新挑战：绑定流程中提交正确验证码会同时完成绑定，且挑战只能使用一次
+	token, _ = mfaService.CreateChallenge(user.ID, 1, "LOGIN")
+	challenge, err := mfaService.GetChallenge(token)
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+	code, _ := GenerateTOTP(secret, time.Now())
+	recoveryCodes, err := mfaService.CompleteChallenge(challenge, user, code)
+	if err != nil || len(recoveryCodes) == 0 || !user.MFAEnabled {
+		t.Fatalf("expected enrollment to complete: codes=%d enabled=%v err=%v", len(recoveryCodes), user.MFAEnabled, err)
+	}
+	if _, err := mfaService.GetChallenge(token); !errors.Is(err, ErrInvalidMFAChallenge) {
+		t.Fatalf("expected used challenge to be rejected, got %v", err)
+	}
+}
+
+func TestMFASuperAdminPolicyUsesEffectiveRoles(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	roleService := NewRoleService(db, casbinService)
+	departmentService := NewDepartmentService(db)
+	userGroupService := NewUserGroupService(db)
+	mfaService := NewMFAService(db, casbinService, time.Minute)
+
+	app := &model.Application{Name: "mfa-effective", Code: "mfa-effective", Status: 1, MFAPolicy: model.MFAPolicySuperAdmin}
+	db.Create(app)
+	superRole := &model.Role{Name: "super", AppID: app.ID, IsSuperAdmin: true}
+	childRole := &model.Role{Name: "child", AppID: app.ID}
+	db.Create(superRole)
+	db.Create(childRole)
+	if err := roleService.SetRoleParents(childRole.ID, app.ID, []uint{superRole.ID}); err != nil {
+		t.Fatalf("failed to set role parents: %v", err)
+	}
+
+	newUser := func(name string) *model.User {
+		user := &model.User{Username: name, Password: "p", Status: 1, AppID: app.ID}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		return user
+	}
+
+	// 继承：持有子角色，经父角色获得超级管理员身份
+	inherited := newUser("inherited")
+	db.Model(inherited).Association("Roles").Append(childRole)
+
+	// 部门：部门被授予超级管理员角色
+	viaDepartment := newUser("via-department")
+	dept := &model.Department{Name: "ops", AppID: app.ID}
+	if err := departmentService.CreateDepartment(dept); err != nil {
+		t.Fatalf("failed to create department: %v", err)
+	}
+	if err := departmentService.SetDepartmentRoles(dept.ID, app.ID, []uint{superRole.ID}); err != nil {
+		t.Fatalf("failed to set department roles: %v", err)
+	}
+	if err := departmentService.SetUserDepartments(viaDepartment.ID, app.ID, []uint{dept.ID}); err != nil {
+		t.Fatalf("failed to set user departments: %v", err)
+	}
+
+	// 用户组：用户组被授予继承了超级管理员的角色
+	viaGroup := newUser("via-group")
+	group := &model.UserGroup{Name: "admins", AppID: app.ID}
+	if err := userGroupService.CreateUserGroup(group); err != nil {
+		t.Fatalf("failed to create user group: %v", err)
+	}
+	if err := userGroupService.SetUserGroupRoles(group.ID, app.ID, []uint{childRole.ID}); err != nil {
+		t.Fatalf("failed to set user group roles: %v", err)
+	}
+	if err := userGroupService.AddUserGroupMembers(group.ID, app.ID, []uint{viaGroup.ID}); err != nil {
+		t.Fatalf("failed to add user group members: %v", err)
+	}
+
+	for _, user := range []*model.User{inherited, viaDepartment, viaGroup} {
+		if required, enroll := mfaService.IsRequired(user, app); !required || !enroll {
+			t.Fatalf("%s should require enrollment: required=%v enroll=%v", user.Username, required, enroll)
+		}
+	}
+	if required, _ := mfaService.IsRequired(newUser("plain"), app); required {
+		t.Fatal("user without super admin role should not require mfa")
+	}
+}
+
+func TestMigrateTOTPSecrets(t *testing.T) {
+	db := newTestDB(t)
+	secretBox, err := NewSecretBox(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create secret box: %v", err)
+	}
+	mfaService := NewMFAService(db, nil, time.Minute)
+	mfaService.SecretBox = secretBox
+
+	// 升级前明文保存的密钥
+	legacy := &model.User{Username: "legacy", Password: "p", Status: 1, AppID: 1, MFAEnabled: true, TOTPSecret: "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"}
+	if err := db.Create(legacy).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	migrated, err := MigrateTOTPSecrets(db, secretBox)
+	if err != nil || migrated != 1 {
+		t.Fatalf("unexpected migration result: %d, %v", migrated, err)
+	}
+	var stored model.User
+	db.First(&stored, legacy.ID)
+	if stored.TOTPSecret == legacy.TOTPSecret {
+		t.Fatal("expected plaintext totp secret to be encrypted")
+	}
+	code, _ := GenerateTOTP(legacy.TOTPSecret, time.Now())
+	if err := mfaService.Verify(&stored, code); err != nil {
+		t.Fatalf("expected migrated secret to keep working, got %v", err)
+	}
+
+	if migrated, err := MigrateTOTPSecrets(db, secretBox); err != nil || migrated != 0 {
+		t.Fatalf("expected migration to be idempotent, got %d, %v", migrated, err)
+	}
+}
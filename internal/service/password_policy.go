@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"Authos/internal/model"
+)
+
+// passwordHistoryLimit 每个用户最多保留的历史密码数量（策略中的历史次数不能超过该值）
+const passwordHistoryLimit = 24
+
+//go:embed common_passwords.txt
+var builtinCommonPasswords string
+
+var (
+	commonPasswordsMu sync.RWMutex
+	commonPasswords   = parsePasswordDictionary(builtinCommonPasswords)
+)
+
+// PasswordPolicyError 密码不符合应用的密码策略，Violations 为全部未满足的规则
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+// IsPasswordPolicyError 判断错误是否为密码策略校验失败，返回未满足的规则
+func IsPasswordPolicyError(err error) ([]string, bool) {
+	var policyErr *PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Violations, true
+	}
+	return nil, false
+}
+
+// parsePasswordDictionary 解析弱密码字典（每行一个，忽略空行与 # 注释）
+func parsePasswordDictionary(content string) map[string]bool {
+	dictionary := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dictionary[strings.ToLower(line)] = true
+	}
+	return dictionary
+}
+
+// LoadPasswordDictionary 从文件追加弱密码 / 泄露密码字典，返回追加后的字典大小
+func LoadPasswordDictionary(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read password dictionary: %w", err)
+	}
+
+	commonPasswordsMu.Lock()
+	defer commonPasswordsMu.Unlock()
+	for password := range parsePasswordDictionary(string(data)) {
+		commonPasswords[password] = true
+	}
+	return len(commonPasswords), nil
+}
+
+// isCommonPassword 判断密码是否在弱密码字典中（忽略大小写）
+func isCommonPassword(password string) bool {
+	commonPasswordsMu.RLock()
+	defer commonPasswordsMu.RUnlock()
+	return commonPasswords[strings.ToLower(password)]
+}
+
+// passwordCharClasses 统计密码包含的字符类别数（小写字母、大写字母、数字、符号）
+func passwordCharClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			count++
+		}
+	}
+	return count
+}
+
+// CheckPasswordStrength 按应用的密码策略校验密码强度（长度、字符类别、弱密码字典），不包含历史密码校验
+func CheckPasswordStrength(app *model.Application, username, password string) error {
+	var violations []string
+
+	if length := len([]rune(password)); length < app.PasswordMinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", app.PasswordMinLength))
+	}
+	if len(password) > 72 {
+		// bcrypt 只使用前 72 字节
+		violations = append(violations, "must not exceed 72 bytes")
+	}
+	if classes := passwordCharClasses(password); classes < app.PasswordMinClasses {
+		violations = append(violations, fmt.Sprintf("must contain at least %d of: lowercase letters, uppercase letters, digits, symbols", app.PasswordMinClasses))
+	}
+	if app.PasswordCheckCommon {
+		if isCommonPassword(password) {
+			violations = append(violations, "is too common")
+		}
+		if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+			violations = append(violations, "must not contain the username")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// PasswordExpired 判断用户密码是否已超过应用配置的有效期
+func PasswordExpired(app *model.Application, user *model.User) bool {
+	if app.PasswordMaxAgeDays <= 0 {
+		return false
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(app.PasswordMaxAgeDays)*24*time.Hour
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestCheckPasswordStrength(t *testing.T) {
+	app := &model.Application{PasswordMinLength: 8, PasswordMinClasses: 3, PasswordCheckCommon: true}
+
+	cases := map[string]int{
+		"test":             3, // 过短、字符类别不足、常见弱密码
+		"Password123":      1, // 常见弱密码（忽略大小写）
+		"alice-Secret9":    1, // 包含用户名
+		"abcdefgh12":       1, // 字符类别不足
+		"Correct-Horse-42": 0,
+	}
+	for password, expected := range cases {
+		err := CheckPasswordStrength(app, "Alice", password)
+		violations, _ := IsPasswordPolicyError(err)
+		if len(violations) != expected {
+			t.Fatalf("password %q: expected %d violations, got %v", password, expected, violations)
+		}
+	}
+}
+
+func TestPasswordPolicyOnCreateAndUpdate(t *testing.T) {
+	db := newTestDB(t)
+	userService := NewUserService(db, nil)
+
+	app := &model.Application{Name: "app", Code: "app", SecretKey: "secret", PasswordMinLength: 10, PasswordMinClasses: 2, PasswordHistory: 2}
+	if err := db.Create(app).Error; err != nil {
+		t.Fatalf("failed to create application: %v", err)
+	}
+
+	if err := userService.CreateUser(&model.User{Username: "bob", Password: "123456", AppID: app.ID}); err == nil {
+		t.Fatal("expected weak password to be rejected on create")
+	}
+
+	user := &model.User{Username: "bob", Password: "first-Password", AppID: app.ID}
+	if err := userService.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// 当前密码与上一个密码都不能重复使用（历史 2 次）
+	if err := userService.UpdateUserPassword(user.ID, app.ID, "first-Password"); err == nil {
+		t.Fatal("expected current password to be rejected")
+	}
+	if err := userService.UpdateUserPassword(user.ID, app.ID, "second-Password"); err != nil {
+		t.Fatalf("failed to update password: %v", err)
+	}
+	if _, ok := IsPasswordPolicyError(userService.UpdateUserPassword(user.ID, app.ID, "first-Password")); !ok {
+		t.Fatal("expected previous password to be rejected by history")
+	}
+	if err := userService.UpdateUserPassword(user.ID, app.ID, "third-Password"); err != nil {
+		t.Fatalf("failed to update password: %v", err)
+	}
+	if err := userService.UpdateUserPassword(user.ID, app.ID, "first-Password"); err != nil {
+		t.Fatalf("expected password older than history to be accepted, got %v", err)
+	}
+
+	// 其他应用的管理员不能修改该用户密码
+	if err := userService.UpdateUserPassword(user.ID, app.ID+1, "fourth-Password"); err == nil {
+		t.Fatal("expected cross-application password update to fail")
+	}
+}
+
+func TestPasswordExpired(t *testing.T) {
+	app := &model.Application{PasswordMaxAgeDays: 30}
+	old := time.Now().Add(-31 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	user := &model.User{PasswordChangedAt: &recent}
+	if PasswordExpired(app, user) {
+		t.Fatal("recently changed password should not be expired")
+	}
+	user.PasswordChangedAt = &old
+	if !PasswordExpired(app, user) {
+		t.Fatal("expected password to be expired")
+	}
+
+	// 从未修改过密码的用户以创建时间计算
+	user = &model.User{}
+	user.CreatedAt = old
+	if !PasswordExpired(app, user) {
+		t.Fatal("expected password without change time to expire based on creation time")
+	}
+
+	app.PasswordMaxAgeDays = 0
+	if PasswordExpired(app, user) {
+		t.Fatal("password should never expire when max age is 0")
+	}
+}
+
+func TestSeedRejectsWeakAdminPassword(t *testing.T) {
+	db := newTestDB(t)
+
+	weak := &Config{System: SystemConfig{AdminUsername: "admin", AdminPassword: "123456"}}
+	if err := seedData(db, weak); err == nil {
+		t.Fatal("expected weak admin password to be rejected")
+	}
+	// 校验失败时不留下不完整的种子数据，修改配置后可以重新初始化
+	var count int64
+	db.Model(&model.Application{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no seed data after rejection, got %d applications", count)
+	}
+
+	strong := &Config{System: SystemConfig{AdminUsername: "admin", AdminPassword: "Str0ng-Pass-9x"}}
+	if err := seedData(db, strong); err != nil {
+		t.Fatalf("expected strong admin password to be accepted: %v", err)
+	}
+	var admin model.User
+	if err := db.Where("username = ?", "admin").First(&admin).Error; err != nil {
+		t.Fatalf("expected admin user to be created: %v", err)
+	}
+}
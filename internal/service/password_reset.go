@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+// ErrInvalidResetToken 重置令牌不存在、已过期、已使用或已被新令牌取代
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+// PasswordResetService 密码重置服务（管理员签发一次性重置令牌，用户凭令牌设置新密码）
+type PasswordResetService struct {
+	DB         *gorm.DB
+	ExpireTime time.Duration
+	ResetURL   string // 重置密码页面地址，为空时不生成链接
+}
+
+// NewPasswordResetService 创建密码重置服务实例
+func NewPasswordResetService(db *gorm.DB, expireTime time.Duration, resetURL string) *PasswordResetService {
+	return &PasswordResetService{
+		DB:         db,
+		ExpireTime: expireTime,
+		ResetURL:   resetURL,
+	}
+}
+
+// CreateToken 为用户签发重置令牌，同一用户之前未使用的令牌全部失效
+func (s *PasswordResetService) CreateToken(user *model.User, createdBy uint) (string, *model.PasswordResetToken, error) {
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	token := &model.PasswordResetToken{
+		TokenHash: hashRefreshToken(raw),
+		AppID:     user.AppID,
+		UserID:    user.ID,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(s.ExpireTime),
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&model.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to save reset token: %w", err)
+	}
+
+	return raw, token, nil
+}
+
+// GetValidToken 查询未使用且未过期的重置令牌
+func (s *PasswordResetService) GetValidToken(raw string) (*model.PasswordResetToken, error) {
+	var token model.PasswordResetToken
+	if err := s.DB.Where("token_hash = ?", hashRefreshToken(raw)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidResetToken
+		}
+		return nil, err
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidResetToken
+	}
+	return &token, nil
+}
+
+// MarkUsed 标记重置令牌已使用（条件更新，保证只能使用一次）
+func (s *PasswordResetService) MarkUsed(token *model.PasswordResetToken) error {
+	return s.MarkUsedTx(s.DB, token)
+}
+
+// MarkUsedTx 在指定事务中标记重置令牌已使用，令牌已被使用时返回 ErrInvalidResetToken
+func (s *PasswordResetService) MarkUsedTx(tx *gorm.DB, token *model.PasswordResetToken) error {
+	now := time.Now()
+	result := tx.Model(&model.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidResetToken
+	}
+	token.UsedAt = &now
+	return nil
+}
+
+// ResetLink 生成重置密码链接（未配置重置页面地址时返回空）
+func (s *PasswordResetService) ResetLink(raw string) string {
+	if s.ResetURL == "" {
+		return ""
+	}
+	u, err := url.Parse(s.ResetURL)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("token", raw)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// CleanupExpired 清理过期的重置令牌
+func (s *PasswordResetService) CleanupExpired() error {
+	return s.DB.Where("expires_at < ?", time.Now()).Delete(&model.PasswordResetToken{}).Error
+}
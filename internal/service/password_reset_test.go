@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	db := newTestDB(t)
+	passwordResetService := NewPasswordResetService(db, 30*time.Minute, "https://app.example.com/reset?lang=zh")
+
+	user := &model.User{Username: "alice", Password: "hash", AppID: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	first, _, err := passwordResetService.CreateToken(user, 1)
+	if err != nil {
+		t.Fatalf("failed to create reset token: %v", err)
+	}
+	second, _, err := passwordResetService.CreateToken(user, 1)
+	if err != nil {
+		t.Fatalf("failed to create reset token: %v", err)
+	}
+
+	// 签发新令牌后旧令牌失效
+	if _, err := passwordResetService.GetValidToken(first); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected superseded token to be invalid, got %v", err)
+	}
+
+	token, err := passwordResetService.GetValidToken(second)
+	if err != nil || token.UserID != user.ID {
+		t.Fatalf("expected valid token for user, got %+v, err=%v", token, err)
+	}
+	if err := passwordResetService.MarkUsed(token); err != nil {
+		t.Fatalf("failed to mark token used: %v", err)
+	}
+	if err := passwordResetService.MarkUsed(token); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected second use to fail, got %v", err)
+	}
+	if _, err := passwordResetService.GetValidToken(second); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected used token to be invalid, got %v", err)
+	}
+
+	link := passwordResetService.ResetLink(second)
+	if !strings.HasPrefix(link, "https://app.example.com/reset?") || !strings.Contains(link, "lang=zh") || !strings.Contains(link, "token="+second) {
+		t.Fatalf("unexpected reset link: %s", link)
+	}
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	db := newTestDB(t)
+	passwordResetService := NewPasswordResetService(db, -time.Minute, "")
+
+	user := &model.User{Username: "bob", Password: "hash", AppID: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	raw, _, err := passwordResetService.CreateToken(user, 1)
+	if err != nil {
+		t.Fatalf("failed to create reset token: %v", err)
+	}
+	if _, err := passwordResetService.GetValidToken(raw); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected expired token to be invalid, got %v", err)
+	}
+	if link := passwordResetService.ResetLink(raw); link != "" {
+		t.Fatalf("expected no link without reset url, got %s", link)
+	}
+}
+
+func TestResetUserPasswordClaimsTokenOnce(t *testing.T) {
+	db := newTestDB(t)
+	passwordResetService := NewPasswordResetService(db, 30*time.Minute, "")
+	userService := NewUserService(db, nil)
+
+	app := &model.Application{Name: "reset-app", Code: "reset-app", Status: 1, PasswordMinLength: 8}
+	db.Create(app)
+	user := &model.User{Username: "carol", Password: "hash", Status: 1, AppID: app.ID}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	raw, _, err := passwordResetService.CreateToken(user, 1)
+	if err != nil {
+		t.Fatalf("failed to create reset token: %v", err)
+	}
+
+	// 两个并发请求都在认领前读到了有效令牌
+	first, err := passwordResetService.GetValidToken(raw)
+	if err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	second, err := passwordResetService.GetValidToken(raw)
+	if err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+
+	// 不符合密码策略时不认领令牌
+	claim := func(token *model.PasswordResetToken) func(tx *gorm.DB) error {
+		return func(tx *gorm.DB) error { return passwordResetService.MarkUsedTx(tx, token) }
+	}
+	if _, ok := IsPasswordPolicyError(userService.ResetUserPassword(user.ID, app.ID, "short", claim(first))); !ok {
+		t.Fatal("expected weak password to be rejected")
+	}
+	if err := userService.ResetUserPassword(user.ID, app.ID, "first-Password", claim(first)); err != nil {
+		t.Fatalf("expected first reset to succeed: %v", err)
+	}
+	if err := userService.ResetUserPassword(user.ID, app.ID, "second-Password", claim(second)); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected second reset with the same token to fail, got %v", err)
+	}
+
+	var stored model.User
+	db.First(&stored, user.ID)
+	if bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("first-Password")) != nil {
+		t.Fatal("expected password from the first reset to be kept")
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casbin/govaluate"
+
+	"Authos/internal/model"
+)
+
+// permissionConditionMaxLength 条件表达式的最大长度（与 casbin_rule 字段长度一致）
+const permissionConditionMaxLength = 100
+
+// ErrInvalidCondition 条件表达式无法解析
+var ErrInvalidCondition = errors.New("invalid permission condition")
+
+// PermissionContext 权限条件的求值上下文，由鉴权调用方传入
+type PermissionContext struct {
+	IP         string                 `json:"ip"`         // 终端用户IP
+	Time       time.Time              `json:"time"`       // 请求时间（RFC3339），为空时取服务器当前时间
+	Attributes map[string]interface{} `json:"attributes"` // 任意请求属性，在条件中直接以属性名引用
+}
+
+// conditionFunctions 条件表达式中可用的函数
+var conditionFunctions = map[string]govaluate.ExpressionFunction{
+	// ipMatch(ip, "10.0.0.0/8") 判断 IP 是否属于网段（或等于指定 IP）
+	"ipMatch": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, errors.New("ipMatch requires 2 arguments")
+		}
+		ipStr, _ := args[0].(string)
+		pattern, _ := args[1].(string)
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			return false, nil
+		}
+		if _, network, err := net.ParseCIDR(pattern); err == nil {
+			return network.Contains(ip), nil
+		}
+		return ip.Equal(net.ParseIP(pattern)), nil
+	},
+}
+
+// conditionCache 已解析的条件表达式
+var conditionCache sync.Map
+
+// compileCondition 解析条件表达式（带缓存）
+func compileCondition(condition string) (*govaluate.EvaluableExpression, error) {
+	if cached, ok := conditionCache.Load(condition); ok {
+		return cached.(*govaluate.EvaluableExpression), nil
+	}
+	expr, err := govaluate.NewEvaluableExpressionWithFunctions(condition, conditionFunctions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+	}
+	conditionCache.Store(condition, expr)
+	return expr, nil
+}
+
+// normalizeCondition 校验条件表达式，为空时返回 model.CONDITION_ALWAYS
+func normalizeCondition(condition string) (string, error) {
+	condition = strings.TrimSpace(condition)
+	if condition == "" || condition == model.CONDITION_ALWAYS {
+		return model.CONDITION_ALWAYS, nil
+	}
+	if len(condition) > permissionConditionMaxLength {
+		return "", fmt.Errorf("%w: cannot exceed %d characters", ErrInvalidCondition, permissionConditionMaxLength)
+	}
+	if _, err := compileCondition(condition); err != nil {
+		return "", err
+	}
+	return condition, nil
+}
+
+// conditionEnv 条件表达式的变量
+type conditionEnv map[string]interface{}
+
+// Get 实现 govaluate.Parameters，引用不存在的变量时报错
+func (e conditionEnv) Get(name string) (interface{}, error) {
+	value, ok := e[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s", name)
+	}
+	return value, nil
+}
+
+// newConditionEnv 构建条件变量：请求属性、ip、time（HH:MM）、date（YYYY-MM-DD）、hour、weekday（0 为周日）以及 user（id、username 与用户属性）
+func newConditionEnv(user *model.User, ctx *PermissionContext) conditionEnv {
+	env := conditionEnv{}
+	if ctx == nil {
+		ctx = &PermissionContext{}
+	}
+	for name, value := range ctx.Attributes {
+		env[name] = value
+	}
+
+	now := ctx.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	env["ip"] = ctx.IP
+	env["time"] = now.Format("15:04")
+	env["date"] = now.Format("2006-01-02")
+	env["hour"] = float64(now.Hour())
+	env["weekday"] = float64(now.Weekday())
+
+	userAttrs := map[string]interface{}{"id": float64(user.ID), "username": user.Username}
+	for name, value := range user.Attributes {
+		if _, builtin := userAttrs[name]; !builtin {
+			userAttrs[name] = value
+		}
+	}
+	env["user"] = userAttrs
+	return env
+}
+
+// conditionMatch Casbin 匹配器函数 conditionMatch(p.cond, p.eft, r.ctx)：
+// 条件成立时策略生效；条件无法求值（变量缺失、类型错误）时允许策略不生效、拒绝策略生效
+func conditionMatch(args ...interface{}) (interface{}, error) {
+	if len(args) != 3 {
+		return false, errors.New("conditionMatch requires 3 arguments")
+	}
+	condition, _ := args[0].(string)
+	if condition == "" || condition == model.CONDITION_ALWAYS {
+		return true, nil
+	}
+	failClosed := args[1] == model.EFFECT_DENY
+
+	env, ok := args[2].(conditionEnv)
+	if !ok {
+		return failClosed, nil
+	}
+	expr, err := compileCondition(condition)
+	if err != nil {
+		return failClosed, nil
+	}
+	result, err := expr.Eval(env)
+	if err != nil {
+		return failClosed, nil
+	}
+	matched, ok := result.(bool)
+	if !ok {
+		return failClosed, nil
+	}
+	return matched, nil
+}
+
+// isConditionalPolicy 判断策略是否附加了条件
+func isConditionalPolicy(policy []string) bool {
+	return len(policy) > 4 && policy[4] != "" && policy[4] != model.CONDITION_ALWAYS
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestConditionalPermissionRules(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+	userService := NewUserService(db, nil)
+
+	app := &model.Application{Name: "cond-app", Code: "cond-app", Status: 1}
+	db.Create(app)
+	role := &model.Role{Name: "clerk", AppID: app.ID}
+	db.Create(role)
+	user := &model.User{Username: "cond-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+
+	// 用户属性经 UpdateUser 写入并在条件中以 user.region 引用
+	user.RoleIDs = []uint{role.ID}
+	user.Attributes = map[string]string{"region": "cn"}
+	if err := userService.UpdateUser(user, app.ID); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+
+	create := func(key string) *model.ApiPermission {
+		permission, err := apiPermissionService.CreateApiPermission(app.ID, key, key, "/api/"+key, model.HTTP_ALL, "")
+		if err != nil {
+			t.Fatalf("failed to create api permission: %v", err)
+		}
+		return permission
+	}
+	report, office, regional := create("report.view"), create("office.view"), create("regional.view")
+
+	for _, rule := range []struct {
+		permission *model.ApiPermission
+		act        string
+		effect     string
+		condition  string
+	}{
+		{report, model.HTTP_ALL, model.EFFECT_ALLOW, `hour >= 9 && hour < 18 && weekday >= 1 && weekday <= 5`},
+		{office, model.HTTP_ALL, model.EFFECT_ALLOW, `ipMatch(ip, "10.0.0.0/8")`},
+		{regional, model.HTTP_ALL, model.EFFECT_ALLOW, `tenant_region == user.region`},
+		{regional, model.HTTP_DELETE, model.EFFECT_DENY, `tenant_region != user.region`},
+	} {
+		if err := apiPermissionService.AddApiPermissionRuleToRole(app.ID, role.UUID, rule.permission.UUID, rule.act, rule.effect, rule.condition); err != nil {
+			t.Fatalf("failed to add rule %s: %v", rule.condition, err)
+		}
+	}
+
+	if err := apiPermissionService.AddApiPermissionRuleToRole(app.ID, role.UUID, office.UUID, "GET", model.EFFECT_ALLOW, "hour >="); !errors.Is(err, ErrInvalidCondition) {
+		t.Fatalf("expected invalid condition rejected, got %v", err)
+	}
+
+	monday10 := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	monday20 := time.Date(2026, 10, 12, 20, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		obj  string
+		act  string
+		ctx  *PermissionContext
+		want bool
+	}{
+		{"business hours", "report.view", "GET", &PermissionContext{Time: monday10}, true},
+		{"after hours", "report.view", "GET", &PermissionContext{Time: monday20}, false},
+		{"office network", "office.view", "GET", &PermissionContext{IP: "10.1.2.3"}, true},
+		{"outside network", "office.view", "GET", &PermissionContext{IP: "192.168.1.2"}, false},
+		{"no context", "office.view", "GET", nil, false},
+		{"same region", "regional.view", "GET", &PermissionContext{Attributes: map[string]interface{}{"tenant_region": "cn"}}, true},
+		{"other region", "regional.view", "GET", &PermissionContext{Attributes: map[string]interface{}{"tenant_region": "us"}}, false},
+		{"delete in region", "regional.view", "DELETE", &PermissionContext{Attributes: map[string]interface{}{"tenant_region": "cn"}}, true},
+		// 条件无法求值时拒绝策略生效
+		{"delete without attribute", "regional.view", "DELETE", &PermissionContext{}, false},
+	}
+	for _, tc := range cases {
+		allowed, err := casbinService.CheckPermission(user.ID, tc.obj, tc.act, tc.ctx)
+		if err != nil {
+			t.Fatalf("%s: check permission error: %v", tc.name, err)
+		}
+		if allowed != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, allowed)
+		}
+	}
+
+	explanation, err := casbinService.ExplainPermission(user.ID, "report.view", "GET", &PermissionContext{Time: monday20})
+	if err != nil || explanation.Allowed {
+		t.Fatalf("unexpected explanation: %+v, err=%v", explanation, err)
+	}
+	if reason := explanation.Roles[0].Reason; reason != "role is granted report.view only when [hour >= 9 && hour < 18 && weekday >= 1 && weekday <= 5]" {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+
+	rules, err := apiPermissionService.GetApiPermissionRulesForRole(app.ID, role.UUID, model.EFFECT_DENY)
+	if err != nil || len(rules) != 1 || rules[0].Condition != `tenant_region != user.region` {
+		t.Fatalf("unexpected rules: %+v, err=%v", rules, err)
+	}
+
+	perms, err := apiPermissionService.GetUserPermissions(user.ID, app.ID)
+	if err != nil || len(perms.Apis) != 3 || !perms.Apis[0].Conditional {
+		t.Fatalf("unexpected user permissions: %+v, err=%v", perms, err)
+	}
+}
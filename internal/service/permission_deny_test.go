@@ -0,0 +1,137 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+
+	"Authos/internal/model"
+)
+
+func TestDenyPolicyOverridesAllow(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+	roleService := NewRoleService(db, casbinService)
+
+	app := &model.Application{Name: "deny-app", Code: "deny-app", Status: 1}
+	db.Create(app)
+	support := &model.Role{Name: "support", AppID: app.ID}
+	manager := &model.Role{Name: "manager", AppID: app.ID}
+	db.Create(support)
+	db.Create(manager)
+	user := &model.User{Username: "deny-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append(support)
+
+	permission, err := apiPermissionService.CreateApiPermission(app.ID, "user.manage", "用户管理", "/api/v1/users", model.HTTP_ALL, "")
+	if err != nil {
+		t.Fatalf("failed to create api permission: %v", err)
+	}
+	if err := apiPermissionService.AddApiPermissionToRole(app.ID, support.UUID, permission.UUID); err != nil {
+		t.Fatalf("failed to add permission to role: %v", err)
+	}
+	if err := apiPermissionService.AddApiPermissionRuleToRole(app.ID, support.UUID, permission.UUID, "delete", model.EFFECT_DENY, ""); err != nil {
+		t.Fatalf("failed to add deny rule: %v", err)
+	}
+	if err := apiPermissionService.AddApiPermissionRuleToRole(app.ID, support.UUID, permission.UUID, "TRACE", model.EFFECT_DENY, ""); err == nil {
+		t.Fatal("expected unsupported method rejected")
+	}
+
+	results, err := casbinService.CheckPermissions(user.ID, []PermissionRequest{
+		{Obj: "user.manage", Act: "GET"},
+		{Obj: "user.manage", Act: "DELETE"},
+	}, nil)
+	if err != nil || !results[0] || results[1] {
+		t.Fatalf("unexpected results %v, err=%v", results, err)
+	}
+
+	// 拒绝规则只列出拒绝策略，角色的允许权限不受影响
+	rules, err := apiPermissionService.GetApiPermissionRulesForRole(app.ID, support.UUID, model.EFFECT_DENY)
+	if err != nil || len(rules) != 1 || rules[0].Act != model.HTTP_DELETE || rules[0].PermissionUUID != permission.UUID {
+		t.Fatalf("unexpected deny rules: %+v, err=%v", rules, err)
+	}
+	allowed, _ := apiPermissionService.GetApiPermissionsForRole(app.ID, support.UUID)
+	if len(allowed) != 1 {
+		t.Fatalf("expected one allowed permission, got %d", len(allowed))
+	}
+
+	perms, err := apiPermissionService.GetUserPermissions(user.ID, app.ID)
+	if err != nil {
+		t.Fatalf("failed to get user permissions: %v", err)
+	}
+	want := UserApi{Key: "user.manage", Path: "/api/v1/users", Method: model.HTTP_ALL, DeniedMethods: []string{model.HTTP_DELETE}}
+	if len(perms.Apis) != 1 || !reflect.DeepEqual(perms.Apis[0], want) {
+		t.Fatalf("unexpected apis: %+v", perms.Apis)
+	}
+
+	// 其他角色的允许不能覆盖拒绝
+	if err := apiPermissionService.AddApiPermissionToRole(app.ID, manager.UUID, permission.UUID); err != nil {
+		t.Fatalf("failed to add permission to role: %v", err)
+	}
+	db.Model(user).Association("Roles").Append(manager)
+	if ok, _ := casbinService.CheckPermission(user.ID, "user.manage", "DELETE", nil); ok {
+		t.Fatal("expected deny to override allow from another role")
+	}
+	explanation, err := casbinService.ExplainPermission(user.ID, "user.manage", "DELETE", nil)
+	if err != nil || explanation.Allowed || explanation.Reason != "denied by role support: denied by policy" {
+		t.Fatalf("unexpected explanation: %+v, err=%v", explanation, err)
+	}
+
+	// 拒绝策略沿继承链生效
+	db.Model(user).Association("Roles").Replace(manager)
+	if err := roleService.SetRoleParents(manager.ID, app.ID, []uint{support.ID}); err != nil {
+		t.Fatalf("failed to set parents: %v", err)
+	}
+	if ok, _ := casbinService.CheckPermission(user.ID, "user.manage", "DELETE", nil); ok {
+		t.Fatal("expected inherited deny to apply")
+	}
+
+	// 超级管理员不受拒绝策略限制
+	manager.IsSuperAdmin = true
+	roleService.UpdateRole(manager)
+	if ok, _ := casbinService.CheckPermission(user.ID, "user.manage", "DELETE", nil); !ok {
+		t.Fatal("expected super admin to bypass deny policies")
+	}
+
+	// 移除拒绝规则后恢复允许
+	manager.IsSuperAdmin = false
+	roleService.UpdateRole(manager)
+	if err := apiPermissionService.RemoveApiPermissionRuleFromRole(app.ID, support.UUID, permission.UUID, "DELETE", model.EFFECT_DENY); err != nil {
+		t.Fatalf("failed to remove deny rule: %v", err)
+	}
+	if ok, _ := casbinService.CheckPermission(user.ID, "user.manage", "DELETE", nil); !ok {
+		t.Fatal("expected DELETE allowed after deny rule removed")
+	}
+}
+
+func TestLegacyPoliciesMigratedToAllow(t *testing.T) {
+	db := newTestDB(t)
+
+	app := &model.Application{Name: "legacy-app", Code: "legacy-app", Status: 1}
+	db.Create(app)
+	role := &model.Role{Name: "legacy", AppID: app.ID}
+	db.Create(role)
+	user := &model.User{Username: "legacy-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append(role)
+
+	// 旧版本写入的三元策略
+	if err := db.AutoMigrate(&gormadapter.CasbinRule{}); err != nil {
+		t.Fatalf("failed to migrate casbin table: %v", err)
+	}
+	db.Create(&gormadapter.CasbinRule{Ptype: "p", V0: "role:" + role.UUID, V1: "report.view", V2: model.HTTP_ALL})
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	if ok, err := casbinService.CheckPermission(user.ID, "report.view", "GET", nil); err != nil || !ok {
+		t.Fatalf("expected legacy policy to allow, got %v, err=%v", ok, err)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+
+	"Authos/internal/model"
+)
+
+func TestExplainApiPermissionMatch(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+
+	apiPermissionService.CreateApiPermission(1, "api.all", "全部接口", "/api", model.HTTP_ALL, "")
+	apiPermissionService.CreateApiPermission(1, "order.any", "订单", "/api/orders", model.HTTP_ALL, "")
+	apiPermissionService.CreateApiPermission(1, "order.read", "查看订单", "/api/orders", model.HTTP_GET, "")
+	apiPermissionService.CreateApiPermission(1, "order.create", "创建订单", "/api/orders", model.HTTP_POST, "")
+	apiPermissionService.CreateApiPermission(1, "user.read", "查看用户", "/api/users", model.HTTP_GET, "")
+
+	match, err := apiPermissionService.ExplainApiPermissionMatch(1, "/api/orders/7", "GET")
+	if err != nil {
+		t.Fatalf("failed to explain match: %v", err)
+	}
+	if match.Permission == nil || match.Permission.Key != "order.read" {
+		t.Fatalf("expected order.read to be selected, got %+v", match.Permission)
+	}
+	// 与普通匹配结果一致
+	permission, _ := apiPermissionService.GetApiPermissionByPathAndMethod(1, "/api/orders/7", "GET")
+	if permission.Key != match.Permission.Key {
+		t.Fatalf("explain selected %s, normal match selected %s", match.Permission.Key, permission.Key)
+	}
+
+	reasons := make(map[string]ApiPermissionCandidate)
+	for _, candidate := range match.Candidates {
+		reasons[candidate.Key] = candidate
+	}
+	if len(reasons) != 4 || !reasons["order.read"].Selected {
+		t.Fatalf("unexpected candidates: %+v", match.Candidates)
+	}
+	if _, ok := reasons["user.read"]; ok {
+		t.Fatal("expected non-prefix permission to be excluded from candidates")
+	}
+	for _, key := range []string{"api.all", "order.any", "order.create"} {
+		if reasons[key].Selected || reasons[key].Reason == "" {
+			t.Fatalf("expected %s to be rejected with a reason: %+v", key, reasons[key])
+		}
+	}
+
+	// 前缀匹配但方法不匹配
+	match, _ = apiPermissionService.ExplainApiPermissionMatch(1, "/api/users", "DELETE")
+	if match.Permission == nil || match.Permission.Key != "api.all" {
+		t.Fatalf("expected wildcard parent to be selected, got %+v", match.Permission)
+	}
+	match, _ = apiPermissionService.ExplainApiPermissionMatch(1, "/health", "GET")
+	if match.Permission != nil || match.Reason == "" {
+		t.Fatalf("expected no match with a reason, got %+v", match)
+	}
+}
+
+func TestExplainPermission(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+
+	user := &model.User{Username: "explain-user", Password: "password", Status: 1, AppID: 1}
+	db.Create(user)
+
+	explanation, err := casbinService.ExplainPermission(user.ID, "order.read", "GET", nil)
+	if err != nil || explanation.Allowed || explanation.Reason != "user has no roles" {
+		t.Fatalf("unexpected explanation for user without roles: %+v, err=%v", explanation, err)
+	}
+
+	reader := &model.Role{Name: "reader", AppID: 1}
+	writer := &model.Role{Name: "writer", AppID: 1}
+	db.Create(reader)
+	db.Create(writer)
+	db.Model(user).Association("Roles").Append([]*model.Role{reader, writer})
+	casbinService.AddPolicy("role:"+reader.UUID, "order.read", "GET")
+	casbinService.AddPolicy("role:"+writer.UUID, "order.write", model.HTTP_ALL)
+
+	// 放行时返回命中的策略
+	explanation, _ = casbinService.ExplainPermission(user.ID, "order.read", "GET", nil)
+	if !explanation.Allowed || len(explanation.Roles) != 2 {
+		t.Fatalf("expected order.read to be allowed: %+v", explanation)
+	}
+	for _, decision := range explanation.Roles {
+		if decision.RoleName == "reader" && (len(decision.Policy) != 5 || decision.Policy[1] != "order.read" || decision.Policy[3] != model.EFFECT_ALLOW) {
+			t.Fatalf("expected granting policy to be reported: %+v", decision)
+		}
+	}
+
+	// 方法不符、其他角色持有、无任何策略三种拒绝原因
+	explanation, _ = casbinService.ExplainPermission(user.ID, "order.read", "DELETE", nil)
+	if explanation.Allowed || explanation.Reason == "" {
+		t.Fatalf("expected method mismatch to be denied with a reason: %+v", explanation)
+	}
+	explanation, _ = casbinService.ExplainPermission(user.ID, "order.audit", "GET", nil)
+	if explanation.Allowed || explanation.Reason != "no role has any policy for order.audit" {
+		t.Fatalf("unexpected reason: %+v", explanation)
+	}
+
+	allowed, _ := casbinService.CheckPermission(user.ID, "order.write", "POST", nil)
+	explanation, _ = casbinService.ExplainPermission(user.ID, "order.write", "POST", nil)
+	if !allowed || !explanation.Allowed {
+		t.Fatal("expected explain to agree with CheckPermission")
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+var (
+	// ErrInvalidRefreshToken 刷新令牌不存在或不属于当前应用
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenExpired 刷新令牌已过期
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	// ErrRefreshTokenReused 已轮换或已吊销的刷新令牌被再次使用，整个令牌家族已被吊销
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshTokenService 刷新令牌服务
+type RefreshTokenService struct {
+	DB         *gorm.DB
+	ExpireTime time.Duration
+}
+
+// NewRefreshTokenService 创建刷新令牌服务实例
+func NewRefreshTokenService(db *gorm.DB, expireTime time.Duration) *RefreshTokenService {
+	return &RefreshTokenService{
+		DB:         db,
+		ExpireTime: expireTime,
+	}
+}
+
+// hashRefreshToken 计算刷新令牌的哈希，数据库中只保存哈希值
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRefreshToken 生成随机刷新令牌明文
+func generateRefreshToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// IssueRefreshToken 为一次新的登录签发刷新令牌（开启新的令牌家族）
+func (s *RefreshTokenService) IssueRefreshToken(userID, appID uint, ip string) (string, error) {
+	return s.issue(s.DB, userID, appID, uuid.New().String(), ip)
+}
+
+// issue 在指定家族中签发刷新令牌
+func (s *RefreshTokenService) issue(tx *gorm.DB, userID, appID uint, familyID, ip string) (string, error) {
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	token := &model.RefreshToken{
+		TokenHash: hashRefreshToken(raw),
+		FamilyID:  familyID,
+		UserID:    userID,
+		AppID:     appID,
+		ExpiresAt: time.Now().Add(s.ExpireTime),
+		IP:        ip,
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return "", fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	return raw, nil
+}
+
+// RotateRefreshToken 使用刷新令牌换取新的刷新令牌（旧令牌立即失效）。
+// 若旧令牌已被使用或已吊销，视为令牌泄露，吊销整个令牌家族并返回 ErrRefreshTokenReused。
+func (s *RefreshTokenService) RotateRefreshToken(raw string, appID uint, ip string) (*model.RefreshToken, string, error) {
+	var (
+		current  model.RefreshToken
+		newRaw   string
+		reused   bool
+		familyID string
+	)
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", hashRefreshToken(raw)).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if current.AppID != appID {
+			return ErrInvalidRefreshToken
+		}
+
+		if current.Revoked || current.UsedAt != nil {
+			reused = true
+			familyID = current.FamilyID
+			return ErrRefreshTokenReused
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			return ErrRefreshTokenExpired
+		}
+
+		// 条件更新，防止并发请求同时轮换同一个令牌
+		now := time.Now()
+		result := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked = ?", current.ID, false).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			familyID = current.FamilyID
+			return ErrRefreshTokenReused
+		}
+		current.UsedAt = &now
+
+		var err error
+		newRaw, err = s.issue(tx, current.UserID, current.AppID, current.FamilyID, ip)
+		return err
+	})
+
+	// 家族吊销需在事务回滚之后单独执行
+	if reused {
+		if revokeErr := s.RevokeFamily(familyID); revokeErr != nil {
+			return nil, "", fmt.Errorf("failed to revoke refresh token family: %w", revokeErr)
+		}
+		return &current, "", ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &current, newRaw, nil
+}
+
+// GetActiveRefreshToken 查询仍可使用的刷新令牌（未轮换、未吊销、未过期），用于令牌内省
+func (s *RefreshTokenService) GetActiveRefreshToken(raw string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := s.DB.Where("token_hash = ?", hashRefreshToken(raw)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if token.Revoked || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return &token, nil
+}
+
+// RevokeRefreshToken 吊销单个刷新令牌所在的整个家族（用于登出）
+func (s *RefreshTokenService) RevokeRefreshToken(raw string) error {
+	var token model.RefreshToken
+	if err := s.DB.Where("token_hash = ?", hashRefreshToken(raw)).First(&token).Error; err != nil {
+		return err
+	}
+	return s.RevokeFamily(token.FamilyID)
+}
+
+// RevokeFamily 吊销整个令牌家族
+func (s *RefreshTokenService) RevokeFamily(familyID string) error {
+	return s.DB.Model(&model.RefreshToken{}).Where("family_id = ?", familyID).Update("revoked", true).Error
+}
+
+// RevokeUserRefreshTokens 吊销指定用户的全部刷新令牌
+func (s *RefreshTokenService) RevokeUserRefreshTokens(userID uint) error {
+	return s.DB.Model(&model.RefreshToken{}).Where("user_id = ?", userID).Update("revoked", true).Error
+}
+
+// RevokeAppRefreshTokens 吊销指定应用下的全部刷新令牌
+func (s *RefreshTokenService) RevokeAppRefreshTokens(appID uint) error {
+	return s.DB.Model(&model.RefreshToken{}).Where("app_id = ?", appID).Update("revoked", true).Error
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestRefreshTokenRotationAndReuseDetection(t *testing.T) {
+	db := newTestDB(t)
+	refreshTokenService := NewRefreshTokenService(db, time.Hour)
+
+	first, err := refreshTokenService.IssueRefreshToken(1, 1, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to issue refresh token: %v", err)
+	}
+
+	current, second, err := refreshTokenService.RotateRefreshToken(first, 1, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to rotate refresh token: %v", err)
+	}
+	if current.UserID != 1 || second == "" || second == first {
+		t.Fatalf("unexpected rotation result: userID=%d new=%q", current.UserID, second)
+	}
+
+	// 其他应用不能使用该刷新令牌
+	if _, _, err := refreshTokenService.RotateRefreshToken(second, 2, "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken for foreign app, got %v", err)
+	}
+
+	// 重复使用已轮换的令牌会吊销整个家族
+	if _, _, err := refreshTokenService.RotateRefreshToken(first, 1, "127.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, _, err := refreshTokenService.RotateRefreshToken(second, 1, "127.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected family to be revoked after reuse, got %v", err)
+	}
+
+	var active int64
+	db.Model(&model.RefreshToken{}).Where("family_id = ? AND revoked = ?", current.FamilyID, false).Count(&active)
+	if active != 0 {
+		t.Fatalf("expected no active tokens in family, got %d", active)
+	}
+}
+
+func TestRefreshTokenExpired(t *testing.T) {
+	db := newTestDB(t)
+	refreshTokenService := NewRefreshTokenService(db, -time.Minute)
+
+	raw, err := refreshTokenService.IssueRefreshToken(1, 1, "")
+	if err != nil {
+		t.Fatalf("failed to issue refresh token: %v", err)
+	}
+
+	if _, _, err := refreshTokenService.RotateRefreshToken(raw, 1, ""); !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Fatalf("expected ErrRefreshTokenExpired, got %v", err)
+	}
+}
+
+func TestGetActiveRefreshToken(t *testing.T) {
+	db := newTestDB(t)
+	refreshTokenService := NewRefreshTokenService(db, time.Hour)
+
+	raw, err := refreshTokenService.IssueRefreshToken(1, 1, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to issue refresh token: %v", err)
+	}
+
+	token, err := refreshTokenService.GetActiveRefreshToken(raw)
+	if err != nil || token.UserID != 1 || token.AppID != 1 {
+		t.Fatalf("expected active refresh token, got %+v (err=%v)", token, err)
+	}
+
+	// 轮换后的旧令牌不再有效
+	if _, _, err := refreshTokenService.RotateRefreshToken(raw, 1, "127.0.0.1"); err != nil {
+		t.Fatalf("failed to rotate refresh token: %v", err)
+	}
+	if _, err := refreshTokenService.GetActiveRefreshToken(raw); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken after rotation, got %v", err)
+	}
+	if _, err := refreshTokenService.GetActiveRefreshToken("unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken for unknown token, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"Authos/internal/model"
+)
+
+// 请求签名相关的请求头
+const (
+	SignatureHeaderApp       = "X-Authos-App"       // 应用代码
+	SignatureHeaderTimestamp = "X-Authos-Timestamp" // Unix 时间戳（秒）
+	SignatureHeaderNonce     = "X-Authos-Nonce"     // 随机串，签名有效期内不能重复
+	SignatureHeaderSignature = "X-Authos-Signature" // 签名（十六进制 HMAC-SHA256）
+)
+
+// SignatureMaxSkew 请求时间戳允许的最大偏差，同时也是随机串的保留时长
+const SignatureMaxSkew = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature 签名缺失或不匹配
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrSignatureExpired 请求时间戳超出允许范围
+	ErrSignatureExpired = errors.New("request timestamp is out of range")
+	// ErrNonceReplayed 随机串已被使用（重放请求）
+	ErrNonceReplayed = errors.New("request nonce has already been used")
+)
+
+// signatureKeyContext 推导请求签名密钥时使用的上下文，使签名密钥与数据库中的密钥哈希互不相同
+const signatureKeyContext = "authos-request-signing"
+
+// SignatureSigningKey 由应用密钥推导签名密钥：hex(HMAC-SHA256(appSecret, "authos-request-signing"))。
+// 服务端以服务端密钥加密保存该值，数据库中的密钥哈希不能用于签名
+func SignatureSigningKey(appSecret string) string {
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write([]byte(signatureKeyContext))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CanonicalRequest 构造待签名字符串：方法、路径（含查询参数）、时间戳、随机串、请求体 SHA-256，以换行分隔
+func CanonicalRequest(method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		requestURI,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// SignRequest 使用签名密钥计算待签名字符串的 HMAC-SHA256（十六进制）
+func SignRequest(signingKey, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequestSignature 校验应用的请求签名：时间戳在允许范围内、签名与任一有效密钥匹配、随机串未被使用。
+// 只有配置了 SecretBox 后创建或迁移的密钥保存了签名密钥，更早的密钥仍可在请求体中使用，但不能用于签名
+func (s *ApplicationService) VerifyRequestSignature(app *model.Application, method, requestURI, timestamp, nonce, signature string, body []byte, ip string) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureExpired
+	}
+	signedAt := time.Unix(unix, 0)
+	if skew := time.Since(signedAt); skew > SignatureMaxSkew || skew < -SignatureMaxSkew {
+		return ErrSignatureExpired
+	}
+	if len(nonce) < 8 || len(nonce) > 64 {
+		return ErrInvalidSignature
+	}
+
+	if s.SecretBox == nil {
+		return ErrInvalidSignature
+	}
+
+	var secrets []*model.AppSecret
+	if err := activeSecrets(s.DB, app.ID).Where("signing_key <> ''").Find(&secrets).Error; err != nil {
+		return err
+	}
+
+	canonical := CanonicalRequest(method, requestURI, timestamp, nonce, body)
+	var matched *model.AppSecret
+	for _, secret := range secrets {
+		signingKey, err := s.SecretBox.Open(secret.SigningKey)
+		if err != nil {
+			Log.Warnf("Failed to decrypt signing key of application secret: %v, secretID=%d", err, secret.ID)
+			continue
+		}
+		expected := SignRequest(signingKey, canonical)
+		if hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+			matched = secret
+			break
+		}
+	}
+	if matched == nil {
+		return ErrInvalidSignature
+	}
+
+	// 签名通过后再登记随机串，唯一索引保证并发重放只有一个成功
+	if err := s.DB.Create(&model.RequestNonce{
+		AppID:     app.ID,
+		Nonce:     nonce,
+		ExpiresAt: signedAt.Add(SignatureMaxSkew),
+	}).Error; err != nil {
+		var count int64
+		s.DB.Unscoped().Model(&model.RequestNonce{}).Where("app_id = ? AND nonce = ?", app.ID, nonce).Count(&count)
+		if count > 0 {
+			return ErrNonceReplayed
+		}
+		return err
+	}
+
+	s.touchSecret(matched, ip)
+	return nil
+}
+
+// CleanupExpiredNonces 清理已超出签名有效期的随机串
+func (s *ApplicationService) CleanupExpiredNonces() error {
+	return s.DB.Unscoped().Where("expires_at < ?", time.Now()).Delete(&model.RequestNonce{}).Error
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestVerifyRequestSignature(t *testing.T) {
+	db := newTestDB(t)
+	applicationService := NewApplicationService(db)
+	secretBox, err := NewSecretBox(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create secret box: %v", err)
+	}
+	applicationService.SecretBox = secretBox
+
+	app, err := applicationService.CreateApplication("galaxy", "galaxy", "")
+	if err != nil {
+		t.Fatalf("failed to create application: %v", err)
+	}
+	body := []byte(`{"token":"t","obj":"/api/orders","act":"GET"}`)
+	sign := func(secret, timestamp, nonce string) string {
+		return SignRequest(SignatureSigningKey(secret), CanonicalRequest("POST", "/api/public/check-access", timestamp, nonce, body))
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	signature := sign(app.SecretKey, now, "nonce-0001")
+	if err := applicationService.VerifyRequestSignature(app, "POST", "/api/public/check-access", now, "nonce-0001", signature, body, ""); err != nil {
+		t.Fatalf("expected signature to be valid: %v", err)
+	}
+
+	// 同一随机串不能重复使用
+	err = applicationService.VerifyRequestSignature(app, "POST", "/api/public/check-access", now, "nonce-0001", signature, body, "")
+	if !errors.Is(err, ErrNonceReplayed) {
+		t.Fatalf("expected replayed nonce to be rejected, got %v", err)
+	}
+
+	// 请求体或路径被篡改、密钥错误均校验失败
+	signature = sign(app.SecretKey, now, "nonce-0002")
+	if err := applicationService.VerifyRequestSignature(app, "POST", "/api/public/check-access", now, "nonce-0002", signature, []byte(`{}`), ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected tampered body to be rejected, got %v", err)
+	}
+	if err := applicationService.VerifyRequestSignature(app, "POST", "/api/public/proxy-login", now, "nonce-0002", signature, body, ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected tampered path to be rejected, got %v", err)
+	}
+	if err := applicationService.VerifyRequestSignature(app, "POST", "/api/public/check-access", now, "nonce-0003", sign("wrong", now, "nonce-0003"), body, ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected wrong secret to be rejected, got %v", err)
+	}
+
+	// 数据库中保存的密钥哈希不能用于签名
+	var stored model.AppSecret
+	if err := db.Where("app_id = ?", app.ID).First(&stored).Error; err != nil {
+		t.Fatalf("failed to load secret: %v", err)
+	}
+	if stored.SigningKey == "" || stored.SigningKey == SignatureSigningKey(app.SecretKey) {
+		t.Fatalf("expected signing key to be stored encrypted")
+	}
+	forged := SignRequest(stored.SecretHash, CanonicalRequest("POST", "/api/public/check-access", now, "nonce-0006", body))
+	if err := applicationService.VerifyRequestSignature(app, "POST", "/api/public/check-access", now, "nonce-0006", forged, body, ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected signature keyed by stored hash to be rejected, got %v", err)
+	}
+
+	// 超出时间偏差范围
+	stale := strconv.FormatInt(time.Now().Add(-SignatureMaxSkew-time.Minute).Unix(), 10)
+	if err := applicationService.VerifyRequestSignature(app, "POST", "/api/public/check-access", stale, "nonce-0004", sign(app.SecretKey, stale, "nonce-0004"), body, ""); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expected stale timestamp to be rejected, got %v", err)
+	}
+
+	// 轮换期间新密钥同样可用于签名
+	second, _, err := applicationService.CreateSecret(app.ID, "v2", 0, 1)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	if err := applicationService.VerifyRequestSignature(app, "POST", "/api/public/check-access", now, "nonce-0005", sign(second, now, "nonce-0005"), body, ""); err != nil {
+		t.Fatalf("expected rotated secret to sign requests: %v", err)
+	}
+}
@@ -85,6 +85,13 @@ func (s *RoleService) DeleteRole(id uint, appID uint) error {
 	if err := s.CasbinService.RemoveFilteredPolicy(0, roleKey); err != nil {
 		return fmt.Errorf("failed to remove role-permission policies for role %s: %w", role.UUID, err)
 	}
+	// 移除角色继承关系（作为子角色与作为父角色）
+	if _, err := s.CasbinService.Enforcer.RemoveFilteredGroupingPolicy(0, roleKey); err != nil {
+		return fmt.Errorf("failed to remove parent roles of role %s: %w", role.UUID, err)
+	}
+	if _, err := s.CasbinService.Enforcer.RemoveFilteredGroupingPolicy(1, roleKey); err != nil {
+		return fmt.Errorf("failed to remove child roles of role %s: %w", role.UUID, err)
+	}
 
 	// 重新加载策略
 	if err := s.CasbinService.LoadPolicy(); err != nil {
@@ -97,6 +104,10 @@ func (s *RoleService) DeleteRole(id uint, appID uint) error {
 		if err := tx.Where("id = ? AND app_id = ?", id, appID).Delete(&model.Role{}).Error; err != nil {
 			return fmt.Errorf("failed to delete role with ID %d: %w", id, err)
 		}
+		// 删除角色在接口权限上的数据范围
+		if err := tx.Unscoped().Where("role_id = ?", id).Delete(&model.RoleDataScope{}).Error; err != nil {
+			return fmt.Errorf("failed to delete data scopes of role %d: %w", id, err)
+		}
 		return nil
 	})
 }
@@ -150,8 +161,8 @@ func (s *RoleService) ListRolesByApp(appID uint) ([]*model.Role, error) {
 		}
 		role.ApiPermPreview = make([]string, 0, apiPreviewCount)
 		for i := 0; i < apiPreviewCount; i++ {
-			// policies[i] 为 [sub, obj, act]
-			role.ApiPermPreview = append(role.ApiPermPreview, fmt.Sprintf("%s %s", policies[i][2], policies[i][1]))
+			// policies[i] 为 [sub, obj, act, eft]
+			role.ApiPermPreview = append(role.ApiPermPreview, policyPreview(policies[i]))
 		}
 	}
 
@@ -200,6 +211,20 @@ func (s *RoleService) AssignPermissions(roleID uint, appID uint, permissions []m
 		return fmt.Errorf("failed to get role with ID %d: %w", roleID, err)
 	}
 
+	// 先校验全部规则（eft 为空时为允许策略，cond 为空时无附加条件），避免删除旧权限后才发现参数错误
+	rules := make([][4]string, 0, len(permissions))
+	for _, perm := range permissions {
+		act, eft, err := normalizePermissionRule(perm["act"], perm["eft"])
+		if err != nil {
+			return err
+		}
+		cond, err := normalizeCondition(perm["cond"])
+		if err != nil {
+			return err
+		}
+		rules = append(rules, [4]string{perm["obj"], act, eft, cond})
+	}
+
 	// 开始事务
 	return s.DB.Transaction(func(tx *gorm.DB) error {
 		roleKey := fmt.Sprintf("role:%s", role.UUID)
@@ -210,10 +235,8 @@ func (s *RoleService) AssignPermissions(roleID uint, appID uint, permissions []m
 		}
 
 		// 添加新权限
-		for _, perm := range permissions {
-			obj := perm["obj"]
-			act := perm["act"]
-			if err := s.CasbinService.AddPolicy(roleKey, obj, act); err != nil {
+		for _, rule := range rules {
+			if err := s.CasbinService.AddPolicyRule(roleKey, rule[0], rule[1], rule[2], rule[3]); err != nil {
 				return err
 			}
 		}
@@ -222,3 +245,12 @@ func (s *RoleService) AssignPermissions(roleID uint, appID uint, permissions []m
 		return s.CasbinService.LoadPolicy()
 	})
 }
+
+// policyPreview 角色列表中策略的简要展示，拒绝策略加 DENY 前缀
+func policyPreview(policy []string) string {
+	preview := fmt.Sprintf("%s %s", policy[2], policy[1])
+	if isDenyPolicy(policy) {
+		preview = "DENY " + preview
+	}
+	return preview
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"Authos/internal/model"
+)
+
+// roleMaxHierarchyLevel 角色继承链的最大层数（与 Casbin 默认角色管理器的层数上限一致）
+const roleMaxHierarchyLevel = 10
+
+var (
+	// ErrRoleInheritanceCycle 角色继承形成环
+	ErrRoleInheritanceCycle = errors.New("role inheritance would create a cycle")
+	// ErrRoleHierarchyTooDeep 角色继承层数超过上限
+	ErrRoleHierarchyTooDeep = fmt.Errorf("role inheritance cannot exceed %d levels", roleMaxHierarchyLevel)
+)
+
+// roleSubject 角色在 Casbin 中的主体标识
+func roleSubject(uuid string) string {
+	return fmt.Sprintf("role:%s", uuid)
+}
+
+// roleHierarchyLevels 计算从 sub 出发向上的最长继承链包含的角色数（parents 为 子角色 -> 父角色 列表）
+func roleHierarchyLevels(parents map[string][]string, sub string, visiting map[string]bool) (int, error) {
+	if visiting[sub] {
+		return 0, ErrRoleInheritanceCycle
+	}
+	visiting[sub] = true
+	defer delete(visiting, sub)
+
+	levels := 1
+	for _, parent := range parents[sub] {
+		n, err := roleHierarchyLevels(parents, parent, visiting)
+		if err != nil {
+			return 0, err
+		}
+		levels = max(levels, n+1)
+	}
+	return levels, nil
+}
+
+// SetRoleParents 设置角色直接继承的父角色（按应用隔离），替换原有的父角色；
+// 子角色拥有父角色及其祖先的全部接口权限，超级管理员身份同样可被继承
+func (s *RoleService) SetRoleParents(roleID uint, appID uint, parentIDs []uint) error {
+	role, err := s.GetRoleByID(roleID, appID)
+	if err != nil {
+		return fmt.Errorf("failed to get role with ID %d: %w", roleID, err)
+	}
+
+	parentIDs = uniqueUints(parentIDs)
+	var parentRoles []*model.Role
+	if len(parentIDs) > 0 {
+		if err := s.DB.Where("id IN ? AND app_id = ?", parentIDs, appID).Find(&parentRoles).Error; err != nil {
+			return err
+		}
+		if len(parentRoles) != len(parentIDs) {
+			return errors.New("parent role not found in this application")
+		}
+	}
+
+	// 在现有继承关系上替换该角色的父角色，校验无环且层数不超限
+	sub := roleSubject(role.UUID)
+	rules, err := s.CasbinService.Enforcer.GetGroupingPolicy()
+	if err != nil {
+		return err
+	}
+	parents := make(map[string][]string)
+	for _, rule := range rules {
+		if len(rule) >= 2 && rule[0] != sub {
+			parents[rule[0]] = append(parents[rule[0]], rule[1])
+		}
+	}
+	for _, parent := range parentRoles {
+		if parent.ID == role.ID {
+			return ErrRoleInheritanceCycle
+		}
+		parents[sub] = append(parents[sub], roleSubject(parent.UUID))
+	}
+	for child := range parents {
+		levels, err := roleHierarchyLevels(parents, child, make(map[string]bool))
+		if err != nil {
+			return err
+		}
+		if levels > roleMaxHierarchyLevel {
+			return ErrRoleHierarchyTooDeep
+		}
+	}
+
+	if _, err := s.CasbinService.Enforcer.RemoveFilteredGroupingPolicy(0, sub); err != nil {
+		return fmt.Errorf("failed to remove role inheritance: %w", err)
+	}
+	for _, parent := range parentRoles {
+		if _, err := s.CasbinService.Enforcer.AddGroupingPolicy(sub, roleSubject(parent.UUID)); err != nil {
+			return fmt.Errorf("failed to add role inheritance: %w", err)
+		}
+	}
+
+	// 重新加载策略
+	return s.CasbinService.LoadPolicy()
+}
+
+// uniqueUints 去重并去除 0
+func uniqueUints(values []uint) []uint {
+	seen := make(map[uint]bool, len(values))
+	result := make([]uint, 0, len(values))
+	for _, v := range values {
+		if v != 0 && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// rolesBySubjects 根据 Casbin 主体标识（role:UUID）查询角色（按应用隔离）
+func (s *CasbinService) rolesBySubjects(subjects []string, appID uint) ([]*model.Role, error) {
+	var uuids []string
+	for _, sub := range subjects {
+		if uuid, ok := strings.CutPrefix(sub, "role:"); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+	var roles []*model.Role
+	if len(uuids) == 0 {
+		return roles, nil
+	}
+	if err := s.DB.Where("uuid IN ? AND app_id = ?", uuids, appID).Order("id asc").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetRoleParents 获取角色直接继承的父角色
+func (s *CasbinService) GetRoleParents(role *model.Role) ([]*model.Role, error) {
+	rules, err := s.Enforcer.GetFilteredGroupingPolicy(0, roleSubject(role.UUID))
+	if err != nil {
+		return nil, err
+	}
+	subjects := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		subjects = append(subjects, rule[1])
+	}
+	return s.rolesBySubjects(subjects, role.AppID)
+}
+
+// GetRoleAncestors 获取角色沿继承链的全部祖先角色（不含自身）
+func (s *CasbinService) GetRoleAncestors(role *model.Role) ([]*model.Role, error) {
+	subjects, err := s.Enforcer.GetImplicitRolesForUser(roleSubject(role.UUID))
+	if err != nil {
+		return nil, err
+	}
+	return s.rolesBySubjects(subjects, role.AppID)
+}
+
+// GetRoleDescendants 获取沿继承链继承该角色的全部子孙角色（不含自身）
+func (s *CasbinService) GetRoleDescendants(role *model.Role) ([]*model.Role, error) {
+	subjects, err := s.Enforcer.GetImplicitUsersForRole(roleSubject(role.UUID))
+	if err != nil {
+		return nil, err
+	}
+	return s.rolesBySubjects(subjects, role.AppID)
+}
+
+// expandRoles 返回角色及其全部祖先角色（去重）
+func (s *CasbinService) expandRoles(roles []*model.Role) ([]*model.Role, error) {
+	seen := make(map[uint]bool)
+	var expanded []*model.Role
+	for _, role := range roles {
+		ancestors, err := s.GetRoleAncestors(role)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range append([]*model.Role{role}, ancestors...) {
+			if !seen[r.ID] {
+				seen[r.ID] = true
+				expanded = append(expanded, r)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// roleMenus 返回角色及其全部祖先角色关联的菜单（菜单与按钮同接口权限一样沿继承链继承）
+func (s *CasbinService) roleMenus(roles []*model.Role) ([]*model.Menu, error) {
+	expanded, err := s.expandRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+	var menus []*model.Menu
+	if len(expanded) == 0 {
+		return menus, nil
+	}
+	roleIDs := make([]uint, 0, len(expanded))
+	for _, role := range expanded {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	var withMenus []*model.Role
+	if err := s.DB.Preload("Menus").Where("id IN ?", roleIDs).Find(&withMenus).Error; err != nil {
+		return nil, err
+	}
+	for _, role := range withMenus {
+		menus = append(menus, role.Menus...)
+	}
+	return menus, nil
+}
+
+// EffectiveRoles 返回用户的有效角色（直接、用户组与部门角色）及其沿继承链的全部祖先角色
+func (s *CasbinService) EffectiveRoles(userID uint) ([]*model.Role, error) {
+	user := model.User{}
+	user.ID = userID
+	if err := loadEffectiveRoles(s.DB, &user); err != nil {
+		return nil, err
+	}
+	return s.expandRoles(user.Roles)
+}
+
+// EffectiveRoleNames 返回用户全部有效角色（包括继承的角色）的名称，用于 ID Token、/userinfo 与令牌内省的 roles 声明
+func (s *CasbinService) EffectiveRoleNames(userID uint) ([]string, error) {
+	roles, err := s.EffectiveRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return names, nil
+}
+
+// IsSuperAdmin 用户是否通过任一有效角色（包括继承的角色）拥有超级管理员身份，与 CheckPermission 的放行规则一致
+func (s *CasbinService) IsSuperAdmin(userID uint) (bool, error) {
+	roles, err := s.EffectiveRoles(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if role.IsSuperAdmin {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FillRoleHierarchy 填充角色的父角色ID、继承的超级管理员身份以及从祖先角色继承的接口权限（用于角色详情）
+func (s *RoleService) FillRoleHierarchy(role *model.Role) error {
+	parents, err := s.CasbinService.GetRoleParents(role)
+	if err != nil {
+		return err
+	}
+	role.ParentIDs = make([]uint, 0, len(parents))
+	for _, parent := range parents {
+		role.ParentIDs = append(role.ParentIDs, parent.ID)
+	}
+
+	ancestors, err := s.CasbinService.GetRoleAncestors(role)
+	if err != nil {
+		return err
+	}
+	role.InheritedPermissions = []model.InheritedPermission{}
+	for _, ancestor := range ancestors {
+		if ancestor.IsSuperAdmin {
+			role.InheritedSuperAdmin = true
+		}
+		policies, err := s.CasbinService.Enforcer.GetFilteredPolicy(0, roleSubject(ancestor.UUID))
+		if err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			role.InheritedPermissions = append(role.InheritedPermissions, model.InheritedPermission{
+				Obj:          policy[1],
+				Act:          policy[2],
+				Effect:       policy[3],
+				FromRoleID:   ancestor.ID,
+				FromRoleName: ancestor.Name,
+			})
+		}
+	}
+	return nil
+}
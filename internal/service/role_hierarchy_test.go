@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"Authos/internal/model"
+)
+
+func TestRoleInheritance(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+	roleService := NewRoleService(db, casbinService)
+
+	app := &model.Application{Name: "hierarchy-app", Code: "hierarchy-app", Status: 1}
+	other := &model.Application{Name: "other-app", Code: "other-app", Status: 1}
+	db.Create(app)
+	db.Create(other)
+
+	viewer := &model.Role{Name: "viewer", AppID: app.ID}
+	editor := &model.Role{Name: "editor", AppID: app.ID}
+	admin := &model.Role{Name: "admin", AppID: app.ID}
+	foreign := &model.Role{Name: "foreign", AppID: other.ID}
+	for _, role := range []*model.Role{viewer, editor, admin, foreign} {
+		db.Create(role)
+	}
+	user := &model.User{Username: "hierarchy-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append(admin)
+
+	permission, err := apiPermissionService.CreateApiPermission(app.ID, "doc.read", "查看文档", "/api/docs", model.HTTP_ALL, "")
+	if err != nil {
+		t.Fatalf("failed to create api permission: %v", err)
+	}
+	if err := apiPermissionService.AddApiPermissionToRole(app.ID, viewer.UUID, permission.UUID); err != nil {
+		t.Fatalf("failed to add permission to role: %v", err)
+	}
+
+	// admin -> editor -> viewer，权限沿继承链解析
+	if err := roleService.SetRoleParents(editor.ID, app.ID, []uint{viewer.ID}); err != nil {
+		t.Fatalf("failed to set editor parents: %v", err)
+	}
+	if err := roleService.SetRoleParents(admin.ID, app.ID, []uint{editor.ID}); err != nil {
+		t.Fatalf("failed to set admin parents: %v", err)
+	}
+	if allowed, err := casbinService.CheckPermission(user.ID, "doc.read", "GET", nil); err != nil || !allowed {
+		t.Fatalf("expected inherited permission allowed, got %v, err=%v", allowed, err)
+	}
+
+	explanation, err := casbinService.ExplainPermission(user.ID, "doc.read", "GET", nil)
+	if err != nil || !explanation.Allowed || explanation.Roles[0].Reason != "granted by policy inherited from role viewer" {
+		t.Fatalf("unexpected explanation: %+v, err=%v", explanation, err)
+	}
+
+	if err := roleService.FillRoleHierarchy(admin); err != nil {
+		t.Fatalf("failed to fill role hierarchy: %v", err)
+	}
+	if len(admin.ParentIDs) != 1 || admin.ParentIDs[0] != editor.ID {
+		t.Fatalf("unexpected parent ids: %v", admin.ParentIDs)
+	}
+	if len(admin.InheritedPermissions) != 1 || admin.InheritedPermissions[0].FromRoleID != viewer.ID {
+		t.Fatalf("unexpected inherited permissions: %+v", admin.InheritedPermissions)
+	}
+
+	// 循环继承、继承自身、跨应用继承均被拒绝
+	if err := roleService.SetRoleParents(viewer.ID, app.ID, []uint{admin.ID}); !errors.Is(err, ErrRoleInheritanceCycle) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+	if err := roleService.SetRoleParents(viewer.ID, app.ID, []uint{viewer.ID}); !errors.Is(err, ErrRoleInheritanceCycle) {
+		t.Fatalf("expected self inheritance rejected, got %v", err)
+	}
+	if err := roleService.SetRoleParents(viewer.ID, app.ID, []uint{foreign.ID}); err == nil {
+		t.Fatalf("expected cross-app parent rejected")
+	}
+
+	// 超级管理员身份可被继承
+	viewer.IsSuperAdmin = true
+	if err := roleService.UpdateRole(viewer); err != nil {
+		t.Fatalf("failed to update role: %v", err)
+	}
+	if allowed, err := casbinService.CheckPermission(user.ID, "anything", "DELETE", nil); err != nil || !allowed {
+		t.Fatalf("expected inherited super admin allowed, got %v, err=%v", allowed, err)
+	}
+
+	// 删除中间角色后继承链断开
+	if err := roleService.DeleteRole(editor.ID, app.ID); err != nil {
+		t.Fatalf("failed to delete role: %v", err)
+	}
+	if allowed, err := casbinService.CheckPermission(user.ID, "doc.read", "GET", nil); err != nil || allowed {
+		t.Fatalf("expected permission denied after parent deleted, got %v, err=%v", allowed, err)
+	}
+	if rules, _ := casbinService.Enforcer.GetGroupingPolicy(); len(rules) != 0 {
+		t.Fatalf("expected grouping policies removed, got %v", rules)
+	}
+}
+
+func TestEffectiveRoleNames(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	roleService := NewRoleService(db, casbinService)
+	departmentService := NewDepartmentService(db)
+	userGroupService := NewUserGroupService(db)
+
+	app := &model.Application{Name: "claims-app", Code: "claims-app", Status: 1}
+	db.Create(app)
+	direct := &model.Role{Name: "direct", AppID: app.ID}
+	parent := &model.Role{Name: "parent", AppID: app.ID}
+	deptRole := &model.Role{Name: "dept-role", AppID: app.ID}
+	groupRole := &model.Role{Name: "group-role", AppID: app.ID}
+	for _, role := range []*model.Role{direct, parent, deptRole, groupRole} {
+		db.Create(role)
+	}
+	if err := roleService.SetRoleParents(direct.ID, app.ID, []uint{parent.ID}); err != nil {
+		t.Fatalf("failed to set role parents: %v", err)
+	}
+
+	user := &model.User{Username: "claims-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append(direct)
+
+	dept := &model.Department{Name: "sales", AppID: app.ID}
+	if err := departmentService.CreateDepartment(dept); err != nil {
+		t.Fatalf("failed to create department: %v", err)
+	}
+	if err := departmentService.SetDepartmentRoles(dept.ID, app.ID, []uint{deptRole.ID}); err != nil {
+		t.Fatalf("failed to set department roles: %v", err)
+	}
+	if err := departmentService.SetUserDepartments(user.ID, app.ID, []uint{dept.ID}); err != nil {
+		t.Fatalf("failed to set user departments: %v", err)
+	}
+
+	group := &model.UserGroup{Name: "reviewers", AppID: app.ID}
+	if err := userGroupService.CreateUserGroup(group); err != nil {
+		t.Fatalf("failed to create user group: %v", err)
+	}
+	if err := userGroupService.SetUserGroupRoles(group.ID, app.ID, []uint{groupRole.ID}); err != nil {
+		t.Fatalf("failed to set user group roles: %v", err)
+	}
+	if err := userGroupService.AddUserGroupMembers(group.ID, app.ID, []uint{user.ID}); err != nil {
+		t.Fatalf("failed to add user group members: %v", err)
+	}
+
+	// roles 声明包含直接、部门、用户组角色以及继承的父角色
+	names, err := casbinService.EffectiveRoleNames(user.ID)
+	if err != nil {
+		t.Fatalf("failed to load effective role names: %v", err)
+	}
+	slices.Sort(names)
+	if want := []string{"dept-role", "direct", "group-role", "parent"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected role names %v, got %v", want, names)
+	}
+}
+
+func TestInheritedMenusAndButtons(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+	roleService := NewRoleService(db, casbinService)
+	menuService := NewMenuService(db, casbinService)
+
+	app := &model.Application{Name: "menu-inherit-app", Code: "menu-inherit-app", Status: 1}
+	db.Create(app)
+	parent := &model.Role{Name: "reader", AppID: app.ID}
+	child := &model.Role{Name: "writer", AppID: app.ID}
+	for _, role := range []*model.Role{parent, child} {
+		db.Create(role)
+	}
+	if err := roleService.SetRoleParents(child.ID, app.ID, []uint{parent.ID}); err != nil {
+		t.Fatalf("failed to set role parents: %v", err)
+	}
+
+	page := &model.Menu{Name: "reports", Path: "/reports", Type: 1, AppID: app.ID}
+	db.Create(page)
+	button := &model.Menu{Name: "导出", Type: 2, Code: "report:export", ParentID: page.ID, AppID: app.ID}
+	db.Create(button)
+	if err := roleService.AssignMenus(parent.ID, app.ID, []uint{page.ID, button.ID}); err != nil {
+		t.Fatalf("failed to assign menus: %v", err)
+	}
+
+	user := &model.User{Username: "menu-inherit-user", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append(child)
+
+	// 只持有子角色的用户继承父角色的菜单与按钮
+	menus, err := menuService.GetUserMenuTree(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get user menu tree: %v", err)
+	}
+	if len(menus) != 1 || menus[0].ID != page.ID || len(menus[0].Children) != 1 {
+		t.Fatalf("expected inherited menu tree, got %+v", menus)
+	}
+	permissions, err := apiPermissionService.GetUserPermissions(user.ID, app.ID)
+	if err != nil {
+		t.Fatalf("failed to get user permissions: %v", err)
+	}
+	if !reflect.DeepEqual(permissions.Buttons, []string{"report:export"}) {
+		t.Fatalf("expected inherited buttons, got %v", permissions.Buttons)
+	}
+}
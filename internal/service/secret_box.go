@@ -0,0 +1,89 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretBoxKeySize 服务端加密密钥长度（AES-256）
+const secretBoxKeySize = 32
+
+// ErrSecretBoxCiphertext 密文格式错误或无法用当前密钥解密
+var ErrSecretBoxCiphertext = errors.New("invalid secret ciphertext")
+
+// SecretBox 使用服务端密钥（AES-256-GCM）加密需要可逆保存的敏感数据。
+// 密钥只保存在服务器本地文件中，不进入数据库，仅凭数据库或备份无法解密
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox 使用 32 字节密钥创建加密实例
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != secretBoxKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", secretBoxKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// LoadOrCreateSecretBox 从密钥文件（十六进制）加载加密密钥，文件不存在时生成随机密钥并以 0600 权限写入
+func LoadOrCreateSecretBox(path string) (*SecretBox, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key := make([]byte, secretBoxKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write encryption key file: %w", err)
+		}
+		return NewSecretBox(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key file must contain a hex encoded key: %w", err)
+	}
+	return NewSecretBox(key)
+}
+
+// Seal 加密明文，返回 base64(nonce + 密文)
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 生成的密文
+func (b *SecretBox) Open(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrSecretBoxCiphertext
+	}
+	nonce, sealed := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrSecretBoxCiphertext
+	}
+	return string(plaintext), nil
+}
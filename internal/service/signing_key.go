@@ -0,0 +1,253 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+// 支持的非对称签名算法
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmHS256 = "HS256"
+)
+
+// SigningKeyService 签名密钥服务，负责生成、持久化和加载非对称密钥对
+type SigningKeyService struct {
+	DB *gorm.DB
+}
+
+// NewSigningKeyService 创建签名密钥服务实例
+func NewSigningKeyService(db *gorm.DB) *SigningKeyService {
+	return &SigningKeyService{DB: db}
+}
+
+// LoadOrCreateActiveKey 加载当前生效的签名密钥。
+// 不存在时按指定算法生成；若配置的算法与当前密钥不一致，则轮换到新算法的密钥
+func (s *SigningKeyService) LoadOrCreateActiveKey(algorithm string) (*model.SigningKey, error) {
+	var key model.SigningKey
+	err := s.DB.Where("status = ?", model.SigningKeyStatusActive).Order("id desc").First(&key).Error
+	if err == nil {
+		if key.Algorithm == algorithm {
+			return &key, nil
+		}
+		return s.RotateKey(algorithm)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return s.CreateKey(algorithm, model.SigningKeyStatusActive)
+}
+
+// RotateKey 轮换签名密钥：生成新的 active 密钥，原 active 密钥转为 verifying
+// algorithm 为空时沿用当前密钥的算法
+func (s *SigningKeyService) RotateKey(algorithm string) (*model.SigningKey, error) {
+	var newKey *model.SigningKey
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var current model.SigningKey
+		err := tx.Where("status = ?", model.SigningKeyStatusActive).Order("id desc").First(&current).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if algorithm == "" {
+			algorithm = current.Algorithm
+		}
+		if algorithm == "" {
+			algorithm = AlgorithmRS256
+		}
+
+		now := time.Now()
+		if err := tx.Model(&model.SigningKey{}).Where("status = ?", model.SigningKeyStatusActive).
+			Updates(map[string]interface{}{"status": model.SigningKeyStatusVerifying, "rotated_at": now}).Error; err != nil {
+			return fmt.Errorf("failed to demote active signing key: %w", err)
+		}
+
+		newKey, err = createSigningKey(tx, algorithm, model.SigningKeyStatusActive)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newKey, nil
+}
+
+// RetireExpiredKeys 将轮换时间早于 overlap 的 verifying 密钥退役
+// overlap 应不小于访问令牌有效期，保证旧密钥签发的令牌在过期前仍可校验
+func (s *SigningKeyService) RetireExpiredKeys(overlap time.Duration) error {
+	return s.DB.Model(&model.SigningKey{}).
+		Where("status = ? AND rotated_at < ?", model.SigningKeyStatusVerifying, time.Now().Add(-overlap)).
+		Update("status", model.SigningKeyStatusRetired).Error
+}
+
+// RetireKey 手动退役指定的 verifying 密钥（当前 active 密钥不可退役）
+func (s *SigningKeyService) RetireKey(kid string) error {
+	var key model.SigningKey
+	if err := s.DB.Where("kid = ?", kid).First(&key).Error; err != nil {
+		return err
+	}
+	if key.Status == model.SigningKeyStatusActive {
+		return fmt.Errorf("cannot retire the active signing key %s, rotate first", kid)
+	}
+
+	return s.DB.Model(&key).Update("status", model.SigningKeyStatusRetired).Error
+}
+
+// ListKeys 列出全部签名密钥（不含私钥）
+func (s *SigningKeyService) ListKeys() ([]*model.SigningKey, error) {
+	var keys []*model.SigningKey
+	if err := s.DB.Order("id desc").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// LoadKeyStore 将未退役的密钥加载到密钥库，并让密钥库在遇到未知 kid 时可从数据库刷新
+func (s *SigningKeyService) LoadKeyStore(store *KeyStore) error {
+	store.SetLoader(s.loadableKeys)
+	return store.Reload()
+}
+
+// loadableKeys 查询未退役（active 与 verifying）的密钥记录
+func (s *SigningKeyService) loadableKeys() ([]*model.SigningKey, error) {
+	var keys []*model.SigningKey
+	if err := s.DB.Where("status IN ?", []string{model.SigningKeyStatusActive, model.SigningKeyStatusVerifying}).
+		Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RefreshKeyStore 退役超过 overlap 的 verifying 密钥并重新加载密钥库
+func (s *SigningKeyService) RefreshKeyStore(store *KeyStore, overlap time.Duration) error {
+	if err := s.RetireExpiredKeys(overlap); err != nil {
+		return err
+	}
+	return s.LoadKeyStore(store)
+}
+
+// WatchKeyStore 按 interval 定期刷新密钥库，使多实例部署中其他实例的轮换与退役在本实例生效
+func (s *SigningKeyService) WatchKeyStore(store *KeyStore, overlap, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.RefreshKeyStore(store, overlap); err != nil {
+			Log.Warnf("WatchKeyStore: Failed to refresh signing keys: %v", err)
+		}
+	}
+}
+
+// CreateKey 生成并保存新的签名密钥
+func (s *SigningKeyService) CreateKey(algorithm, status string) (*model.SigningKey, error) {
+	return createSigningKey(s.DB, algorithm, status)
+}
+
+// createSigningKey 在指定数据库会话中生成并保存签名密钥
+func createSigningKey(db *gorm.DB, algorithm, status string) (*model.SigningKey, error) {
+	privateKey, err := generatePrivateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	// kid 取公钥摘要，保证同一公钥的 kid 稳定
+	sum := sha256.Sum256(publicDER)
+
+	key := &model.SigningKey{
+		KID:        base64.RawURLEncoding.EncodeToString(sum[:12]),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		Status:     status,
+	}
+	if err := db.Create(key).Error; err != nil {
+		return nil, fmt.Errorf("failed to save signing key: %w", err)
+	}
+
+	return key, nil
+}
+
+// generatePrivateKey 按算法生成私钥
+func generatePrivateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
+
+// ParseSigningKey 解析密钥记录中的私钥
+func ParseSigningKey(key *model.SigningKey) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key PEM for kid %s", key.KID)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key for kid %s: %w", key.KID, err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key for kid %s is not a signer", key.KID)
+	}
+	return signer, nil
+}
+
+// PublicJWK 将公钥转换为 JWK (RFC 7517) 表示
+func PublicJWK(kid, algorithm string, publicKey crypto.PublicKey) (map[string]interface{}, error) {
+	jwk := map[string]interface{}{
+		"kid": kid,
+		"alg": algorithm,
+		"use": "sig",
+	}
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// 未压缩点格式: 0x04 || X || Y
+		point, err := pub.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode ec public key: %w", err)
+		}
+		size := (len(point) - 1) / 2
+		jwk["kty"] = "EC"
+		jwk["crv"] = pub.Curve.Params().Name
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(point[1 : 1+size])
+		jwk["y"] = base64.RawURLEncoding.EncodeToString(point[1+size:])
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return jwk, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"Authos/internal/model"
+)
+
+func TestTokenRevocationByJTIUserAndRole(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	refreshTokenService := NewRefreshTokenService(db, time.Hour)
+	revocationService := NewTokenRevocationService(db, casbinService, refreshTokenService)
+
+	issuedAt := time.Now().Add(-time.Minute)
+
+	// 单个令牌吊销
+	if err := revocationService.RevokeToken("jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if revoked, _ := revocationService.IsTokenRevoked("jti-1", 1, 1, issuedAt); !revoked {
+		t.Fatalf("expected jti-1 to be revoked")
+	}
+	if revoked, _ := revocationService.IsTokenRevoked("jti-2", 1, 1, issuedAt); revoked {
+		t.Fatalf("expected jti-2 to be valid")
+	}
+
+	// 按角色吊销：角色下的用户此前签发的令牌全部失效，刷新令牌同时失效
+	role := &model.Role{Name: "role", AppID: 1}
+	db.Create(role)
+	user := &model.User{Username: "u", Password: "p", AppID: 1}
+	db.Create(user)
+	if err := db.Model(user).Association("Roles").Append(role); err != nil {
+		t.Fatalf("failed to associate role: %v", err)
+	}
+	refreshToken, _ := refreshTokenService.IssueRefreshToken(user.ID, 1, "")
+
+	count, err := revocationService.RevokeRoleTokens(role.ID, 1)
+	if err != nil || count != 1 {
+		t.Fatalf("failed to revoke role tokens: count=%d err=%v", count, err)
+	}
+	if revoked, _ := revocationService.IsTokenRevoked("jti-3", user.ID, 1, issuedAt); !revoked {
+		t.Fatalf("expected token issued before revocation to be revoked")
+	}
+	if revoked, _ := revocationService.IsTokenRevoked("jti-4", user.ID, 1, time.Now().Add(time.Second)); revoked {
+		t.Fatalf("expected token issued after revocation to be valid")
+	}
+	if _, _, err := refreshTokenService.RotateRefreshToken(refreshToken, 1, ""); err != ErrRefreshTokenReused {
+		t.Fatalf("expected revoked refresh token to be rejected, got %v", err)
+	}
+
+	// 按应用吊销
+	if err := revocationService.RevokeAppTokens(2); err != nil {
+		t.Fatalf("failed to revoke app tokens: %v", err)
+	}
+	if revoked, _ := revocationService.IsTokenRevoked("", 0, 2, issuedAt); !revoked {
+		t.Fatalf("expected app token to be revoked")
+	}
+}
+
+func TestRevokeRoleTokensCoversEffectiveHolders(t *testing.T) {
+	db := newTestDB(t)
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	roleService := NewRoleService(db, casbinService)
+	departmentService := NewDepartmentService(db)
+	userGroupService := NewUserGroupService(db)
+	revocationService := NewTokenRevocationService(db, casbinService, nil)
+
+	app := &model.Application{Name: "revoke-app", Code: "revoke-app", Status: 1}
+	db.Create(app)
+	target := &model.Role{Name: "target", AppID: app.ID}
+	child := &model.Role{Name: "child", AppID: app.ID}
+	unrelated := &model.Role{Name: "unrelated", AppID: app.ID}
+	for _, role := range []*model.Role{target, child, unrelated} {
+		db.Create(role)
+	}
+	if err := roleService.SetRoleParents(child.ID, app.ID, []uint{target.ID}); err != nil {
+		t.Fatalf("failed to set role parents: %v", err)
+	}
+
+	users := make(map[string]*model.User)
+	for _, name := range []string{"direct", "group", "department", "inherited", "bystander"} {
+		user := &model.User{Username: "revoke-" + name, Password: "password", Status: 1, AppID: app.ID}
+		db.Create(user)
+		users[name] = user
+	}
+	db.Model(users["direct"]).Association("Roles").Append(target)
+	db.Model(users["inherited"]).Association("Roles").Append(child)
+	db.Model(users["bystander"]).Association("Roles").Append(unrelated)
+
+	group := &model.UserGroup{Name: "revoke-group", AppID: app.ID}
+	if err := userGroupService.CreateUserGroup(group); err != nil {
+		t.Fatalf("failed to create user group: %v", err)
+	}
+	if err := userGroupService.SetUserGroupRoles(group.ID, app.ID, []uint{target.ID}); err != nil {
+		t.Fatalf("failed to set user group roles: %v", err)
+	}
+	if err := userGroupService.AddUserGroupMembers(group.ID, app.ID, []uint{users["group"].ID}); err != nil {
+		t.Fatalf("failed to add user group members: %v", err)
+	}
+	dept := &model.Department{Name: "revoke-dept", AppID: app.ID}
+	if err := departmentService.CreateDepartment(dept); err != nil {
+		t.Fatalf("failed to create department: %v", err)
+	}
+	if err := departmentService.SetDepartmentRoles(dept.ID, app.ID, []uint{target.ID}); err != nil {
+		t.Fatalf("failed to set department roles: %v", err)
+	}
+	if err := departmentService.SetUserDepartments(users["department"].ID, app.ID, []uint{dept.ID}); err != nil {
+		t.Fatalf("failed to set user departments: %v", err)
+	}
+
+	// 直接、用户组、部门以及经子角色继承持有该角色的用户均被吊销，其他用户不受影响
+	issuedAt := time.Now().Add(-time.Minute)
+	count, err := revocationService.RevokeRoleTokens(target.ID, app.ID)
+	if err != nil || count != 4 {
+		t.Fatalf("expected 4 users revoked, got %d, err=%v", count, err)
+	}
+	for name, user := range users {
+		revoked, _ := revocationService.IsTokenRevoked("", user.ID, 0, issuedAt)
+		if revoked != (name != "bystander") {
+			t.Fatalf("unexpected revocation for %s user: %v", name, revoked)
+		}
+	}
+}
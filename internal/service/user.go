@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -11,12 +12,16 @@ import (
 
 // UserService 用户服务
 type UserService struct {
-	DB *gorm.DB
+	DB                     *gorm.DB
+	TokenRevocationService *TokenRevocationService
 }
 
 // NewUserService 创建用户服务实例
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{DB: db}
+func NewUserService(db *gorm.DB, tokenRevocationService *TokenRevocationService) *UserService {
+	return &UserService{
+		DB:                     db,
+		TokenRevocationService: tokenRevocationService,
+	}
 }
 
 // GetApplicationByCode 根据应用代码获取应用信息
@@ -40,12 +45,23 @@ func (s *UserService) CreateUser(user *model.User) error {
 		return fmt.Errorf("user with username '%s' already exists in this application", user.Username)
 	}
 
+	// 按应用的密码策略校验
+	var app model.Application
+	if err := s.DB.Where("id = ?", user.AppID).First(&app).Error; err != nil {
+		return fmt.Errorf("failed to find application: %w", err)
+	}
+	if err := CheckPasswordStrength(&app, user.Username, user.Password); err != nil {
+		return err
+	}
+
 	// 密码加密
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 	user.Password = string(hashedPassword)
+	now := time.Now()
+	user.PasswordChangedAt = &now
 
 	// 开始事务
 	return s.DB.Transaction(func(tx *gorm.DB) error {
@@ -70,17 +86,25 @@ func (s *UserService) CreateUser(user *model.User) error {
 }
 
 // UpdateUser 更新用户
+// 用户被禁用时，其已签发的全部令牌同时被吊销
 func (s *UserService) UpdateUser(user *model.User, appID uint) error {
 	// 开始事务
-	return s.DB.Transaction(func(tx *gorm.DB) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
 		// 更新用户基本信息（不包含密码）
 		updateData := map[string]interface{}{
 			"Username": user.Username,
+			"Email":    user.Email,
 			"Status":   user.Status,
 		}
 		if err := tx.Model(user).Updates(updateData).Error; err != nil {
 			return err
 		}
+		// 未传入用户属性时保留原值（按结构体更新以使用 JSON 序列化）
+		if user.Attributes != nil {
+			if err := tx.Model(user).Select("Attributes").Updates(&model.User{Attributes: user.Attributes}).Error; err != nil {
+				return err
+			}
+		}
 
 		// 始终替换角色关联（若 RoleIDs 为空则清空所有角色）
 		// 同时校验角色必须属于同一应用，防止跨应用赋权
@@ -96,35 +120,140 @@ func (s *UserService) UpdateUser(user *model.User, appID uint) error {
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if user.Status == 0 && s.TokenRevocationService != nil {
+		if err := s.TokenRevocationService.RevokeUserTokens(user.ID); err != nil {
+			return fmt.Errorf("failed to revoke tokens of disabled user: %w", err)
+		}
+	}
+
+	return nil
 }
 
-// UpdateUserPassword 更新用户密码
-func (s *UserService) UpdateUserPassword(id uint, password string) error {
+// UpdateUserPassword 更新用户密码（按应用隔离），按应用的密码策略校验强度与历史密码
+func (s *UserService) UpdateUserPassword(id uint, appID uint, password string) error {
+	return s.updateUserPassword(id, appID, password, nil)
+}
+
+// ResetUserPassword 凭一次性凭据修改密码：按密码策略校验后，在同一事务中先执行 claim（如认领重置令牌）再更新密码，
+// claim 失败时整个事务回滚，密码不变
+func (s *UserService) ResetUserPassword(id uint, appID uint, password string, claim func(tx *gorm.DB) error) error {
+	return s.updateUserPassword(id, appID, password, claim)
+}
+
+// updateUserPassword 校验并更新用户密码，claim 不为空时在更新密码的事务中先执行
+func (s *UserService) updateUserPassword(id uint, appID uint, password string, claim func(tx *gorm.DB) error) error {
+	user, err := s.GetUserByID(id, appID)
+	if err != nil {
+		return err
+	}
+
+	var app model.Application
+	if err := s.DB.Where("id = ?", appID).First(&app).Error; err != nil {
+		return fmt.Errorf("failed to find application: %w", err)
+	}
+	if err := CheckPasswordStrength(&app, user.Username, password); err != nil {
+		return err
+	}
+	if err := s.checkPasswordHistory(user, app.PasswordHistory, password); err != nil {
+		return err
+	}
+
 	// 密码加密
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	return s.DB.Model(&model.User{}).Where("id = ?", id).Update("password", string(hashedPassword)).Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if claim != nil {
+			if err := claim(tx); err != nil {
+				return err
+			}
+		}
+
+		// 旧密码写入历史，只保留最近 passwordHistoryLimit 条
+		if err := tx.Create(&model.PasswordHistory{UserID: user.ID, PasswordHash: user.Password}).Error; err != nil {
+			return err
+		}
+		var staleIDs []uint
+		if err := tx.Model(&model.PasswordHistory{}).Where("user_id = ?", user.ID).
+			Order("id desc").Offset(passwordHistoryLimit).Pluck("id", &staleIDs).Error; err != nil {
+			return err
+		}
+		if len(staleIDs) > 0 {
+			if err := tx.Unscoped().Where("id IN ?", staleIDs).Delete(&model.PasswordHistory{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"password":            string(hashedPassword),
+			"password_changed_at": time.Now(),
+		}).Error
+	})
 }
 
-// DeleteUser 删除用户（按应用隔离）
+// checkPasswordHistory 校验新密码不能与当前密码及最近 n-1 个历史密码相同（n 为 0 时不校验）
+func (s *UserService) checkPasswordHistory(user *model.User, n int, password string) error {
+	if n <= 0 {
+		return nil
+	}
+
+	hashes := []string{user.Password}
+	if n > 1 {
+		var history []string
+		if err := s.DB.Model(&model.PasswordHistory{}).Where("user_id = ?", user.ID).
+			Order("id desc").Limit(n-1).Pluck("password_hash", &history).Error; err != nil {
+			return fmt.Errorf("failed to load password history: %w", err)
+		}
+		hashes = append(hashes, history...)
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return &PasswordPolicyError{Violations: []string{fmt.Sprintf("must not reuse any of the last %d passwords", n)}}
+		}
+	}
+	return nil
+}
+
+// DeleteUser 删除用户（按应用隔离），同时吊销其已签发的令牌
 func (s *UserService) DeleteUser(id uint, appID uint) error {
-	return s.DB.Where("id = ? AND app_id = ?", id, appID).Delete(&model.User{}).Error
+	result := s.DB.Where("id = ? AND app_id = ?", id, appID).Delete(&model.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 && s.TokenRevocationService != nil {
+		if err := s.TokenRevocationService.RevokeUserTokens(id); err != nil {
+			return fmt.Errorf("failed to revoke tokens of deleted user: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // GetUserByID 根据ID获取用户（按应用隔离）
 func (s *UserService) GetUserByID(id uint, appID uint) (*model.User, error) {
 	var user model.User
-	if err := s.DB.Preload("Roles").Where("id = ? AND app_id = ?", id, appID).First(&user).Error; err != nil {
+	if err := s.DB.Preload("Roles").Preload("Departments").Preload("Groups").Where("id = ? AND app_id = ?", id, appID).First(&user).Error; err != nil {
 		return nil, err
 	}
 
-	// 填充 RoleIDs
+	// 填充 RoleIDs、DepartmentIDs 与 GroupIDs
 	for _, role := range user.Roles {
 		user.RoleIDs = append(user.RoleIDs, role.ID)
 	}
+	for _, dept := range user.Departments {
+		user.DepartmentIDs = append(user.DepartmentIDs, dept.ID)
+	}
+	for _, group := range user.Groups {
+		user.GroupIDs = append(user.GroupIDs, group.ID)
+	}
 
 	return &user, nil
 }
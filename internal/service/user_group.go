@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"Authos/internal/model"
+)
+
+// ErrUserGroupNameExists 同一应用内用户组名称重复
+var ErrUserGroupNameExists = errors.New("user group with this name already exists in this application")
+
+// UserGroupService 用户组服务
+type UserGroupService struct {
+	DB *gorm.DB
+}
+
+// NewUserGroupService 创建用户组服务实例
+func NewUserGroupService(db *gorm.DB) *UserGroupService {
+	return &UserGroupService{DB: db}
+}
+
+// checkUserGroupName 校验名称非空且在应用内唯一（excludeID 为正在更新的用户组）
+func (s *UserGroupService) checkUserGroupName(appID uint, name string, excludeID uint) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name is required")
+	}
+	var count int64
+	if err := s.DB.Model(&model.UserGroup{}).Where("app_id = ? AND name = ? AND id <> ?", appID, name, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserGroupNameExists
+	}
+	return nil
+}
+
+// CreateUserGroup 创建用户组（按应用隔离）
+func (s *UserGroupService) CreateUserGroup(group *model.UserGroup) error {
+	group.ID = 0
+	group.Name = strings.TrimSpace(group.Name)
+	if err := s.checkUserGroupName(group.AppID, group.Name, 0); err != nil {
+		return err
+	}
+	return s.DB.Omit("Users", "Roles").Create(group).Error
+}
+
+// UpdateUserGroup 更新用户组名称与描述（按应用隔离）
+func (s *UserGroupService) UpdateUserGroup(group *model.UserGroup) error {
+	if _, err := s.GetUserGroupByID(group.ID, group.AppID); err != nil {
+		return err
+	}
+	group.Name = strings.TrimSpace(group.Name)
+	if err := s.checkUserGroupName(group.AppID, group.Name, group.ID); err != nil {
+		return err
+	}
+	return s.DB.Model(group).Where("id = ? AND app_id = ?", group.ID, group.AppID).
+		Select("Name", "Description").Updates(group).Error
+}
+
+// DeleteUserGroup 删除用户组（按应用隔离），成员随之失去用户组的角色
+func (s *UserGroupService) DeleteUserGroup(id uint, appID uint) error {
+	group, err := s.GetUserGroupByID(id, appID)
+	if err != nil {
+		return err
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		// 移除成员与角色关联
+		if err := tx.Model(group).Association("Users").Clear(); err != nil {
+			return fmt.Errorf("failed to remove members of user group %d: %w", id, err)
+		}
+		if err := tx.Model(group).Association("Roles").Clear(); err != nil {
+			return fmt.Errorf("failed to remove roles of user group %d: %w", id, err)
+		}
+		return tx.Delete(group).Error
+	})
+}
+
+// GetUserGroupByID 根据ID获取用户组（按应用隔离），包含授予用户组的角色
+func (s *UserGroupService) GetUserGroupByID(id uint, appID uint) (*model.UserGroup, error) {
+	var group model.UserGroup
+	if err := s.DB.Preload("Roles").Where("id = ? AND app_id = ?", id, appID).First(&group).Error; err != nil {
+		return nil, err
+	}
+	for _, role := range group.Roles {
+		group.RoleIDs = append(group.RoleIDs, role.ID)
+	}
+	group.MemberCount = s.DB.Model(&group).Association("Users").Count()
+	return &group, nil
+}
+
+// ListUserGroupsByApp 列出指定应用的用户组，包含角色与成员数量
+func (s *UserGroupService) ListUserGroupsByApp(appID uint, name string) ([]*model.UserGroup, error) {
+	db := s.DB.Preload("Roles").Where("app_id = ?", appID)
+	if name != "" {
+		db = db.Where("name LIKE ?", "%"+name+"%")
+	}
+	var groups []*model.UserGroup
+	if err := db.Order("id desc").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	// 成员数量一次查询
+	var counts []struct {
+		UserGroupID uint
+		Count       int64
+	}
+	if err := s.DB.Table("user_group_members").Select("user_group_id, COUNT(*) AS count").
+		Group("user_group_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	countByGroup := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		countByGroup[c.UserGroupID] = c.Count
+	}
+
+	for _, group := range groups {
+		group.RoleIDs = make([]uint, 0, len(group.Roles))
+		for _, role := range group.Roles {
+			group.RoleIDs = append(group.RoleIDs, role.ID)
+		}
+		group.MemberCount = countByGroup[group.ID]
+	}
+	return groups, nil
+}
+
+// ListUserGroupMembers 获取用户组成员（按应用隔离）
+func (s *UserGroupService) ListUserGroupMembers(id uint, appID uint) ([]*model.User, error) {
+	group, err := s.GetUserGroupByID(id, appID)
+	if err != nil {
+		return nil, err
+	}
+	var users []*model.User
+	if err := s.DB.Model(group).Order("id").Association("Users").Find(&users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// findAppUsers 查询同一应用内的用户，存在不属于该应用的用户时报错
+func (s *UserGroupService) findAppUsers(appID uint, userIDs []uint) ([]*model.User, error) {
+	userIDs = uniqueUints(userIDs)
+	if len(userIDs) == 0 {
+		return nil, errors.New("userIds is required")
+	}
+	var users []*model.User
+	if err := s.DB.Where("id IN ? AND app_id = ?", userIDs, appID).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) != len(userIDs) {
+		return nil, errors.New("user not found in this application")
+	}
+	return users, nil
+}
+
+// AddUserGroupMembers 将用户加入用户组（已是成员的忽略）
+func (s *UserGroupService) AddUserGroupMembers(id uint, appID uint, userIDs []uint) error {
+	group, err := s.GetUserGroupByID(id, appID)
+	if err != nil {
+		return err
+	}
+	users, err := s.findAppUsers(appID, userIDs)
+	if err != nil {
+		return err
+	}
+	return s.DB.Model(group).Association("Users").Append(users)
+}
+
+// RemoveUserGroupMembers 将用户移出用户组
+func (s *UserGroupService) RemoveUserGroupMembers(id uint, appID uint, userIDs []uint) error {
+	group, err := s.GetUserGroupByID(id, appID)
+	if err != nil {
+		return err
+	}
+	users, err := s.findAppUsers(appID, userIDs)
+	if err != nil {
+		return err
+	}
+	return s.DB.Model(group).Association("Users").Delete(users)
+}
+
+// SetUserGroupRoles 替换授予用户组的角色，组内成员继承这些角色；角色必须属于同一应用
+func (s *UserGroupService) SetUserGroupRoles(id uint, appID uint, roleIDs []uint) error {
+	group, err := s.GetUserGroupByID(id, appID)
+	if err != nil {
+		return err
+	}
+	roleIDs = uniqueUints(roleIDs)
+	var roles []*model.Role
+	if len(roleIDs) > 0 {
+		if err := s.DB.Where("id IN ? AND app_id = ?", roleIDs, appID).Find(&roles).Error; err != nil {
+			return err
+		}
+		if len(roles) != len(roleIDs) {
+			return errors.New("role not found in this application")
+		}
+	}
+	return s.DB.Model(group).Association("Roles").Replace(roles)
+}
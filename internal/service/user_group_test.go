@@ -0,0 +1,130 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"Authos/internal/model"
+)
+
+func TestUserGroupRoles(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+	roleService := NewRoleService(db, casbinService)
+	userGroupService := NewUserGroupService(db)
+	departmentService := NewDepartmentService(db)
+	menuService := NewMenuService(db, casbinService)
+
+	app := &model.Application{Name: "group-app", Code: "group-app", Status: 1}
+	other := &model.Application{Name: "group-other", Code: "group-other", Status: 1}
+	db.Create(app)
+	db.Create(other)
+	alice := &model.User{Username: "group-alice", Password: "password", Status: 1, AppID: app.ID}
+	bob := &model.User{Username: "group-bob", Password: "password", Status: 1, AppID: app.ID}
+	outsider := &model.User{Username: "group-outsider", Password: "password", Status: 1, AppID: other.ID}
+	for _, user := range []*model.User{alice, bob, outsider} {
+		db.Create(user)
+	}
+
+	viewer := &model.Role{Name: "viewer", AppID: app.ID}
+	editor := &model.Role{Name: "editor", AppID: app.ID}
+	foreign := &model.Role{Name: "foreign", AppID: other.ID}
+	for _, role := range []*model.Role{viewer, editor, foreign} {
+		db.Create(role)
+	}
+	// alice 直接持有 viewer
+	db.Model(alice).Association("Roles").Append(viewer)
+
+	grant := func(role *model.Role, key string) {
+		permission, err := apiPermissionService.CreateApiPermission(app.ID, key, key, "/api/"+key, model.HTTP_ALL, "")
+		if err != nil {
+			t.Fatalf("failed to create api permission: %v", err)
+		}
+		if err := apiPermissionService.AddApiPermissionToRole(app.ID, role.UUID, permission.UUID); err != nil {
+			t.Fatalf("failed to add permission to role: %v", err)
+		}
+	}
+	grant(viewer, "doc.read")
+	grant(editor, "doc.write")
+	menu := &model.Menu{Name: "editor", Path: "/editor", Type: 1, AppID: app.ID}
+	db.Create(menu)
+	if err := roleService.AssignMenus(editor.ID, app.ID, []uint{menu.ID}); err != nil {
+		t.Fatalf("failed to assign menus: %v", err)
+	}
+
+	group := &model.UserGroup{Name: "writers", AppID: app.ID}
+	if err := userGroupService.CreateUserGroup(group); err != nil {
+		t.Fatalf("failed to create user group: %v", err)
+	}
+	if err := userGroupService.CreateUserGroup(&model.UserGroup{Name: "writers", AppID: app.ID}); !errors.Is(err, ErrUserGroupNameExists) {
+		t.Fatalf("expected duplicate name rejected, got %v", err)
+	}
+	if err := userGroupService.SetUserGroupRoles(group.ID, app.ID, []uint{foreign.ID}); err == nil {
+		t.Fatal("expected role from another application rejected")
+	}
+	if err := userGroupService.SetUserGroupRoles(group.ID, app.ID, []uint{editor.ID}); err != nil {
+		t.Fatalf("failed to set user group roles: %v", err)
+	}
+	if err := userGroupService.AddUserGroupMembers(group.ID, app.ID, []uint{alice.ID, outsider.ID}); err == nil {
+		t.Fatal("expected user from another application rejected")
+	}
+	if err := userGroupService.AddUserGroupMembers(group.ID, app.ID, []uint{alice.ID, bob.ID}); err != nil {
+		t.Fatalf("failed to add members: %v", err)
+	}
+
+	// 有效角色 = 直接角色 ∪ 用户组角色
+	results, err := casbinService.CheckPermissions(alice.ID, []PermissionRequest{
+		{Obj: "doc.read", Act: "GET"},
+		{Obj: "doc.write", Act: "POST"},
+	}, nil)
+	if err != nil || !results[0] || !results[1] {
+		t.Fatalf("expected direct and group roles allowed, got %v, err=%v", results, err)
+	}
+	if allowed, _ := casbinService.CheckPermission(bob.ID, "doc.read", "GET", nil); allowed {
+		t.Fatal("expected bob denied without viewer role")
+	}
+	menus, err := menuService.GetUserMenuTree(bob.ID)
+	if err != nil || len(menus) != 1 || menus[0].ID != menu.ID {
+		t.Fatalf("unexpected group member menus: %+v, err=%v", menus, err)
+	}
+
+	// 用户组与部门都授予同一角色时不重复
+	dept := &model.Department{Name: "writers dept", AppID: app.ID}
+	if err := departmentService.CreateDepartment(dept); err != nil {
+		t.Fatalf("failed to create department: %v", err)
+	}
+	departmentService.SetDepartmentRoles(dept.ID, app.ID, []uint{editor.ID})
+	departmentService.SetUserDepartments(bob.ID, app.ID, []uint{dept.ID})
+	explanation, err := casbinService.ExplainPermission(bob.ID, "doc.write", "POST", nil)
+	if err != nil || !explanation.Allowed || len(explanation.Roles) != 1 {
+		t.Fatalf("unexpected explanation: %+v, err=%v", explanation, err)
+	}
+
+	groups, err := userGroupService.ListUserGroupsByApp(app.ID, "")
+	if err != nil || len(groups) != 1 || groups[0].MemberCount != 2 || groups[0].RoleIDs[0] != editor.ID {
+		t.Fatalf("unexpected user groups: %+v, err=%v", groups, err)
+	}
+
+	// 移出用户组、删除用户组后失去组角色
+	if err := userGroupService.RemoveUserGroupMembers(group.ID, app.ID, []uint{alice.ID}); err != nil {
+		t.Fatalf("failed to remove members: %v", err)
+	}
+	if allowed, _ := casbinService.CheckPermission(alice.ID, "doc.write", "POST", nil); allowed {
+		t.Fatal("expected alice denied after leaving group")
+	}
+	departmentService.SetUserDepartments(bob.ID, app.ID, nil)
+	if err := userGroupService.DeleteUserGroup(group.ID, app.ID); err != nil {
+		t.Fatalf("failed to delete user group: %v", err)
+	}
+	if allowed, _ := casbinService.CheckPermission(bob.ID, "doc.write", "POST", nil); allowed {
+		t.Fatal("expected bob denied after group deleted")
+	}
+	if members, _ := userGroupService.ListUserGroupMembers(group.ID, app.ID); members != nil {
+		t.Fatalf("expected deleted group not found, got %+v", members)
+	}
+}
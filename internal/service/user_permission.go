@@ -0,0 +1,216 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"Authos/internal/model"
+)
+
+// UserApi 用户可访问的接口
+type UserApi struct {
+	Key    string `json:"key"`    // 权限标识
+	Path   string `json:"path"`   // 接口路径
+	Method string `json:"method"` // HTTP方法，* 表示全部方法
+	// 方法为 * 时被拒绝策略排除的方法
+	DeniedMethods []string `json:"deniedMethods,omitempty"`
+	// 是否受附加条件的策略影响，实际是否放行以鉴权结果为准
+	Conditional bool `json:"conditional,omitempty"`
+}
+
+// UserPermissions 用户在应用内的有效权限（合并全部角色，超级管理员展开为应用内全部权限）
+type UserPermissions struct {
+	UserID      uint      `json:"userId"`
+	AppID       uint      `json:"appId"`
+	SuperAdmin  bool      `json:"superAdmin"`
+	Permissions []string  `json:"permissions"` // 权限标识
+	Apis        []UserApi `json:"apis"`        // 可访问的接口
+	Buttons     []string  `json:"buttons"`     // 按钮标识
+	Version     string    `json:"version"`     // 权限内容摘要，角色、授权或菜单变化时随之改变，可用作 ETag
+}
+
+// GetUserPermissions 获取用户在应用内的有效权限
+func (s *ApiPermissionService) GetUserPermissions(userID, appID uint) (*UserPermissions, error) {
+	var user model.User
+	if err := s.DB.Where("id = ? AND app_id = ?", userID, appID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if err := loadEffectiveRoles(s.DB, &user); err != nil {
+		return nil, err
+	}
+
+	result := &UserPermissions{
+		UserID:      user.ID,
+		AppID:       appID,
+		Permissions: []string{},
+		Apis:        []UserApi{},
+		Buttons:     []string{},
+	}
+	// 超级管理员身份可沿继承链继承
+	expanded, err := s.CasbinService.expandRoles(user.Roles)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range expanded {
+		if role.IsSuperAdmin {
+			result.SuperAdmin = true
+		}
+	}
+
+	// 1. 接口权限：超级管理员为应用内全部权限，否则为各角色（含继承）允许策略的并集，再排除任一角色拒绝的方法
+	// （权限标识 -> 允许/拒绝的方法）
+	var permissions []model.ApiPermission
+	grants := make(map[string]map[string]bool)
+	denies := make(map[string]map[string]bool)
+	conditional := make(map[string]bool) // 存在附加条件策略的权限标识
+	if result.SuperAdmin {
+		if err := s.DB.Where("app_id = ?", appID).Find(&permissions).Error; err != nil {
+			return nil, err
+		}
+		for _, permission := range permissions {
+			grants[permission.Key] = map[string]bool{model.HTTP_ALL: true}
+		}
+	} else {
+		for _, role := range user.Roles {
+			// 包含沿继承链从祖先角色继承的策略
+			policies, err := s.CasbinService.Enforcer.GetImplicitPermissionsForUser(roleSubject(role.UUID))
+			if err != nil {
+				return nil, err
+			}
+			for _, policy := range policies {
+				if len(policy) < 3 {
+					continue
+				}
+				// 附加条件的拒绝策略不一定生效，不排除方法，只标记为有条件
+				if isConditionalPolicy(policy) {
+					conditional[policy[1]] = true
+					if isDenyPolicy(policy) {
+						continue
+					}
+				}
+				target := grants
+				if isDenyPolicy(policy) {
+					target = denies
+				}
+				if target[policy[1]] == nil {
+					target[policy[1]] = make(map[string]bool)
+				}
+				target[policy[1]][policy[2]] = true
+			}
+		}
+		if len(grants) > 0 {
+			keys := make([]string, 0, len(grants))
+			for key := range grants {
+				keys = append(keys, key)
+			}
+			if err := s.DB.Where("key IN ? AND app_id = ?", keys, appID).Find(&permissions).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	keySet := make(map[string]bool)
+	apiSet := make(map[[3]string]UserApi)
+	for _, permission := range permissions {
+		denied := denies[permission.Key]
+		if denied[model.HTTP_ALL] {
+			continue
+		}
+		for act := range grants[permission.Key] {
+			api := UserApi{Key: permission.Key, Path: permission.Path, Method: permission.Method}
+			switch {
+			case act == model.HTTP_ALL:
+			case permission.Method == model.HTTP_ALL || permission.Method == act:
+				// 策略只授权了部分方法
+				api.Method = act
+			default:
+				continue
+			}
+			if denied[api.Method] {
+				continue
+			}
+			if api.Method == model.HTTP_ALL && len(denied) > 0 {
+				for method := range denied {
+					api.DeniedMethods = append(api.DeniedMethods, method)
+				}
+				sort.Strings(api.DeniedMethods)
+			}
+			api.Conditional = conditional[permission.Key]
+			keySet[permission.Key] = true
+			apiSet[[3]string{api.Key, api.Path, api.Method}] = api
+		}
+	}
+	for key := range keySet {
+		result.Permissions = append(result.Permissions, key)
+	}
+	for _, api := range apiSet {
+		result.Apis = append(result.Apis, api)
+	}
+
+	// 2. 按钮：超级管理员为应用内全部按钮，否则为各角色（含继承）菜单中的按钮
+	var menus []*model.Menu
+	if result.SuperAdmin {
+		if err := s.DB.Where("app_id = ? AND type = ?", appID, 2).Find(&menus).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		menus, err = s.CasbinService.roleMenus(user.Roles)
+		if err != nil {
+			return nil, err
+		}
+	}
+	buttonSet := make(map[string]bool)
+	for _, menu := range menus {
+		if menu.Type == 2 && menu.Code != "" && menu.AppID == appID && !buttonSet[menu.Code] {
+			buttonSet[menu.Code] = true
+			result.Buttons = append(result.Buttons, menu.Code)
+		}
+	}
+
+	sort.Strings(result.Permissions)
+	sort.Strings(result.Buttons)
+	sort.Slice(result.Apis, func(i, j int) bool {
+		a, b := result.Apis[i], result.Apis[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+
+	result.Version = permissionsVersion(result)
+	return result, nil
+}
+
+// FilterByAPIKey 按个人 API 密钥的权限范围裁剪有效权限（按钮不受影响），并重新计算版本
+func (p *UserPermissions) FilterByAPIKey(key *model.APIKey) {
+	if key == nil || len(key.Scopes) == 0 {
+		return
+	}
+
+	permissions := []string{}
+	for _, permission := range p.Permissions {
+		if APIKeyAllows(key, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	apis := []UserApi{}
+	for _, api := range p.Apis {
+		if APIKeyAllows(key, api.Key) {
+			apis = append(apis, api)
+		}
+	}
+	p.Permissions, p.Apis = permissions, apis
+	p.Version = permissionsVersion(p)
+}
+
+// permissionsVersion 计算有效权限内容的摘要
+func permissionsVersion(p *UserPermissions) string {
+	data, _ := json.Marshal([]interface{}{p.SuperAdmin, p.Permissions, p.Apis, p.Buttons})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
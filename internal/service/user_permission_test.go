@@ -0,0 +1,70 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"Authos/internal/model"
+)
+
+func TestGetUserPermissions(t *testing.T) {
+	db := newTestDB(t)
+
+	casbinService, err := NewCasbinService(db)
+	if err != nil {
+		t.Fatalf("failed to create casbin service: %v", err)
+	}
+	apiPermissionService := NewApiPermissionService(db, casbinService, nil)
+
+	app := &model.Application{Name: "perm-app", Code: "perm-app", Status: 1}
+	db.Create(app)
+	role := &model.Role{Name: "clerk", AppID: app.ID}
+	db.Create(role)
+	user := &model.User{Username: "clerk", Password: "password", Status: 1, AppID: app.ID}
+	db.Create(user)
+	db.Model(user).Association("Roles").Append(role)
+
+	read, _ := apiPermissionService.CreateApiPermission(app.ID, "order.read", "查看订单", "/api/orders", model.HTTP_GET, "")
+	apiPermissionService.CreateApiPermission(app.ID, "order.delete", "删除订单", "/api/orders", model.HTTP_DELETE, "")
+	if err := apiPermissionService.AddApiPermissionToRole(app.ID, role.UUID, read.UUID); err != nil {
+		t.Fatalf("failed to add permission to role: %v", err)
+	}
+	button := &model.Menu{Name: "导出", Type: 2, Code: "order:export", AppID: app.ID}
+	db.Create(button)
+	db.Model(role).Association("Menus").Append(button)
+
+	perms, err := apiPermissionService.GetUserPermissions(user.ID, app.ID)
+	if err != nil {
+		t.Fatalf("failed to get user permissions: %v", err)
+	}
+	if !reflect.DeepEqual(perms.Permissions, []string{"order.read"}) || !reflect.DeepEqual(perms.Buttons, []string{"order:export"}) {
+		t.Fatalf("unexpected permissions: %+v", perms)
+	}
+	if len(perms.Apis) != 1 || !reflect.DeepEqual(perms.Apis[0], UserApi{Key: "order.read", Path: "/api/orders", Method: model.HTTP_GET}) {
+		t.Fatalf("unexpected apis: %+v", perms.Apis)
+	}
+
+	// 内容不变时版本不变；授权变化后版本随之改变
+	again, _ := apiPermissionService.GetUserPermissions(user.ID, app.ID)
+	if again.Version != perms.Version {
+		t.Fatal("expected stable version for unchanged permissions")
+	}
+	apiPermissionService.RemoveApiPermissionFromRole(app.ID, role.UUID, read.UUID)
+	changed, _ := apiPermissionService.GetUserPermissions(user.ID, app.ID)
+	if changed.Version == perms.Version || len(changed.Permissions) != 0 {
+		t.Fatalf("expected version to change after revoking permission: %+v", changed)
+	}
+
+	// 超级管理员展开为应用内全部权限与按钮
+	db.Model(&model.Role{}).Where("id = ?", role.ID).Update("is_super_admin", true)
+	admin, _ := apiPermissionService.GetUserPermissions(user.ID, app.ID)
+	if !admin.SuperAdmin || !reflect.DeepEqual(admin.Permissions, []string{"order.delete", "order.read"}) || len(admin.Buttons) != 1 {
+		t.Fatalf("expected super admin to get every permission: %+v", admin)
+	}
+
+	// API 密钥按权限范围裁剪
+	admin.FilterByAPIKey(&model.APIKey{Scopes: []string{"order.read"}})
+	if !reflect.DeepEqual(admin.Permissions, []string{"order.read"}) || len(admin.Apis) != 1 {
+		t.Fatalf("expected api key scopes to narrow permissions: %+v", admin)
+	}
+}
@@ -41,6 +41,32 @@ func corsConfig() echo.MiddlewareFunc {
 	})
 }
 
+// cleanupTask 定期清理过期数据的任务
+type cleanupTask struct {
+	name string
+	run  func() error
+}
+
+// runCleanupTasks 立即执行一次全部清理任务，之后按间隔在后台定期执行
+func runCleanupTasks(tasks []cleanupTask, interval time.Duration) {
+	runAll := func() {
+		for _, task := range tasks {
+			if err := task.run(); err != nil {
+				service.Log.Warnf("Failed to cleanup expired %s: %v", task.name, err)
+			}
+		}
+	}
+
+	runAll()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runAll()
+		}
+	}()
+}
+
 func main() {
 	// 加载配置
 	cfg, err := service.LoadConfig("config.yaml")
@@ -53,8 +79,26 @@ func main() {
 	service.InitGlobalLogger(logPath)
 
 	// 配置信息
-	jwtSecret := "authos-secret-key1212" // 实际部署时应使用环境变量
-	jwtExpireTime := 24 * time.Hour
+	jwtSecret := cfg.Token.Secret
+	if jwtSecret == "" {
+		jwtSecret = "authos-secret-key1212" // 实际部署时应在配置文件中设置
+	}
+	jwtExpireTime := 2 * time.Hour                 // 访问令牌有效期（短期）
+	refreshTokenExpireTime := 7 * 24 * time.Hour   // 刷新令牌有效期（每次刷新时轮换）
+	authorizationCodeExpireTime := 5 * time.Minute // 授权码有效期（一次性）
+	mfaChallengeExpireTime := 5 * time.Minute      // 两步登录挑战令牌有效期
+	passwordResetExpireTime := 30 * time.Minute    // 密码重置令牌有效期（一次性）
+	signingKeyRefreshInterval := time.Minute       // 签名密钥库刷新间隔（同步其他实例的轮换与退役）
+	cleanupInterval := 10 * time.Minute            // 过期数据清理间隔（请求签名随机串每次签名请求都会写入）
+
+	// 追加弱密码字典
+	if cfg.Password.DictionaryFile != "" {
+		size, err := service.LoadPasswordDictionary(cfg.Password.DictionaryFile)
+		if err != nil {
+			service.Log.Fatalf("Failed to load password dictionary: %v", err)
+		}
+		service.Log.Infof("Password dictionary loaded, %d entries", size)
+	}
 
 	// 初始化数据库服务
 	dbService, err := service.NewDBService(cfg)
@@ -62,37 +106,113 @@ func main() {
 		service.Log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// 加载服务端加密密钥（加密保存请求签名密钥与 TOTP 密钥）
+	secretBox, err := service.LoadOrCreateSecretBox(cfg.Security.EncryptionKeyFile)
+	if err != nil {
+		service.Log.Fatalf("Failed to load encryption key: %v", err)
+	}
+	if migrated, err := service.MigrateTOTPSecrets(dbService.DB, secretBox); err != nil {
+		service.Log.Fatalf("Failed to encrypt totp secrets: %v", err)
+	} else if migrated > 0 {
+		service.Log.Infof("Encrypted %d plaintext totp secrets", migrated)
+	}
+
 	// 初始化 Casbin 服务
 	casbinService, err := service.NewCasbinService(dbService.DB)
 	if err != nil {
 		service.Log.Fatalf("Failed to initialize casbin service: %v", err)
 	}
 
+	// 初始化令牌相关服务
+	refreshTokenService := service.NewRefreshTokenService(dbService.DB, refreshTokenExpireTime)
+	tokenRevocationService := service.NewTokenRevocationService(dbService.DB, casbinService, refreshTokenService)
+	signingKeyService := service.NewSigningKeyService(dbService.DB)
+	authorizationCodeService := service.NewAuthorizationCodeService(dbService.DB, authorizationCodeExpireTime)
+	mfaService := service.NewMFAService(dbService.DB, casbinService, mfaChallengeExpireTime)
+	mfaService.SecretBox = secretBox
+	loginGuardService := service.NewLoginGuardService(dbService.DB)
+	passwordResetService := service.NewPasswordResetService(dbService.DB, passwordResetExpireTime, cfg.Password.ResetURL)
+	mailer := service.NewMailer(cfg.SMTP)
+	apiKeyService := service.NewAPIKeyService(dbService.DB)
+
 	// 初始化各种服务
-	userService := service.NewUserService(dbService.DB)
+	userService := service.NewUserService(dbService.DB, tokenRevocationService)
 	roleService := service.NewRoleService(dbService.DB, casbinService)
-	menuService := service.NewMenuService(dbService.DB)
+	menuService := service.NewMenuService(dbService.DB, casbinService)
+	departmentService := service.NewDepartmentService(dbService.DB)
+	userGroupService := service.NewUserGroupService(dbService.DB)
 	apiPermissionService := service.NewApiPermissionService(dbService.DB, casbinService, roleService)
 	applicationService := service.NewApplicationService(dbService.DB)
+	applicationService.SecretBox = secretBox
+
+	// 旧版明文应用密钥迁移为哈希密钥（默认应用的初始密钥即其 UUID），须在设置 SecretBox 后执行以保存请求签名密钥
+	if migrated, err := applicationService.MigrateLegacySecrets(); err != nil {
+		service.Log.Fatalf("Failed to migrate application secrets: %v", err)
+	} else if migrated > 0 {
+		service.Log.Infof("Migrated %d plaintext application secrets to hashed secrets", migrated)
+	}
+
+	// 清理过期的吊销记录、授权码、两步登录挑战、登录失败计数、密码重置令牌与请求签名随机串：启动时执行一次，之后定期执行
+	runCleanupTasks([]cleanupTask{
+		{"revoked tokens", tokenRevocationService.CleanupExpired},
+		{"authorization codes", authorizationCodeService.CleanupExpired},
+		{"mfa challenges", mfaService.CleanupExpired},
+		{"login failures", loginGuardService.CleanupExpired},
+		{"password reset tokens", passwordResetService.CleanupExpired},
+		{"request nonces", applicationService.CleanupExpiredNonces},
+	}, cleanupInterval)
 	auditLogService := service.NewAuditLogService(dbService.DB)
 	configDictionaryService := service.NewConfigDictionaryService(dbService.DB)
 
 	// 初始化 JWT 配置
 	jwtConfig := service.NewJWTConfig(jwtSecret, jwtExpireTime)
+	jwtConfig.Issuer = cfg.Token.Issuer
+
+	// 非对称签名：加载（或首次生成）签名密钥
+	if cfg.Token.Algorithm != service.AlgorithmHS256 {
+		signingKey, err := signingKeyService.LoadOrCreateActiveKey(cfg.Token.Algorithm)
+		if err != nil {
+			service.Log.Fatalf("Failed to load signing key: %v", err)
+		}
+		// 超过访问令牌有效期的旧密钥退役，其余未退役密钥加载到密钥库
+		if err := signingKeyService.RetireExpiredKeys(jwtExpireTime); err != nil {
+			service.Log.Fatalf("Failed to retire expired signing keys: %v", err)
+		}
+		if err := signingKeyService.LoadKeyStore(jwtConfig.Keys); err != nil {
+			service.Log.Fatalf("Failed to load signing keys: %v", err)
+		}
+		service.Log.Infof("Tokens are signed with %s key %s", signingKey.Algorithm, signingKey.KID)
+		go signingKeyService.WatchKeyStore(jwtConfig.Keys, jwtExpireTime, signingKeyRefreshInterval)
+	}
 
 	// 初始化 HTTP 处理器
-	authHandler := handler.NewAuthHandler(userService, applicationService, auditLogService, jwtConfig)
+	authHandler := handler.NewAuthHandler(userService, applicationService, auditLogService, refreshTokenService, tokenRevocationService, mfaService, loginGuardService, jwtConfig)
 	userHandler := handler.NewUserHandler(userService)
 	roleHandler := handler.NewRoleHandler(roleService)
 	menuHandler := handler.NewMenuHandler(menuService)
+	departmentHandler := handler.NewDepartmentHandler(departmentService)
+	userGroupHandler := handler.NewUserGroupHandler(userGroupService)
 	apiPermissionHandler := handler.NewApiPermissionHandler(apiPermissionService)
 	applicationHandler := handler.NewApplicationHandler(applicationService)
 	auditLogHandler := handler.NewAuditLogHandler(auditLogService)
-	authzHandler := handler.NewAuthzHandler(casbinService, menuService, applicationService, apiPermissionService, jwtConfig)
+	authzHandler := handler.NewAuthzHandler(casbinService, menuService, applicationService, apiPermissionService, tokenRevocationService, apiKeyService, jwtConfig)
 	configDictionaryHandler := handler.NewConfigDictionaryHandler(configDictionaryService)
+	sessionHandler := handler.NewSessionHandler(tokenRevocationService, userService, applicationService)
+	signingKeyHandler := handler.NewSigningKeyHandler(signingKeyService, jwtConfig)
+	oauthHandler := handler.NewOAuthHandler(userService, applicationService, auditLogService, refreshTokenService, authorizationCodeService, tokenRevocationService, mfaService, loginGuardService, casbinService, jwtConfig)
+	mfaHandler := handler.NewMFAHandler(mfaService, userService, applicationService)
+	loginGuardHandler := handler.NewLoginGuardHandler(loginGuardService, userService, applicationService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, userService)
+	passwordResetHandler := handler.NewPasswordResetHandler(passwordResetService, userService, applicationService, auditLogService, tokenRevocationService, loginGuardService, mailer)
 
 	// 初始化 JWT 中间件
-	jwtMiddleware := customMiddleware.NewJWTMiddleware(jwtConfig)
+	jwtMiddleware := customMiddleware.NewJWTMiddleware(jwtConfig, tokenRevocationService, apiKeyService, casbinService)
+
+	// 初始化系统管理员中间件（全局管理接口）
+	systemAdminMiddleware := customMiddleware.NewSystemAdminMiddleware()
+
+	// 初始化请求签名中间件（公共接口可用 HMAC 签名替代请求体中的 appSecret）
+	signatureMiddleware := customMiddleware.NewSignatureMiddleware(applicationService)
 
 	// 创建 Echo 实例
 	e := echo.New()
@@ -115,16 +235,39 @@ func main() {
 	// 静态文件服务（具体实现通过 registerStatic 抽象，支持嵌入或外部静态目录）
 	registerStatic(e)
 
+	// 公钥发布 (JWKS)，供租户后端离线验证令牌
+	e.GET("/.well-known/jwks.json", signingKeyHandler.JWKS)
+
+	// OAuth 2.0 授权端点（托管登录页 + 授权码 / PKCE）与令牌端点
+	e.GET("/oauth/authorize", oauthHandler.Authorize)
+	e.POST("/oauth/authorize", oauthHandler.AuthorizeSubmit)
+	e.POST("/oauth/token", oauthHandler.Token)
+	e.POST("/oauth/introspect", oauthHandler.Introspect) // 令牌内省 (RFC 7662)
+
+	// OpenID Connect 发现文档与用户信息端点
+	e.GET("/.well-known/openid-configuration", oauthHandler.OpenIDConfiguration)
+	e.GET("/userinfo", oauthHandler.UserInfo)
+	e.POST("/userinfo", oauthHandler.UserInfo)
+
 	// 公共路由
-	public := e.Group("/api/public")
+	public := e.Group("/api/public", signatureMiddleware.Middleware())
 	{
 		// 认证相关
 		public.POST("/login", authHandler.Login)
 		public.POST("/system-login", authHandler.SystemLogin)
-		public.POST("/app-login", authHandler.AppLogin)
-		public.POST("/proxy-login", authHandler.ProxyLogin)                  // 新增：后端代理登录
+		public.POST("/app-login", authHandler.AppLogin)                      // 已废弃：请使用 /oauth/token (client_credentials)
+		public.POST("/proxy-login", authHandler.ProxyLogin)                  // 已废弃：请使用 /oauth/token (password)
 		public.POST("/check-access", authzHandler.CheckPermissionWithSecret) // 新增：统一鉴权
+		public.POST("/check-access/batch", authzHandler.CheckAccessBatch)    // 批量统一鉴权
+		public.POST("/refresh", authHandler.RefreshToken)                    // 刷新令牌（轮换）
 		public.POST("/logout", authHandler.Logout)
+		public.POST("/change-password", authHandler.ChangePassword)        // 凭旧密码修改密码（密码过期时使用）
+		public.POST("/reset-password", passwordResetHandler.ResetPassword) // 凭管理员签发的重置令牌设置新密码
+		public.POST("/mfa/enroll", authHandler.EnrollMFAChallenge)         // 两步登录：登录时绑定 MFA
+		public.POST("/mfa/verify", authHandler.VerifyMFAChallenge)         // 两步登录：提交验证码换取令牌
+
+		// 令牌所属用户的有效权限（支持 ETag / If-None-Match）
+		public.POST("/user-permissions", authzHandler.GetUserPermissionsWithSecret)
 
 	}
 
@@ -139,6 +282,19 @@ func main() {
 		api.GET("/applications/:id", applicationHandler.GetApplication)
 		api.PUT("/applications/:id", applicationHandler.UpdateApplication)
 		api.DELETE("/applications/:id", applicationHandler.DeleteApplication)
+		api.PUT("/applications/:id/redirect-uris", applicationHandler.UpdateRedirectURIs, systemAdminMiddleware.Middleware())
+		api.PUT("/applications/:id/mfa-policy", mfaHandler.UpdateMFAPolicy, systemAdminMiddleware.Middleware())
+		api.PUT("/applications/:id/login-policy", loginGuardHandler.UpdateLoginPolicy, systemAdminMiddleware.Middleware())
+		api.PUT("/applications/:id/password-policy", applicationHandler.UpdatePasswordPolicy, systemAdminMiddleware.Middleware())
+		// 应用凭据会返回或改变租户后端的认证方式，仅系统管理员可管理
+		api.GET("/applications/:id/secrets", applicationHandler.ListAppSecrets, systemAdminMiddleware.Middleware())
+		api.POST("/applications/:id/secrets", applicationHandler.CreateAppSecret, systemAdminMiddleware.Middleware())
+		api.DELETE("/applications/:id/secrets/:secretId", applicationHandler.RevokeAppSecret, systemAdminMiddleware.Middleware())
+		api.POST("/applications/:id/reset-secret", applicationHandler.ResetAppSecret, systemAdminMiddleware.Middleware())
+		api.GET("/applications/:id/client-certs", applicationHandler.ListAppClientCerts, systemAdminMiddleware.Middleware())
+		api.POST("/applications/:id/client-certs", applicationHandler.CreateAppClientCert, systemAdminMiddleware.Middleware())
+		api.DELETE("/applications/:id/client-certs/:certId", applicationHandler.DeleteAppClientCert, systemAdminMiddleware.Middleware())
+		api.POST("/applications/:id/revoke-sessions", sessionHandler.RevokeApplicationSessions, systemAdminMiddleware.Middleware())
 
 		// 权限检查
 		api.POST("/check", authzHandler.CheckPermission)
@@ -147,6 +303,24 @@ func main() {
 		// 用户导航菜单
 		api.GET("/user/nav", authzHandler.GetUserNav)
 
+		// 当前用户有效权限（支持 ETag / If-None-Match）
+		api.GET("/me/permissions", authzHandler.GetMyPermissions)
+
+		// 当前用户修改密码
+		api.PUT("/me/password", authHandler.ChangeMyPassword)
+
+		// 当前用户个人 API 密钥
+		api.GET("/me/api-keys", apiKeyHandler.ListMyAPIKeys)
+		api.POST("/me/api-keys", apiKeyHandler.CreateMyAPIKey)
+		api.DELETE("/me/api-keys/:keyId", apiKeyHandler.RevokeMyAPIKey)
+
+		// 当前用户两步验证
+		api.GET("/me/mfa", mfaHandler.GetMyMFA)
+		api.POST("/me/mfa/enroll", mfaHandler.EnrollMyMFA)
+		api.POST("/me/mfa/confirm", mfaHandler.ConfirmMyMFA)
+		api.POST("/me/mfa/disable", mfaHandler.DisableMyMFA)
+		api.POST("/me/mfa/recovery-codes", mfaHandler.RegenerateMyRecoveryCodes)
+
 		// 用户管理
 		users := api.Group("/users")
 		{
@@ -155,6 +329,15 @@ func main() {
 			users.GET("/:id", userHandler.GetUser)
 			users.PUT("/:id", userHandler.UpdateUser)
 			users.DELETE("/:id", userHandler.DeleteUser)
+			users.POST("/:id/revoke-sessions", sessionHandler.RevokeUserSessions)
+			users.DELETE("/:id/mfa", mfaHandler.ResetUserMFA)
+			users.GET("/:id/lockout", loginGuardHandler.GetUserLockout)
+			users.POST("/:id/unlock", loginGuardHandler.UnlockUser)
+			users.POST("/:id/password-reset", passwordResetHandler.CreatePasswordReset)
+			users.GET("/:id/api-keys", apiKeyHandler.ListUserAPIKeys)
+			users.POST("/:id/api-keys", apiKeyHandler.CreateUserAPIKey)
+			users.DELETE("/:id/api-keys/:keyId", apiKeyHandler.RevokeUserAPIKey)
+			users.PUT("/:id/departments", departmentHandler.SetUserDepartments)
 		}
 
 		// 仪表盘统计
@@ -173,6 +356,12 @@ func main() {
 			roles.PUT("/:id/menus", roleHandler.UpdateRoleMenus)
 			roles.POST("/:id/permissions", roleHandler.AssignPermissions)
 			roles.PUT("/:id/permissions", roleHandler.UpdatePermissions)
+			roles.PUT("/:id/parents", roleHandler.SetRoleParents)
+			roles.PUT("/:id/data-scope", roleHandler.SetRoleDataScope)
+			roles.GET("/:id/data-scopes", roleHandler.ListRoleDataScopes)
+			roles.PUT("/:id/data-scopes/:permissionUUID", roleHandler.SetRolePermissionDataScope)
+			roles.DELETE("/:id/data-scopes/:permissionUUID", roleHandler.DeleteRolePermissionDataScope)
+			roles.POST("/:id/revoke-sessions", sessionHandler.RevokeRoleSessions)
 		}
 
 		// 接口权限管理
@@ -184,6 +373,8 @@ func main() {
 			apiPermissions.PUT("/:id", apiPermissionHandler.UpdateApiPermission)
 			apiPermissions.DELETE("/:id", apiPermissionHandler.DeleteApiPermission)
 			apiPermissions.GET("/roles/:roleUUID", apiPermissionHandler.GetApiPermissionsForRole)
+			apiPermissions.GET("/roles/:roleUUID/rules", apiPermissionHandler.GetApiPermissionRulesForRole)
+			apiPermissions.GET("/roles/:roleUUID/denies", apiPermissionHandler.GetApiPermissionDenyRulesForRole)
 			apiPermissions.POST("/roles/:roleUUID", apiPermissionHandler.AddApiPermissionToRole)
 			apiPermissions.DELETE("/roles/:roleUUID", apiPermissionHandler.RemoveApiPermissionFromRole)
 		}
@@ -200,6 +391,34 @@ func main() {
 			menus.DELETE("/:id", menuHandler.DeleteMenu)
 		}
 
+		// 部门管理
+		departments := api.Group("/departments")
+		{
+			departments.POST("", departmentHandler.CreateDepartment)
+			departments.GET("", departmentHandler.ListDepartments)
+			departments.GET("/tree", departmentHandler.GetDepartmentTree)
+			departments.GET("/:id", departmentHandler.GetDepartment)
+			departments.PUT("/:id", departmentHandler.UpdateDepartment)
+			departments.DELETE("/:id", departmentHandler.DeleteDepartment)
+			departments.GET("/:id/users", departmentHandler.ListDepartmentUsers)
+			departments.PUT("/:id/users", departmentHandler.SetDepartmentUsers)
+			departments.PUT("/:id/roles", departmentHandler.SetDepartmentRoles)
+		}
+
+		// 用户组管理
+		userGroups := api.Group("/user-groups")
+		{
+			userGroups.POST("", userGroupHandler.CreateUserGroup)
+			userGroups.GET("", userGroupHandler.ListUserGroups)
+			userGroups.GET("/:id", userGroupHandler.GetUserGroup)
+			userGroups.PUT("/:id", userGroupHandler.UpdateUserGroup)
+			userGroups.DELETE("/:id", userGroupHandler.DeleteUserGroup)
+			userGroups.GET("/:id/members", userGroupHandler.ListUserGroupMembers)
+			userGroups.POST("/:id/members", userGroupHandler.AddUserGroupMembers)
+			userGroups.DELETE("/:id/members", userGroupHandler.RemoveUserGroupMembers)
+			userGroups.PUT("/:id/roles", userGroupHandler.SetUserGroupRoles)
+		}
+
 		configDictionaries := api.Group("/config-dictionaries")
 		{
 			configDictionaries.POST("", configDictionaryHandler.CreateConfigDictionary)
@@ -211,11 +430,33 @@ func main() {
 
 		// 审计日志
 		api.GET("/audit-logs", auditLogHandler.ListAuditLogs)
-		api.GET("/system/audit-logs", auditLogHandler.ListSystemAuditLogs)
+
+		// 系统级管理接口：仅系统管理员可访问，不接受个人 API 密钥
+		system := api.Group("/system", systemAdminMiddleware.Middleware())
+		{
+			system.GET("/audit-logs", auditLogHandler.ListSystemAuditLogs)
+
+			// 签名密钥管理
+			system.GET("/signing-keys", signingKeyHandler.ListSigningKeys)
+			system.POST("/signing-keys/rotate", signingKeyHandler.RotateSigningKey)
+			system.POST("/signing-keys/:kid/retire", signingKeyHandler.RetireSigningKey)
+		}
 	}
 
 	// 启动服务器
 	serverAddr := ":" + cfg.Server.Port
+	if cfg.Server.TLS.Enabled() {
+		tlsConfig, err := cfg.Server.TLS.ServerTLSConfig()
+		if err != nil {
+			service.Log.Fatalf("Failed to configure TLS: %v", err)
+		}
+		service.Log.Infof("Server starting on %s (TLS, client certificate auth: %t)", serverAddr, cfg.Server.TLS.ClientAuth)
+		if err := e.StartServer(&http.Server{Addr: serverAddr, TLSConfig: tlsConfig}); err != nil && err != http.ErrServerClosed {
+			service.Log.Fatalf("Failed to start server: %v", err)
+		}
+		return
+	}
+
 	service.Log.Infof("Server starting on %s", serverAddr)
 	if err := e.Start(serverAddr); err != nil && err != http.ErrServerClosed {
 		service.Log.Fatalf("Failed to start server: %v", err)
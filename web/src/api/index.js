@@ -99,6 +99,10 @@ export const roleAPI = {
   updateRoleMenus: (id, data) => api.put(`/v1/roles/${id}/menus`, data),
   getRolePermissions: (id) => api.get(`/v1/roles/${id}/permissions`),
   updateRolePermissions: (id, data) => api.put(`/v1/roles/${id}/permissions`, data),
+  setRoleDataScope: (id, data) => api.put(`/v1/roles/${id}/data-scope`, data),
+  getRoleDataScopes: (id) => api.get(`/v1/roles/${id}/data-scopes`),
+  setRolePermissionDataScope: (id, permissionUUID, data) => api.put(`/v1/roles/${id}/data-scopes/${permissionUUID}`, data),
+  deleteRolePermissionDataScope: (id, permissionUUID) => api.delete(`/v1/roles/${id}/data-scopes/${permissionUUID}`),
   // 使用现有的API获取菜单和权限数量
   getRoleMenusCount: async (id) => {
     try {
@@ -137,6 +141,31 @@ export const menuAPI = {
   deleteMenu: (id) => api.delete(`/v1/menus/${id}`)
 }
 
+export const departmentAPI = {
+  getDepartments: () => api.get('/v1/departments'),
+  getDepartmentTree: () => api.get('/v1/departments/tree'),
+  getDepartment: (id) => api.get(`/v1/departments/${id}`),
+  createDepartment: (data) => api.post('/v1/departments', data),
+  updateDepartment: (id, data) => api.put(`/v1/departments/${id}`, data),
+  deleteDepartment: (id) => api.delete(`/v1/departments/${id}`),
+  getDepartmentUsers: (id) => api.get(`/v1/departments/${id}/users`),
+  setDepartmentUsers: (id, data) => api.put(`/v1/departments/${id}/users`, data),
+  setDepartmentRoles: (id, data) => api.put(`/v1/departments/${id}/roles`, data),
+  setUserDepartments: (userId, data) => api.put(`/v1/users/${userId}/departments`, data)
+}
+
+export const userGroupAPI = {
+  getUserGroups: (params) => api.get('/v1/user-groups', { params }),
+  getUserGroup: (id) => api.get(`/v1/user-groups/${id}`),
+  createUserGroup: (data) => api.post('/v1/user-groups', data),
+  updateUserGroup: (id, data) => api.put(`/v1/user-groups/${id}`, data),
+  deleteUserGroup: (id) => api.delete(`/v1/user-groups/${id}`),
+  getUserGroupMembers: (id) => api.get(`/v1/user-groups/${id}/members`),
+  addUserGroupMembers: (id, data) => api.post(`/v1/user-groups/${id}/members`, data),
+  removeUserGroupMembers: (id, data) => api.delete(`/v1/user-groups/${id}/members`, { data }),
+  setUserGroupRoles: (id, data) => api.put(`/v1/user-groups/${id}/roles`, data)
+}
+
 export const permissionAPI = {
   getPermissions: (params) => api.get('/v1/permissions', { params }),
   getPermission: (id) => api.get(`/v1/permissions/${id}`),
@@ -152,6 +181,8 @@ export const apiPermissionAPI = {
   updateApiPermission: (id, data) => api.put(`/v1/api-permissions/${id}`, data),
   deleteApiPermission: (id) => api.delete(`/v1/api-permissions/${id}`),
   getApiPermissionsForRole: (roleUUID) => api.get(`/v1/api-permissions/roles/${roleUUID}`),
+  getApiPermissionRulesForRole: (roleUUID, params) => api.get(`/v1/api-permissions/roles/${roleUUID}/rules`, { params }),
+  getApiPermissionDenyRulesForRole: (roleUUID) => api.get(`/v1/api-permissions/roles/${roleUUID}/denies`),
   addApiPermissionToRole: (roleUUID, data) => api.post(`/v1/api-permissions/roles/${roleUUID}`, data),
   removeApiPermissionFromRole: (roleUUID, data) => api.delete(`/v1/api-permissions/roles/${roleUUID}`, { data })
 }
@@ -58,6 +58,18 @@ const routes = [
         component: () => import('../views/Roles.vue'),
         meta: { requiresAuth: true, title: '角色管理', icon: 'person' }
       },
+      {
+        path: 'user-groups',
+        name: 'UserGroups',
+        component: () => import('../views/UserGroups.vue'),
+        meta: { requiresAuth: true, title: '用户组', icon: 'people-circle' }
+      },
+      {
+        path: 'departments',
+        name: 'Departments',
+        component: () => import('../views/Departments.vue'),
+        meta: { requiresAuth: true, title: '部门管理', icon: 'business' }
+      },
       {
         path: 'menus',
         name: 'Menus',